@@ -55,20 +55,41 @@ func mainAPI(runCtx context.Context, svcName string) <-chan struct{} {
 
 	// Initialize TokenValidator
 	var tokenValidator auth.TokenValidator
+	claimMapping := auth.ClaimMapping{
+		UserID: viper.GetString("AUTH_USER_ID_CLAIM"),
+		Name:   viper.GetString("AUTH_NAME_CLAIM"),
+		Email:  viper.GetString("AUTH_EMAIL_CLAIM"),
+	}
+	oidcIssuers := strings.Fields(viper.GetString("OIDC_ISSUERS"))
 	remoteKeysURL := viper.GetString("REMOTE_KEYS_URL")
-	if remoteKeysURL != "" {
+	if len(oidcIssuers) > 0 {
+		// Discover the keys of the trusted OIDC issuers
+		logging.LogInfof("Initializing OIDC token validator for issuers: %s", strings.Join(oidcIssuers, ", "))
+		validator, err := auth.NewOIDCValidator(context.Background(), auth.OIDCConfig{
+			Issuers:           oidcIssuers,
+			Audiences:         strings.Fields(viper.GetString("OIDC_AUDIENCES")),
+			AuthorizedParties: strings.Fields(viper.GetString("OIDC_AUTHORIZED_PARTIES")),
+			ScopedIssuers:     strings.Fields(viper.GetString("OIDC_SCOPED_ISSUERS")),
+		})
+		if err != nil {
+			logging.LogErrorf(err, "failed to create OIDC token validator")
+			return dieEarly
+		}
+		tokenValidator = auth.WithClaimMapping(validator, claimMapping)
+		logging.LogInfof("OIDC token validator initialized successfully")
+	} else if remoteKeysURL != "" {
 		// Use remote token validator for external authentication
 		logging.LogInfof("Initializing remote token validator with URL: %s", remoteKeysURL)
-		var err error
-		tokenValidator, err = auth.NewRemoteKeyStore(context.Background(), remoteKeysURL)
+		keyStore, err := auth.NewRemoteKeyStore(context.Background(), remoteKeysURL)
 		if err != nil {
 			logging.LogErrorf(err, "failed to create remote key store")
 			return dieEarly
 		}
+		tokenValidator = auth.WithClaimMapping(keyStore, claimMapping)
 		logging.LogInfof("Remote token validator initialized successfully")
 	} else {
 		// Use local JWT validator for internal authentication
-		logging.LogInfof("REMOTE_KEYS_URL not configured - using local JWT authentication")
+		logging.LogInfof("OIDC_ISSUERS and REMOTE_KEYS_URL not configured - using local JWT authentication")
 
 		// Get JWT secret from environment (base64-encoded)
 		jwtSecretB64 := viper.GetString("JWT_SECRET")
@@ -83,16 +104,12 @@ func mainAPI(runCtx context.Context, svcName string) <-chan struct{} {
 			logging.LogErrorf(err, "failed to decode JWT secret from base64")
 			return dieEarly
 		}
-
-		tokenValidator, err = auth.NewLocalJWTValidator(jwtSecret)
-		if err != nil {
-			logging.LogErrorf(err, "failed to create local JWT validator")
-			return dieEarly
-		}
-		logging.LogInfof("Local JWT validator initialized successfully")
 	}
 
-	server.SetupRoutes(runCtx, srv.Mux(), tokenValidator, jwtSecret)
+	if err := server.SetupRoutes(runCtx, srv.Mux(), tokenValidator, jwtSecret); err != nil {
+		logging.LogErrorf(err, "failed to set up routes")
+		return dieEarly
+	}
 	metrics.AddBuildInfoMetric()
 	return standard.ListenAndServe(runCtx, srv.Mux(), port)
 }
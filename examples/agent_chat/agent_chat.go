@@ -78,7 +78,11 @@ func main() {
 		TopP:                 &topP,
 	}
 
-	aiAgent := agent.NewAgent(db, mcpManager, llmClient, agentConfig)
+	aiAgent, err := agent.NewAgent(db, mcpManager, llmClient, agentConfig)
+	if err != nil {
+		fmt.Printf("Failed to create agent: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Create a conversation
 	conversationID := uuid.New()
@@ -34,7 +34,9 @@ func GetTestMockServer(t *testing.T) *server.Server {
 
 	// Use a test JWT secret for testing
 	testJWTSecret := []byte("test-secret-key-for-testing-only-32bytes!!")
-	server.SetupRoutes(context.Background(), srv.Mux(), nil, testJWTSecret) // nil tokenValidator for tests
+	if err := server.SetupRoutes(context.Background(), srv.Mux(), nil, testJWTSecret); err != nil { // nil tokenValidator for tests
+		t.Fatalf("failed to set up routes: %v", err)
+	}
 	metrics.AddBuildInfoMetric()
 	return srv
 }
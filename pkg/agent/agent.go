@@ -3,12 +3,15 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/spf13/viper"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"gorm.io/gorm"
 
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
 	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
 	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
 	schemautil "github.com/d4l-data4life/go-mcp-host/pkg/mcp/schemautil"
@@ -28,12 +31,49 @@ type Config struct {
 	// Maximum number of tool execution iterations
 	MaxIterations int
 
-	// Maximum tokens in context
+	// Maximum tokens in context. The history is pruned to fit before every LLM call.
 	MaxContextTokens int
 
+	// Number of relevant resources (or resource chunks, with ResourceEmbedder) whose
+	// contents are added to the context (0 = none)
+	MaxContextResources int
+
+	// Optional embeddings backend for resource retrieval. When set, resource contents are
+	// chunked, embedded and indexed in the database, and the most similar chunks are added
+	// to the context instead of whole resources.
+	ResourceEmbedder llm.Embedder
+
+	// How long indexed resource contents are used before they are read again (default 15m)
+	ResourceIndexTTL time.Duration
+
+	// How history over the context budget is pruned: PruningStrategyDrop (default) or
+	// PruningStrategySummarize
+	PruningStrategy string
+
+	// Maximum number of tools sent to the LLM per request, ranked by relevance to the
+	// conversation; pinned tools are sent in addition (0 = all tools)
+	MaxTools int
+
+	// Optional embeddings backend to rank tools by; BM25 is used without it
+	ToolEmbedder llm.Embedder
+
+	// Optional policy that restricts tools by the claims of the user's token
+	ToolPolicy *ToolPolicy
+
 	// Tool execution timeout
 	ToolExecutionTimeout time.Duration
 
+	// How long an MCP server's elicitation request waits for the user's answer
+	ElicitationTimeout time.Duration
+
+	// How long the tool calls of one LLM turn that require approval wait for the user's
+	// decisions before the undecided ones are denied
+	ApprovalTimeout time.Duration
+
+	// Maximum number of tool calls from one LLM turn that run concurrently.
+	// Servers can set a lower limit via MCPServerConfig.MaxParallelToolCalls.
+	MaxParallelToolCalls int
+
 	// System prompt for the agent
 	SystemPrompt string
 
@@ -46,8 +86,9 @@ type Config struct {
 	TopP        *float64
 }
 
-// NewAgent creates a new agent instance
-func NewAgent(db *gorm.DB, mcpManager *manager.Manager, llmClient llm.Client, cfg Config) *Agent {
+// NewAgent creates a new agent instance. It fails with ErrUnknownPruningStrategy for an
+// unknown Config.PruningStrategy.
+func NewAgent(db *gorm.DB, mcpManager *manager.Manager, llmClient llm.Client, cfg Config) (*Agent, error) {
 	// Set defaults
 	if cfg.MaxIterations == 0 {
 		cfg.MaxIterations = 50
@@ -58,11 +99,27 @@ func NewAgent(db *gorm.DB, mcpManager *manager.Manager, llmClient llm.Client, cf
 	if cfg.ToolExecutionTimeout == 0 {
 		cfg.ToolExecutionTimeout = 60 * time.Second
 	}
+	if cfg.MaxParallelToolCalls == 0 {
+		cfg.MaxParallelToolCalls = 4
+	}
+	switch cfg.PruningStrategy {
+	case "":
+		cfg.PruningStrategy = PruningStrategyDrop
+	case PruningStrategyDrop, PruningStrategySummarize:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPruningStrategy, cfg.PruningStrategy)
+	}
+	if cfg.ElicitationTimeout == 0 {
+		cfg.ElicitationTimeout = 2 * time.Minute
+	}
+	if cfg.ApprovalTimeout == 0 {
+		cfg.ApprovalTimeout = 5 * time.Minute
+	}
 	if cfg.SystemPrompt == "" {
 		cfg.SystemPrompt = "You are a helpful AI assistant with access to various tools. Use them to answer user questions accurately."
 	}
 	if cfg.DefaultModel == "" {
-		cfg.DefaultModel = viper.GetString("OPENAI_DEFAULT_MODEL")
+		cfg.DefaultModel = config.GetDefaultModel()
 	}
 
 	agent := &Agent{
@@ -75,7 +132,23 @@ func NewAgent(db *gorm.DB, mcpManager *manager.Manager, llmClient llm.Client, cf
 	// Create orchestrator
 	agent.orchestrator = NewOrchestrator(mcpManager, llmClient, cfg)
 
-	return agent
+	// Retrieve resource chunks from the vector index
+	if cfg.ResourceEmbedder != nil && db != nil {
+		agent.orchestrator.contextManager.index = NewResourceIndex(db, cfg.ResourceEmbedder, cfg.ResourceIndexTTL)
+	}
+
+	// Condense pruned history into a summary stored on the conversation
+	if cfg.PruningStrategy == PruningStrategySummarize {
+		agent.orchestrator.summarizer = &summarizer{db: db, llmClient: llmClient, model: cfg.DefaultModel}
+	}
+
+	// Route elicitation requests from MCP servers to the conversation's stream
+	agent.orchestrator.elicitations = newElicitationBroker(cfg.ElicitationTimeout)
+	if mcpManager != nil {
+		mcpManager.SetElicitationHandler(agent.orchestrator.elicitations.handle)
+	}
+
+	return agent, nil
 }
 
 // Chat sends a message and returns the agent's response
@@ -95,6 +168,17 @@ func (a *Agent) ChatStream(ctx context.Context, request ChatRequest) (<-chan Str
 	return a.orchestrator.ExecuteStream(ctx, request)
 }
 
+// RespondToElicitation delivers the user's answer to an elicitation_request event of the conversation
+func (a *Agent) RespondToElicitation(conversationID uuid.UUID, elicitationID string, response ElicitationResponse) error {
+	return a.orchestrator.elicitations.respond(conversationID, elicitationID, response)
+}
+
+// WatchResourceUpdates calls fn for every update of a resource the conversation is
+// subscribed to, until the returned function is called
+func (a *Agent) WatchResourceUpdates(conversationID uuid.UUID, fn func(manager.ResourceUpdate)) func() {
+	return a.mcpManager.WatchResourceUpdates(conversationID, fn)
+}
+
 // CloseConversation cleans up resources for a conversation
 func (a *Agent) CloseConversation(conversationID uuid.UUID) error {
 	return a.mcpManager.CloseAllSessionsForConversation(conversationID)
@@ -141,6 +225,33 @@ func (a *Agent) GetAvailableResources(ctx context.Context, userID uuid.UUID, bea
 	return resources, nil
 }
 
+// RenderPrompt fetches an MCP prompt with the given arguments and converts its messages
+// to chat messages
+func (a *Agent) RenderPrompt(
+	ctx context.Context,
+	userID uuid.UUID,
+	bearerToken string,
+	serverName, promptName string,
+	arguments map[string]string,
+) ([]llm.Message, error) {
+	result, err := a.mcpManager.GetPrompt(ctx, userID, bearerToken, serverName, promptName, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]llm.Message, 0, len(result.Messages))
+	for _, msg := range result.Messages {
+		if msg == nil {
+			continue
+		}
+		messages = append(messages, llm.Message{
+			Role:    string(msg.Role),
+			Content: llm.ConvertMCPContentToString([]mcp.Content{msg.Content}),
+		})
+	}
+	return messages, nil
+}
+
 // ChatRequest represents a chat request to the agent
 type ChatRequest struct {
 	ConversationID uuid.UUID
@@ -149,23 +260,50 @@ type ChatRequest struct {
 	UserMessage    string
 	Messages       []llm.Message // Optional: provide full message history
 	Model          string        // Optional: override default model
+
+	// PinnedTools are always sent to the LLM when tools are limited by MaxTools: qualified
+	// tool names (<server>__<tool>) or server names
+	PinnedTools []string
+
+	// ToolFilter restricts the servers and tools the request can use (nil = no restriction)
+	ToolFilter *ToolFilter
+
+	// Claims of the user's validated token, evaluated by Config.ToolPolicy
+	Claims *auth.Claims
+
+	// Approvals receives the user's decisions for tool calls that require approval.
+	// When nil, such tool calls are denied.
+	Approvals *ToolApprovals
 }
 
 // ChatResponse represents the agent's response
 type ChatResponse struct {
-	Message     llm.Message
+	Message llm.Message
+	// Messages is the transcript produced after the user message: assistant tool-call
+	// turns and tool results in order, ending with Message
+	Messages    []llm.Message
 	ToolsUsed   []ToolExecution
 	Iterations  int
 	TotalTokens int
-	Error       error
+	// Context reports pruned history and injected resources
+	Context *ContextReport
+	Error   error
 }
 
 // StreamEvent represents a streaming event from the agent
 type StreamEvent struct {
-	Type    StreamEventType
-	Content string
-	Tool    *ToolExecution
-	Delta   *llm.Delta
+	Type     StreamEventType
+	Content  string
+	Tool     *ToolExecution
+	ToolCall *ToolCallDelta
+	// Elicitation is set for elicitation_request events
+	Elicitation *ElicitationRequest
+	Delta       *llm.Delta
+	// Messages carries the turn's transcript (see ChatResponse.Messages) on the done event,
+	// and the tool calls and results completed before the failure on error events
+	Messages []llm.Message
+	// Context carries the context report (see ChatResponse.Context) on the done event
+	Context *ContextReport
 	Done    bool
 	Error   error
 }
@@ -174,21 +312,45 @@ type StreamEvent struct {
 type StreamEventType string
 
 const (
-	StreamEventTypeContent      StreamEventType = "content"
-	StreamEventTypeToolStart    StreamEventType = "tool_start"
-	StreamEventTypeToolComplete StreamEventType = "tool_complete"
-	StreamEventTypeDone         StreamEventType = "done"
-	StreamEventTypeError        StreamEventType = "error"
+	StreamEventTypeContent       StreamEventType = "content"
+	StreamEventTypeToolCallDelta StreamEventType = "tool_call_delta"
+	StreamEventTypeToolStart     StreamEventType = "tool_start"
+	StreamEventTypeToolComplete  StreamEventType = "tool_complete"
+	StreamEventTypeDone          StreamEventType = "done"
+	StreamEventTypeError         StreamEventType = "error"
+
+	// StreamEventTypeToolApprovalRequired pauses the tool call in Tool until the
+	// decision is delivered through ChatRequest.Approvals
+	StreamEventTypeToolApprovalRequired StreamEventType = "tool_approval_required"
+
+	// StreamEventTypeElicitation asks the user for input requested by an MCP server; the
+	// answer is delivered with Agent.RespondToElicitation
+	StreamEventTypeElicitation StreamEventType = "elicitation_request"
 )
 
+// ToolCallDelta describes a tool call while the LLM is still generating it
+type ToolCallDelta struct {
+	Index      int
+	ID         string
+	ServerName string
+	ToolName   string
+	// ArgumentsDelta is the argument fragment received with this event
+	ArgumentsDelta string
+	// Arguments is the (possibly incomplete) JSON argument string accumulated so far
+	Arguments string
+}
+
 // ToolExecution represents a tool execution
 type ToolExecution struct {
+	ToolCallID string
 	ServerName string
 	ToolName   string
 	Arguments  map[string]interface{}
 	Result     string
 	Error      error
 	Duration   time.Duration
+	// Approval is the user's decision when the tool requires approval (nil otherwise)
+	Approval *ToolApprovalDecision
 }
 
 // ToolInfo represents information about an available tool
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ToolApprovalDecision is the user's answer to a tool_approval_required event
+type ToolApprovalDecision struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ToolApprovals routes approval decisions from the client to tool calls that are waiting for them.
+// One instance is typically shared by all requests streamed over the same connection.
+type ToolApprovals struct {
+	mu      sync.Mutex
+	pending map[string]chan ToolApprovalDecision
+}
+
+// NewToolApprovals creates an empty approval registry
+func NewToolApprovals() *ToolApprovals {
+	return &ToolApprovals{pending: make(map[string]chan ToolApprovalDecision)}
+}
+
+// Resolve delivers the decision for a pending tool call.
+// It returns ErrNoPendingApproval when no call with that ID is waiting.
+func (a *ToolApprovals) Resolve(toolCallID string, decision ToolApprovalDecision) error {
+	a.mu.Lock()
+	ch, ok := a.pending[toolCallID]
+	delete(a.pending, toolCallID)
+	a.mu.Unlock()
+
+	if !ok {
+		return ErrNoPendingApproval
+	}
+	ch <- decision
+	return nil
+}
+
+// register marks a tool call as waiting for a decision. It must be called before the
+// approval request is sent to the client so that an immediate answer is not lost.
+func (a *ToolApprovals) register(toolCallID string) <-chan ToolApprovalDecision {
+	ch := make(chan ToolApprovalDecision, 1)
+	a.mu.Lock()
+	a.pending[toolCallID] = ch
+	a.mu.Unlock()
+	return ch
+}
+
+// wait blocks until the decision for a registered tool call arrives, ctx is done or the
+// deadline (zero = none) passes, in which case it returns ErrApprovalTimeout. A decision that
+// already arrived is returned even after the deadline.
+func (a *ToolApprovals) wait(ctx context.Context, toolCallID string, ch <-chan ToolApprovalDecision, deadline time.Time) (ToolApprovalDecision, error) {
+	select {
+	case decision := <-ch:
+		return decision, nil
+	default:
+	}
+
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case decision := <-ch:
+		return decision, nil
+	case <-ctx.Done():
+		a.cancel(toolCallID)
+		return ToolApprovalDecision{}, ctx.Err()
+	case <-expired:
+		a.cancel(toolCallID)
+		return ToolApprovalDecision{}, ErrApprovalTimeout
+	}
+}
+
+// cancel stops waiting for the decision of a tool call, so a late answer is rejected
+func (a *ToolApprovals) cancel(toolCallID string) {
+	a.mu.Lock()
+	delete(a.pending, toolCallID)
+	a.mu.Unlock()
+}
@@ -2,6 +2,8 @@ package agent
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
@@ -9,12 +11,16 @@ import (
 
 	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
 	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
 )
 
 // ContextManager manages context selection and pruning for the agent
 type ContextManager struct {
 	mcpManager       *manager.Manager
 	maxContextTokens int
+	// index retrieves resource chunks by embedding similarity (nil = whole resources)
+	index *ResourceIndex
 }
 
 // NewContextManager creates a new context manager
@@ -25,42 +31,59 @@ func NewContextManager(mcpManager *manager.Manager, maxContextTokens int) *Conte
 	}
 }
 
-// SelectRelevantResources finds resources relevant to the user's query
+// SelectRelevantResources finds resources relevant to the user's query on the servers
+// allowed by filter
 func (cm *ContextManager) SelectRelevantResources(
 	ctx context.Context,
 	userID uuid.UUID,
 	bearerToken string,
 	query string,
+	filter *ToolFilter,
 ) ([]ResourceWithRelevance, error) {
 	// Get all available resources
-	resourcesWithServer, err := cm.mcpManager.ListAllResourcesForUser(ctx, userID, bearerToken)
+	available, err := cm.mcpManager.ListAllResourcesForUser(ctx, userID, bearerToken)
 	if err != nil {
 		return nil, err
 	}
+	resourcesWithServer := make([]manager.ResourceWithServer, 0, len(available))
+	for _, r := range available {
+		if filter.AllowsServer(r.ServerName) {
+			resourcesWithServer = append(resourcesWithServer, r)
+		}
+	}
 
 	// Score each resource for relevance
+	documents := make([]string, len(resourcesWithServer))
+	for i, r := range resourcesWithServer {
+		documents[i] = resourceDocument(r)
+	}
+	scores := bm25Scores(documents, query)
+
 	results := make([]ResourceWithRelevance, 0, len(resourcesWithServer))
-	for _, r := range resourcesWithServer {
-		score := cm.scoreResourceRelevance(r, query)
-		if score > 0 {
+	for i, r := range resourcesWithServer {
+		if scores[i] > 0 {
 			results = append(results, ResourceWithRelevance{
 				Resource:   r,
-				Relevance:  score,
+				Relevance:  scores[i],
 				ServerName: r.ServerName,
 			})
 		}
 	}
 
 	// Sort by relevance (highest first)
-	sortByRelevance(results)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Relevance > results[j].Relevance
+	})
 
 	return results, nil
 }
 
-// ReadRelevantResources reads the content of relevant resources
+// ReadRelevantResources reads the content of relevant resources, opening sessions with
+// their servers when the conversation has none yet
 func (cm *ContextManager) ReadRelevantResources(
 	ctx context.Context,
-	conversationID uuid.UUID,
+	conversationID, userID uuid.UUID,
+	bearerToken string,
 	resources []ResourceWithRelevance,
 	maxResources int,
 ) ([]ResourceContent, error) {
@@ -73,15 +96,13 @@ func (cm *ContextManager) ReadRelevantResources(
 	for i := 0; i < maxResources; i++ {
 		r := resources[i]
 
-		// Read resource via MCP
-		result, err := cm.mcpManager.ReadResource(ctx, conversationID, r.ServerName, r.Resource.Resource.URI)
+		content, err := cm.readResource(ctx, conversationID, userID, bearerToken, r.Resource)
 		if err != nil {
 			// Log error but continue with other resources
+			logging.LogWarningf(err, "Failed to read resource %s", r.Resource.Resource.URI)
 			continue
 		}
 
-		content := resourceContentToString(result.Contents)
-
 		contents = append(contents, ResourceContent{
 			URI:       r.Resource.Resource.URI,
 			Name:      r.Resource.Resource.Name,
@@ -93,91 +114,207 @@ func (cm *ContextManager) ReadRelevantResources(
 	return contents, nil
 }
 
-// PruneMessages removes old messages to fit within token limit
-func (cm *ContextManager) PruneMessages(messages []llm.Message, maxTokens int) []llm.Message {
-	// Simple pruning: keep system message and recent messages
-	if len(messages) <= 2 {
-		return messages
+// readResource reads the text of a resource through the conversation's session with its
+// server, opening the session when the conversation has none yet
+func (cm *ContextManager) readResource(
+	ctx context.Context,
+	conversationID, userID uuid.UUID,
+	bearerToken string,
+	resource manager.ResourceWithServer,
+) (string, error) {
+	if _, exists := cm.mcpManager.GetSession(conversationID, resource.ServerName); !exists {
+		serverCfg, ok := cm.mcpManager.GetServerConfigForUser(ctx, userID, resource.ServerName)
+		if !ok {
+			return "", fmt.Errorf("unknown or disabled server: %s", resource.ServerName)
+		}
+		if _, err := cm.mcpManager.GetOrCreateSession(ctx, conversationID, serverCfg, bearerToken, userID); err != nil {
+			return "", err
+		}
 	}
 
-	// Estimate tokens (rough approximation: 1 token ≈ 4 chars)
-	estimateTokens := func(msg llm.Message) int {
-		return len(msg.Content) / 4
+	result, err := cm.mcpManager.ReadResource(ctx, conversationID, resource.ServerName, resource.Resource.URI)
+	if err != nil {
+		return "", err
 	}
+	return resourceContentToString(result.Contents), nil
+}
 
-	totalTokens := 0
-	for _, msg := range messages {
-		totalTokens += estimateTokens(msg)
+// PruneMessages drops the oldest history messages until their size, counted with tokenizer,
+// fits maxTokens. Leading system messages and the latest user message with everything after
+// it are always kept, and an assistant message with tool calls is only dropped together with
+// its tool results. A nil tokenizer uses the heuristic.
+func (cm *ContextManager) PruneMessages(messages []llm.Message, maxTokens int, tokenizer llm.Tokenizer) []llm.Message {
+	if maxTokens <= 0 || llm.CountMessageTokens(tokenizer, messages) <= maxTokens {
+		return messages
 	}
 
-	if totalTokens <= maxTokens {
-		return messages
+	// Leading system messages (system prompt, injected resources)
+	head := 0
+	for head < len(messages) && messages[head].Role == llm.RoleSystem {
+		head++
 	}
 
-	// Keep system message
-	pruned := []llm.Message{messages[0]}
-	currentTokens := estimateTokens(messages[0])
+	// The current turn: the latest user message and everything after it
+	tail := len(messages)
+	for i := len(messages) - 1; i >= head; i-- {
+		if messages[i].Role == llm.RoleUser {
+			tail = i
+			break
+		}
+	}
 
-	// Add recent messages from the end
-	for i := len(messages) - 1; i > 0; i-- {
-		msgTokens := estimateTokens(messages[i])
-		if currentTokens+msgTokens > maxTokens {
+	// Add history groups from the most recent until the budget is exhausted
+	used := llm.CountMessageTokens(tokenizer, messages[:head]) + llm.CountMessageTokens(tokenizer, messages[tail:])
+	start := tail
+	for start > head {
+		groupStart := messageGroupStart(messages, head, start)
+		cost := llm.CountMessageTokens(tokenizer, messages[groupStart:start])
+		if used+cost > maxTokens {
 			break
 		}
-		pruned = append([]llm.Message{messages[i]}, pruned...)
-		currentTokens += msgTokens
+		used += cost
+		start = groupStart
 	}
 
+	pruned := make([]llm.Message, 0, head+len(messages)-start)
+	pruned = append(pruned, messages[:head]...)
+	pruned = append(pruned, messages[start:]...)
 	return pruned
 }
 
-// scoreResourceRelevance scores how relevant a resource is to the query
-func (cm *ContextManager) scoreResourceRelevance(resource manager.ResourceWithServer, query string) float64 {
-	score := 0.0
-	queryLower := strings.ToLower(query)
-
-	// Check name
-	if resource.Resource.Name != "" {
-		nameLower := strings.ToLower(resource.Resource.Name)
-		if strings.Contains(queryLower, nameLower) || strings.Contains(nameLower, queryLower) {
-			score += 0.5
+// BuildResourceContext reads up to maxResources resources relevant to the query from the
+// servers allowed by filter and renders them as a system message of at most maxTokens,
+// counted with tokenizer. It returns nil when nothing was selected.
+func (cm *ContextManager) BuildResourceContext(
+	ctx context.Context,
+	conversationID, userID uuid.UUID,
+	bearerToken string,
+	query string,
+	filter *ToolFilter,
+	maxResources int,
+	maxTokens int,
+	tokenizer llm.Tokenizer,
+) (*llm.Message, []string, error) {
+	var contents []ResourceContent
+	if cm.index != nil {
+		chunks, err := cm.RetrieveResourceChunks(ctx, conversationID, userID, bearerToken, query, filter, maxResources)
+		if err != nil {
+			logging.LogWarningf(err, "Failed to retrieve resource chunks, using whole resources")
+		} else {
+			contents = chunks
 		}
 	}
-
-	// Check description
-	if resource.Resource.Description != "" {
-		descLower := strings.ToLower(resource.Resource.Description)
-		for _, word := range strings.Fields(queryLower) {
-			if strings.Contains(descLower, word) {
-				score += 0.1
-			}
+	if contents == nil {
+		relevant, err := cm.SelectRelevantResources(ctx, userID, bearerToken, query, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		contents, err = cm.ReadRelevantResources(ctx, conversationID, userID, bearerToken, relevant, maxResources)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
-	// Check URI
-	if resource.Resource.URI != "" {
-		uriLower := strings.ToLower(resource.Resource.URI)
-		for _, word := range strings.Fields(queryLower) {
-			if strings.Contains(uriLower, word) {
-				score += 0.05
-			}
+	var builder strings.Builder
+	builder.WriteString("The following resources may be relevant to the user's request.")
+	var uris []string
+	included := make(map[string]bool)
+	for _, content := range contents {
+		if content.Content == "" {
+			continue
 		}
+		section := fmt.Sprintf("\n\n## %s (%s)\n%s", content.Name, content.URI, content.Content)
+		if llm.CountMessageTokens(tokenizer, []llm.Message{{Content: builder.String() + section}}) > maxTokens {
+			continue
+		}
+		builder.WriteString(section)
+		if !included[content.URI] {
+			included[content.URI] = true
+			uris = append(uris, content.URI)
+		}
+	}
+	if len(uris) == 0 {
+		return nil, nil, nil
 	}
 
-	return score
+	return &llm.Message{Role: llm.RoleSystem, Content: builder.String()}, uris, nil
 }
 
-// sortByRelevance sorts resources by relevance score (descending)
-func sortByRelevance(resources []ResourceWithRelevance) {
-	// Simple bubble sort (good enough for small lists)
-	n := len(resources)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if resources[j].Relevance < resources[j+1].Relevance {
-				resources[j], resources[j+1] = resources[j+1], resources[j]
-			}
+// RetrieveResourceChunks brings the resource index of the user up to date and returns the
+// maxChunks indexed chunks of the servers allowed by filter most similar to the query, best
+// first
+func (cm *ContextManager) RetrieveResourceChunks(
+	ctx context.Context,
+	conversationID, userID uuid.UUID,
+	bearerToken string,
+	query string,
+	filter *ToolFilter,
+	maxChunks int,
+) ([]ResourceContent, error) {
+	if cm.index == nil {
+		return nil, nil
+	}
+
+	resources, err := cm.mcpManager.ListAllResourcesForUser(ctx, userID, bearerToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers the conversation excludes are neither read nor indexed
+	read := func(ctx context.Context, resource manager.ResourceWithServer) (string, error) {
+		return cm.readResource(ctx, conversationID, userID, bearerToken, resource)
+	}
+	if err := cm.index.Sync(ctx, userID, resources, filter.AllowsServer, read); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(resources))
+	servers := make(map[string]bool)
+	var serverNames []string
+	for _, r := range resources {
+		if r.Resource == nil || !filter.AllowsServer(r.ServerName) {
+			continue
 		}
+		if !servers[r.ServerName] {
+			servers[r.ServerName] = true
+			serverNames = append(serverNames, r.ServerName)
+		}
+		names[resourceKey(r.ServerName, r.Resource.URI)] = r.Resource.Name
+	}
+
+	matches, err := cm.index.Search(ctx, userID, serverNames, query, maxChunks)
+	if err != nil {
+		return nil, err
 	}
+
+	contents := make([]ResourceContent, 0, len(matches))
+	for _, match := range matches {
+		contents = append(contents, ResourceContent{
+			URI:       match.URI,
+			Name:      names[resourceKey(match.ServerName, match.URI)],
+			Content:   match.Content,
+			Relevance: match.Score,
+		})
+	}
+	return contents, nil
+}
+
+// messageGroupStart returns the first index of the message group that ends before end.
+// Tool results form a group with the assistant message that requested them.
+func messageGroupStart(messages []llm.Message, head, end int) int {
+	start := end - 1
+	for start > head && messages[start].Role == llm.RoleTool {
+		start--
+	}
+	return start
+}
+
+// resourceDocument is the text a resource is ranked by: its name, description and URI
+func resourceDocument(resource manager.ResourceWithServer) string {
+	if resource.Resource == nil {
+		return ""
+	}
+	return strings.Join([]string{resource.Resource.Name, resource.Resource.Description, resource.Resource.URI}, " ")
 }
 
 // ResourceWithRelevance represents a resource with its relevance score
@@ -187,6 +324,30 @@ type ResourceWithRelevance struct {
 	Relevance  float64
 }
 
+// ContextReport describes how the conversation was fitted into the model's context
+type ContextReport struct {
+	// Tokenizer names the tokenizer the counts are based on
+	Tokenizer string
+	// TokenBudget is the number of prompt tokens available
+	TokenBudget int
+	// ToolTokens is the size of the tool definitions, which count toward the budget
+	ToolTokens int
+	// OmittedTools is the number of tools not sent because they ranked below MaxTools
+	OmittedTools int
+	// PromptTokens is the size of the last prompt sent to the LLM, including tool definitions
+	PromptTokens int
+	// PrunedMessages is the number of history messages left out of the last prompt
+	PrunedMessages int
+	// SummarizedMessages is the number of pruned messages replaced by a summary
+	// (PruningStrategySummarize only)
+	SummarizedMessages int
+	// InjectedResources lists the URIs of resources added as system context
+	InjectedResources []string
+
+	// summary is the rolling summary used for the request, reused across iterations
+	summary *conversationSummary
+}
+
 // ResourceContent represents the content of a resource
 type ResourceContent struct {
 	URI       string
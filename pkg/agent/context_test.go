@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+)
+
+func TestPruneMessages_KeepsToolCallsWithResults(t *testing.T) {
+	long := strings.Repeat("x", 400) // ~100 tokens
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "system"},
+		{Role: llm.RoleUser, Content: long},
+		{Role: llm.RoleAssistant, Content: long},
+		{Role: llm.RoleUser, Content: "weather?"},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "call_1"}, {ID: "call_2"}}},
+		{Role: llm.RoleTool, ToolCallID: "call_1", Content: long},
+		{Role: llm.RoleTool, ToolCallID: "call_2", Content: "sunny"},
+		{Role: llm.RoleAssistant, Content: "It is sunny."},
+		{Role: llm.RoleUser, Content: "and tomorrow?"},
+	}
+	cm := NewContextManager(nil, 0)
+
+	assert.Equal(t, messages, cm.PruneMessages(messages, 10000, nil))
+
+	// Room for the tool group but not for the older exchange
+	pruned := cm.PruneMessages(messages, 160, nil)
+	require.Len(t, pruned, 7)
+	assert.Equal(t, llm.RoleSystem, pruned[0].Role)
+	assert.Equal(t, "weather?", pruned[1].Content)
+	assert.Equal(t, "and tomorrow?", pruned[len(pruned)-1].Content)
+
+	// Too small for the tool group: the call is dropped together with both results
+	pruned = cm.PruneMessages(messages, 60, nil)
+	for _, msg := range pruned {
+		assert.NotEqual(t, llm.RoleTool, msg.Role)
+		assert.Empty(t, msg.ToolCalls)
+	}
+	assert.Equal(t, "It is sunny.", pruned[1].Content)
+	assert.Equal(t, "and tomorrow?", pruned[len(pruned)-1].Content)
+
+	// The current turn is kept even when it exceeds the budget
+	pruned = cm.PruneMessages(messages, 1, nil)
+	assert.Equal(t, []llm.Message{messages[0], messages[8]}, pruned)
+}
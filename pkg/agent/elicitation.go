@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// ElicitationRequest asks the user for structured input on behalf of an MCP server
+type ElicitationRequest struct {
+	ID         string `json:"id"`
+	ServerName string `json:"serverName"`
+	Message    string `json:"message"`
+	// RequestedSchema is the JSON schema of the requested input
+	RequestedSchema any `json:"requestedSchema"`
+}
+
+// ElicitationResponse is the user's answer to an elicitation request
+type ElicitationResponse struct {
+	// Action is "accept", "decline" or "cancel"
+	Action string `json:"action"`
+	// Content holds the submitted values when Action is "accept"
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// elicitationBroker forwards elicitation requests from MCP servers to the stream of the
+// conversation they belong to and hands the user's response back to the server.
+type elicitationBroker struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	streams map[uuid.UUID]*elicitationStream // conversation -> active stream
+	pending map[string]*pendingElicitation
+}
+
+// elicitationStream is the event channel of an active stream. Requests are sent without
+// holding the broker's lock; detaching closes done and waits for senders, so the channel
+// is never sent on after its stream closed it.
+type elicitationStream struct {
+	events  chan<- StreamEvent
+	done    chan struct{}
+	senders sync.WaitGroup
+}
+
+type pendingElicitation struct {
+	conversationID uuid.UUID
+	response       chan ElicitationResponse
+}
+
+func newElicitationBroker(timeout time.Duration) *elicitationBroker {
+	return &elicitationBroker{
+		timeout: timeout,
+		streams: make(map[uuid.UUID]*elicitationStream),
+		pending: make(map[string]*pendingElicitation),
+	}
+}
+
+// attach makes events the target for elicitation requests of the conversation until the
+// returned function is called. After it returns, no more requests are sent on events.
+func (b *elicitationBroker) attach(conversationID uuid.UUID, events chan<- StreamEvent) func() {
+	stream := &elicitationStream{events: events, done: make(chan struct{})}
+	b.mu.Lock()
+	b.streams[conversationID] = stream
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		if b.streams[conversationID] == stream {
+			delete(b.streams, conversationID)
+		}
+		b.mu.Unlock()
+		close(stream.done)
+		stream.senders.Wait()
+	}
+}
+
+// handle implements manager.ElicitationHandler. Requests for conversations without an
+// active stream are cancelled; unanswered requests are cancelled after the timeout.
+func (b *elicitationBroker) handle(ctx context.Context, req manager.ElicitationRequest) (*mcp.ElicitResult, error) {
+	pending := &pendingElicitation{
+		conversationID: req.ConversationID,
+		response:       make(chan ElicitationResponse, 1),
+	}
+	id := uuid.New().String()
+
+	b.mu.Lock()
+	stream, ok := b.streams[req.ConversationID]
+	if ok {
+		b.pending[id] = pending
+		// Detaching waits for the send below
+		stream.senders.Add(1)
+	}
+	b.mu.Unlock()
+
+	if ok {
+		event := StreamEvent{
+			Type: StreamEventTypeElicitation,
+			Elicitation: &ElicitationRequest{
+				ID:              id,
+				ServerName:      req.ServerName,
+				Message:         req.Message,
+				RequestedSchema: req.RequestedSchema,
+			},
+		}
+		select {
+		case stream.events <- event:
+		case <-stream.done:
+			ok = false
+		case <-ctx.Done():
+			ok = false
+		case <-time.After(b.timeout):
+			// Nobody is reading the stream anymore
+			ok = false
+		}
+		stream.senders.Done()
+		if !ok {
+			b.mu.Lock()
+			delete(b.pending, id)
+			b.mu.Unlock()
+		}
+	}
+
+	if !ok {
+		logging.LogDebugf("Cancelling elicitation from %s: no client connected to conversation %s", req.ServerName, req.ConversationID)
+		return &mcp.ElicitResult{Action: manager.ElicitationActionCancel}, nil
+	}
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, id)
+		b.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(b.timeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-pending.response:
+		return &mcp.ElicitResult{Action: response.Action, Content: response.Content}, nil
+	case <-timer.C:
+		logging.LogDebugf("Elicitation %s from %s timed out", id, req.ServerName)
+		return &mcp.ElicitResult{Action: manager.ElicitationActionCancel}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// respond delivers the user's answer to a pending elicitation of the conversation
+func (b *elicitationBroker) respond(conversationID uuid.UUID, id string, response ElicitationResponse) error {
+	switch response.Action {
+	case manager.ElicitationActionAccept, manager.ElicitationActionDecline, manager.ElicitationActionCancel:
+	default:
+		return ErrInvalidElicitationAction
+	}
+	if response.Action != manager.ElicitationActionAccept {
+		response.Content = nil
+	}
+
+	b.mu.Lock()
+	pending, ok := b.pending[id]
+	if ok && pending.conversationID == conversationID {
+		delete(b.pending, id)
+	} else {
+		ok = false
+	}
+	b.mu.Unlock()
+
+	if !ok {
+		return ErrNoPendingElicitation
+	}
+	pending.response <- response
+	return nil
+}
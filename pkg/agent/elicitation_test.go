@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+)
+
+func TestElicitationBroker_RoutesResponseToServer(t *testing.T) {
+	broker := newElicitationBroker(time.Second)
+	convID := uuid.New()
+	events := make(chan StreamEvent, 1)
+	detach := broker.attach(convID, events)
+	defer detach()
+
+	go func() {
+		event := <-events
+		if !assert.Equal(t, StreamEventTypeElicitation, event.Type) {
+			return
+		}
+		// Responses for other conversations are rejected
+		assert.ErrorIs(t, broker.respond(uuid.New(), event.Elicitation.ID, ElicitationResponse{Action: "accept"}), ErrNoPendingElicitation)
+		assert.ErrorIs(t, broker.respond(convID, event.Elicitation.ID, ElicitationResponse{Action: "maybe"}), ErrInvalidElicitationAction)
+		assert.NoError(t, broker.respond(convID, event.Elicitation.ID, ElicitationResponse{
+			Action:  manager.ElicitationActionAccept,
+			Content: map[string]any{"name": "Ada"},
+		}))
+	}()
+
+	result, err := broker.handle(context.Background(), manager.ElicitationRequest{
+		ConversationID: convID,
+		ServerName:     "crm",
+		Message:        "Who?",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, manager.ElicitationActionAccept, result.Action)
+	assert.Equal(t, "Ada", result.Content["name"])
+}
+
+func TestElicitationBroker_CancelsWithoutStream(t *testing.T) {
+	broker := newElicitationBroker(time.Second)
+
+	result, err := broker.handle(context.Background(), manager.ElicitationRequest{ConversationID: uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, manager.ElicitationActionCancel, result.Action)
+}
+
+func TestElicitationBroker_DetachUnblocksSender(t *testing.T) {
+	broker := newElicitationBroker(time.Minute)
+	convID := uuid.New()
+	// Nobody reads the stream, so the request blocks until the stream is detached
+	events := make(chan StreamEvent)
+	detach := broker.attach(convID, events)
+
+	results := make(chan string, 1)
+	go func() {
+		result, err := broker.handle(context.Background(), manager.ElicitationRequest{ConversationID: convID})
+		if assert.NoError(t, err) {
+			results <- result.Action
+		}
+	}()
+
+	// Other conversations are not blocked by the pending send
+	result, err := broker.handle(context.Background(), manager.ElicitationRequest{ConversationID: uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, manager.ElicitationActionCancel, result.Action)
+
+	detach()
+	close(events)
+	select {
+	case action := <-results:
+		assert.Equal(t, manager.ElicitationActionCancel, action)
+	case <-time.After(5 * time.Second):
+		t.Fatal("elicitation was not cancelled after the stream was detached")
+	}
+}
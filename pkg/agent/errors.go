@@ -7,6 +7,9 @@ var (
 	// ErrLLMUnavailable indicates the LLM service is unreachable or not responding
 	ErrLLMUnavailable = errors.New("LLM service unavailable")
 
+	// ErrUnknownPruningStrategy indicates a Config.PruningStrategy other than drop or summarize
+	ErrUnknownPruningStrategy = errors.New("unknown pruning strategy")
+
 	// ErrMaxIterations indicates the agent reached maximum iterations
 	ErrMaxIterations = errors.New("max iterations reached")
 
@@ -15,4 +18,25 @@ var (
 
 	// ErrToolExecutionFailed indicates a tool execution failed
 	ErrToolExecutionFailed = errors.New("tool execution failed")
+
+	// ErrToolNotAllowed indicates a tool call outside the conversation's server and tool selection
+	ErrToolNotAllowed = errors.New("tool not allowed in this conversation")
+
+	// ErrToolForbidden indicates a tool call the tool policy refuses for the user
+	ErrToolForbidden = errors.New("tool forbidden by policy")
+
+	// ErrToolDenied indicates the user denied a tool call that requires approval
+	ErrToolDenied = errors.New("tool call denied by user")
+
+	// ErrApprovalTimeout indicates the user did not answer an approval request in time
+	ErrApprovalTimeout = errors.New("no approval decision before the timeout")
+
+	// ErrNoPendingApproval indicates an approval decision did not match any waiting tool call
+	ErrNoPendingApproval = errors.New("no pending approval for tool call")
+
+	// ErrNoPendingElicitation indicates an elicitation response did not match any waiting request
+	ErrNoPendingElicitation = errors.New("no pending elicitation")
+
+	// ErrInvalidElicitationAction indicates an elicitation response action other than accept, decline or cancel
+	ErrInvalidElicitationAction = errors.New("invalid elicitation action")
 )
@@ -19,29 +19,39 @@ import (
 
 // Orchestrator manages the agent's reasoning and tool execution loop
 type Orchestrator struct {
-	mcpManager *manager.Manager
-	llmClient  llm.Client
-	config     Config
+	mcpManager     *manager.Manager
+	llmClient      llm.Client
+	config         Config
+	contextManager *ContextManager
+	toolSelector   *ToolSelector
+	elicitations   *elicitationBroker
+	summarizer     *summarizer
 }
 
 // NewOrchestrator creates a new orchestrator
 func NewOrchestrator(mcpManager *manager.Manager, llmClient llm.Client, config Config) *Orchestrator {
 	return &Orchestrator{
-		mcpManager: mcpManager,
-		llmClient:  llmClient,
-		config:     config,
+		mcpManager:     mcpManager,
+		llmClient:      llmClient,
+		config:         config,
+		contextManager: NewContextManager(mcpManager, config.MaxContextTokens),
+		toolSelector:   NewToolSelector(config.ToolEmbedder),
 	}
 }
 
 // Execute runs the agent orchestration loop
 func (o *Orchestrator) Execute(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
 	// Build initial messages
-	messages := o.buildMessages(request)
+	tokenizer := llm.TokenizerFor(o.llmClient, request.Model)
+	messages, contextReport := o.prepareContext(ctx, request, o.buildMessages(request), tokenizer)
+	turnStart := len(messages)
 
 	llmTools, toolLookup, err := o.prepareToolContext(ctx, request)
 	if err != nil {
 		return nil, err
 	}
+	llmTools = o.selectTools(ctx, request, llmTools, contextReport)
+	contextReport.ToolTokens = llm.CountToolTokens(tokenizer, llmTools)
 
 	logging.LogDebugf("Starting agent loop: tools=%d max_iterations=%d",
 		len(llmTools), o.config.MaxIterations)
@@ -58,7 +68,7 @@ func (o *Orchestrator) Execute(ctx context.Context, request ChatRequest) (*ChatR
 		// Build LLM request
 		chatRequest := llm.ChatRequest{
 			Model:       request.Model,
-			Messages:    messages,
+			Messages:    o.fitContext(ctx, request, messages, tokenizer, contextReport),
 			Tools:       llmTools,
 			Temperature: o.config.Temperature,
 			MaxTokens:   o.config.MaxTokens,
@@ -91,21 +101,18 @@ func (o *Orchestrator) Execute(ctx context.Context, request ChatRequest) (*ChatR
 			logging.LogDebugf("Agent complete: iterations=%d tokens=%d", iteration, totalTokens)
 			return &ChatResponse{
 				Message:     response.Message,
+				Messages:    transcript(messages, turnStart),
 				ToolsUsed:   toolExecutions,
 				Iterations:  iteration,
 				TotalTokens: totalTokens,
+				Context:     contextReport,
 			}, nil
 		}
 
 		// Execute tool calls
-		for _, toolCall := range response.Message.ToolCalls {
-			execution, content := o.handleToolCall(ctx, request, toolCall, toolLookup)
-			toolExecutions = append(toolExecutions, execution)
-			messages = append(messages, llm.Message{
-				Role:       llm.RoleTool,
-				ToolCallID: toolCall.ID,
-				Content:    content,
-			})
+		for _, result := range o.executeToolCalls(ctx, request, response.Message.ToolCalls, toolLookup, nil) {
+			toolExecutions = append(toolExecutions, result.execution)
+			messages = append(messages, result.message)
 		}
 
 		// Continue loop to get LLM's response to tool results
@@ -113,14 +120,17 @@ func (o *Orchestrator) Execute(ctx context.Context, request ChatRequest) (*ChatR
 
 	// Max iterations reached
 	logging.LogWarningf(nil, "Agent max iterations reached: %d", o.config.MaxIterations)
+	final := llm.Message{
+		Role:    llm.RoleAssistant,
+		Content: "I've reached my maximum thinking iterations. Please try rephrasing your question.",
+	}
 	return &ChatResponse{
-		Message: llm.Message{
-			Role:    llm.RoleAssistant,
-			Content: "I've reached my maximum thinking iterations. Please try rephrasing your question.",
-		},
+		Message:     final,
+		Messages:    append(transcript(messages, turnStart), final),
 		ToolsUsed:   toolExecutions,
 		Iterations:  iteration,
 		TotalTokens: totalTokens,
+		Context:     contextReport,
 		Error:       ErrMaxIterations,
 	}, nil
 }
@@ -131,9 +141,14 @@ func (o *Orchestrator) ExecuteStream(ctx context.Context, request ChatRequest) (
 
 	go func() {
 		defer close(eventChan)
+		if o.elicitations != nil {
+			defer o.elicitations.attach(request.ConversationID, eventChan)()
+		}
 
 		// Build initial messages
-		messages := o.buildMessages(request)
+		tokenizer := llm.TokenizerFor(o.llmClient, request.Model)
+		messages, contextReport := o.prepareContext(ctx, request, o.buildMessages(request), tokenizer)
+		turnStart := len(messages)
 
 		llmTools, toolLookup, err := o.prepareToolContext(ctx, request)
 		if err != nil {
@@ -144,6 +159,8 @@ func (o *Orchestrator) ExecuteStream(ctx context.Context, request ChatRequest) (
 			}
 			return
 		}
+		llmTools = o.selectTools(ctx, request, llmTools, contextReport)
+		contextReport.ToolTokens = llm.CountToolTokens(tokenizer, llmTools)
 
 		iteration := 0
 
@@ -153,7 +170,7 @@ func (o *Orchestrator) ExecuteStream(ctx context.Context, request ChatRequest) (
 			// Build LLM request
 			chatRequest := llm.ChatRequest{
 				Model:       request.Model,
-				Messages:    messages,
+				Messages:    o.fitContext(ctx, request, messages, tokenizer, contextReport),
 				Tools:       llmTools,
 				Temperature: o.config.Temperature,
 				MaxTokens:   o.config.MaxTokens,
@@ -174,9 +191,10 @@ func (o *Orchestrator) ExecuteStream(ctx context.Context, request ChatRequest) (
 				wrapped := fmt.Errorf("%w: %v", ErrLLMUnavailable, err)
 				logging.LogErrorf(wrapped, "Unable to start LLM streaming")
 				eventChan <- StreamEvent{
-					Type:  StreamEventTypeError,
-					Error: wrapped,
-					Done:  true,
+					Type:     StreamEventTypeError,
+					Error:    wrapped,
+					Messages: transcript(messages, turnStart),
+					Done:     true,
 				}
 				return
 			}
@@ -185,13 +203,15 @@ func (o *Orchestrator) ExecuteStream(ctx context.Context, request ChatRequest) (
 			var contentBuilder strings.Builder
 			var assistantMsg llm.Message
 			assistantMsgSet := false
+			pendingCalls := newToolCallDeltaTracker(toolLookup)
 
 			for chunk := range streamChan {
 				if chunk.Error != nil {
 					eventChan <- StreamEvent{
-						Type:  StreamEventTypeError,
-						Error: chunk.Error,
-						Done:  true,
+						Type:     StreamEventTypeError,
+						Error:    chunk.Error,
+						Messages: transcript(messages, turnStart),
+						Done:     true,
 					}
 					return
 				}
@@ -206,6 +226,13 @@ func (o *Orchestrator) ExecuteStream(ctx context.Context, request ChatRequest) (
 					}
 				}
 
+				for _, partial := range chunk.Delta.ToolCalls {
+					eventChan <- StreamEvent{
+						Type:     StreamEventTypeToolCallDelta,
+						ToolCall: pendingCalls.apply(partial),
+					}
+				}
+
 				if chunk.Message != nil {
 					assistantMsg = *chunk.Message
 					assistantMsgSet = true
@@ -233,66 +260,17 @@ func (o *Orchestrator) ExecuteStream(ctx context.Context, request ChatRequest) (
 			// Check if done
 			if len(toolCalls) == 0 {
 				eventChan <- StreamEvent{
-					Type: StreamEventTypeDone,
-					Done: true,
+					Type:     StreamEventTypeDone,
+					Messages: transcript(messages, turnStart),
+					Context:  contextReport,
+					Done:     true,
 				}
 				return
 			}
 
 			// Execute tool calls
-			for _, toolCall := range toolCalls {
-				binding, ok := toolLookup[toolCall.Function.Name]
-				if !ok {
-					invalid := ToolExecution{
-						ToolName: toolCall.Function.Name,
-						Error:    ErrInvalidToolName,
-					}
-					eventChan <- StreamEvent{
-						Type: StreamEventTypeToolStart,
-						Tool: &invalid,
-					}
-					eventChan <- StreamEvent{
-						Type: StreamEventTypeToolComplete,
-						Tool: &invalid,
-					}
-					messages = append(messages, llm.Message{
-						Role:       llm.RoleTool,
-						ToolCallID: toolCall.ID,
-						Content:    fmt.Sprintf("Error: %v", ErrInvalidToolName),
-					})
-					continue
-				}
-
-				start := ToolExecution{
-					ServerName: binding.ServerName,
-					ToolName:   binding.Tool.Name,
-				}
-				eventChan <- StreamEvent{
-					Type: StreamEventTypeToolStart,
-					Tool: &start,
-				}
-
-				execution, err := o.executeTool(ctx, request, toolCall, binding)
-
-				completed := execution
-				eventChan <- StreamEvent{
-					Type: StreamEventTypeToolComplete,
-					Tool: &completed,
-				}
-
-				if err != nil {
-					messages = append(messages, llm.Message{
-						Role:       llm.RoleTool,
-						ToolCallID: toolCall.ID,
-						Content:    fmt.Sprintf("Error: %v", err),
-					})
-				} else {
-					messages = append(messages, llm.Message{
-						Role:       llm.RoleTool,
-						ToolCallID: toolCall.ID,
-						Content:    execution.Result,
-					})
-				}
+			for _, result := range o.executeToolCalls(ctx, request, toolCalls, toolLookup, eventChan) {
+				messages = append(messages, result.message)
 			}
 
 			// Continue loop
@@ -300,16 +278,215 @@ func (o *Orchestrator) ExecuteStream(ctx context.Context, request ChatRequest) (
 
 		// Max iterations reached
 		eventChan <- StreamEvent{
-			Type:    StreamEventTypeError,
-			Content: "Maximum iterations reached",
-			Error:   ErrMaxIterations,
-			Done:    true,
+			Type:     StreamEventTypeError,
+			Content:  "Maximum iterations reached",
+			Error:    ErrMaxIterations,
+			Messages: transcript(messages, turnStart),
+			Done:     true,
 		}
 	}()
 
 	return eventChan, nil
 }
 
+// prepareContext injects the contents of the most relevant resources after the system prompt
+// (when MaxContextResources is set) and starts the context report of the request
+func (o *Orchestrator) prepareContext(
+	ctx context.Context,
+	request ChatRequest,
+	messages []llm.Message,
+	tokenizer llm.Tokenizer,
+) ([]llm.Message, *ContextReport) {
+	report := &ContextReport{Tokenizer: tokenizer.Name(), TokenBudget: o.promptBudget()}
+	if o.config.MaxContextResources <= 0 || o.mcpManager == nil {
+		return messages, report
+	}
+
+	// Resources may use at most half of the budget so that the conversation still fits
+	resourceMessage, uris, err := o.contextManager.BuildResourceContext(
+		ctx,
+		request.ConversationID,
+		request.UserID,
+		request.BearerToken,
+		request.UserMessage,
+		request.ToolFilter,
+		o.config.MaxContextResources,
+		report.TokenBudget/2,
+		tokenizer,
+	)
+	if err != nil {
+		logging.LogWarningf(err, "Failed to build resource context")
+		return messages, report
+	}
+	if resourceMessage == nil {
+		return messages, report
+	}
+
+	head := 0
+	for head < len(messages) && messages[head].Role == llm.RoleSystem {
+		head++
+	}
+	injected := make([]llm.Message, 0, len(messages)+1)
+	injected = append(injected, messages[:head]...)
+	injected = append(injected, *resourceMessage)
+	injected = append(injected, messages[head:]...)
+
+	report.InjectedResources = uris
+	logging.LogDebugf("Injected %d resources into the context", len(uris))
+	return injected, report
+}
+
+// fitContext prunes the history to the token budget left after the tool definitions and
+// records the result in report
+func (o *Orchestrator) fitContext(
+	ctx context.Context,
+	request ChatRequest,
+	messages []llm.Message,
+	tokenizer llm.Tokenizer,
+	report *ContextReport,
+) []llm.Message {
+	budget := report.TokenBudget
+	if budget > 0 {
+		// Keep at least the system messages and the current turn when the tools fill the budget
+		budget = max(budget-report.ToolTokens, 1)
+	}
+
+	prompt, summarized := o.summarizeContext(ctx, request, messages, budget, tokenizer, report)
+	if !summarized {
+		prompt = o.contextManager.PruneMessages(messages, budget, tokenizer)
+		report.PrunedMessages = len(messages) - len(prompt)
+	}
+	report.PromptTokens = llm.CountMessageTokens(tokenizer, prompt) + report.ToolTokens
+	if report.PrunedMessages > 0 {
+		logging.LogDebugf("Pruned %d messages to fit the context budget of %d tokens", report.PrunedMessages, report.TokenBudget)
+	}
+	return prompt
+}
+
+// summarizeContext replaces the oldest history messages that do not fit budget with a
+// system message holding their summary. It reports false when summarization is disabled,
+// not needed or failed, in which case the messages are dropped instead.
+func (o *Orchestrator) summarizeContext(
+	ctx context.Context,
+	request ChatRequest,
+	messages []llm.Message,
+	budget int,
+	tokenizer llm.Tokenizer,
+	report *ContextReport,
+) ([]llm.Message, bool) {
+	if o.summarizer == nil || budget <= 0 || llm.CountMessageTokens(tokenizer, messages) <= budget {
+		return nil, false
+	}
+
+	// Reserve room for the summary and prune the rest of the history to what is left
+	reserve := min(maxSummaryTokens, budget/4)
+	dropped := len(messages) - len(o.contextManager.PruneMessages(messages, budget-reserve, tokenizer))
+	if dropped == 0 {
+		return nil, false
+	}
+
+	head := 0
+	for head < len(messages) && messages[head].Role == llm.RoleSystem {
+		head++
+	}
+
+	summary, err := o.summarizer.summarize(
+		ctx,
+		request.ConversationID,
+		request.Model,
+		messages[head:],
+		dropped,
+		reserve,
+		report.summary,
+	)
+	if err != nil {
+		logging.LogWarningf(err, "Failed to summarize history, dropping old messages instead")
+		return nil, false
+	}
+	report.summary = summary
+	report.SummarizedMessages = summary.MessageCount
+	report.PrunedMessages = summary.MessageCount
+
+	prompt := make([]llm.Message, 0, len(messages)-summary.MessageCount+1)
+	prompt = append(prompt, messages[:head]...)
+	prompt = append(prompt, llm.Message{
+		Role:    llm.RoleSystem,
+		Content: "Summary of the earlier conversation:\n" + summary.Content,
+	})
+	prompt = append(prompt, messages[head+summary.MessageCount:]...)
+	return prompt, true
+}
+
+// selectTools limits the tools sent to the LLM to the MaxTools most relevant to the
+// conversation plus the pinned ones, and records how many were left out in report
+func (o *Orchestrator) selectTools(ctx context.Context, request ChatRequest, tools []llm.Tool, report *ContextReport) []llm.Tool {
+	selected := o.toolSelector.SelectTools(ctx, tools, toolQuery(request), o.config.MaxTools, request.PinnedTools)
+	report.OmittedTools = len(tools) - len(selected)
+	if report.OmittedTools > 0 {
+		logging.LogDebugf("Selected %d of %d tools", len(selected), len(tools))
+	}
+	return selected
+}
+
+// promptBudget is the number of tokens available for the prompt: the context size minus
+// the room reserved for the completion
+func (o *Orchestrator) promptBudget() int {
+	budget := o.config.MaxContextTokens
+	if o.config.MaxTokens != nil && *o.config.MaxTokens < budget {
+		budget -= *o.config.MaxTokens
+	}
+	return budget
+}
+
+// transcript returns a copy of the messages appended to the history since index start
+func transcript(messages []llm.Message, start int) []llm.Message {
+	out := make([]llm.Message, len(messages)-start)
+	copy(out, messages[start:])
+	return out
+}
+
+// toolCallDeltaTracker assembles partial tool calls streamed by the LLM so that each
+// tool_call_delta event carries the resolved tool and the arguments received so far.
+type toolCallDeltaTracker struct {
+	lookup map[string]manager.ToolWithServer
+	calls  map[int]*ToolCallDelta
+	names  map[int]string
+}
+
+func newToolCallDeltaTracker(lookup map[string]manager.ToolWithServer) *toolCallDeltaTracker {
+	return &toolCallDeltaTracker{
+		lookup: lookup,
+		calls:  make(map[int]*ToolCallDelta),
+		names:  make(map[int]string),
+	}
+}
+
+func (t *toolCallDeltaTracker) apply(partial llm.ToolCall) *ToolCallDelta {
+	call, ok := t.calls[partial.Index]
+	if !ok {
+		call = &ToolCallDelta{Index: partial.Index}
+		t.calls[partial.Index] = call
+	}
+	if partial.ID != "" {
+		call.ID = partial.ID
+	}
+	if partial.Function.Name != "" {
+		t.names[partial.Index] += partial.Function.Name
+		name := t.names[partial.Index]
+		if binding, ok := t.lookup[name]; ok {
+			call.ServerName = binding.ServerName
+			call.ToolName = binding.Tool.Name
+		} else {
+			call.ToolName = name
+		}
+	}
+	call.ArgumentsDelta = partial.Function.Arguments
+	call.Arguments += partial.Function.Arguments
+
+	snapshot := *call
+	return &snapshot
+}
+
 func logLLMRequest(label string, req llm.ChatRequest) {
 	if viper.GetBool("VERBOSE") {
 		payload, _ := json.MarshalIndent(req, "", "  ")
@@ -327,7 +504,8 @@ func logLLMRequest(label string, req llm.ChatRequest) {
 	)
 }
 
-// prepareToolContext fetches available tools and builds both LLM tool definitions and a reverse lookup map.
+// prepareToolContext fetches the tools allowed by the request's tool filter and the tool
+// policy and builds both LLM tool definitions and a reverse lookup map.
 func (o *Orchestrator) prepareToolContext(ctx context.Context, request ChatRequest) ([]llm.Tool, map[string]manager.ToolWithServer, error) {
 	toolsWithServer, err := o.mcpManager.ListAllToolsForUser(ctx, request.UserID, request.BearerToken)
 	if err != nil {
@@ -337,15 +515,228 @@ func (o *Orchestrator) prepareToolContext(ctx context.Context, request ChatReque
 	llmTools := make([]llm.Tool, 0, len(toolsWithServer))
 	lookup := make(map[string]manager.ToolWithServer, len(toolsWithServer))
 
+	forbidden := 0
 	for _, t := range toolsWithServer {
+		if !request.ToolFilter.AllowsTool(t.ServerName, t.Tool.Name) {
+			continue
+		}
+		if !o.config.ToolPolicy.Allows(request.Claims, t.ServerName, t.Tool.Name) {
+			forbidden++
+			continue
+		}
 		llmTool := llm.ConvertMCPToolToLLMTool(t.Tool, t.ServerName)
 		llmTools = append(llmTools, llmTool)
 		lookup[llmTool.Function.Name] = t
 	}
+	if forbidden > 0 {
+		logging.LogDebugf("Tool policy hides %d tools from user %s", forbidden, request.UserID)
+	}
 
 	return llmTools, lookup, nil
 }
 
+// toolCallResult pairs a tool execution with the tool message to append to the history.
+type toolCallResult struct {
+	execution ToolExecution
+	message   llm.Message
+}
+
+// executeToolCalls runs the tool calls of one assistant turn, concurrently up to the
+// configured limits. Results are returned in the order of toolCalls so that tool
+// messages line up with the assistant's tool_call_ids. When events is non-nil, a
+// tool_start/tool_complete pair carrying the tool call ID is emitted for every call.
+func (o *Orchestrator) executeToolCalls(
+	ctx context.Context,
+	request ChatRequest,
+	toolCalls []llm.ToolCall,
+	toolLookup map[string]manager.ToolWithServer,
+	events chan<- StreamEvent,
+) []toolCallResult {
+	results := make([]toolCallResult, len(toolCalls))
+
+	serverOf := func(i int) string {
+		if binding, ok := toolLookup[toolCalls[i].Function.Name]; ok {
+			return binding.ServerName
+		}
+		return ""
+	}
+
+	serverLimits := make(map[string]int)
+	for i := range toolCalls {
+		name := serverOf(i)
+		if _, seen := serverLimits[name]; seen || name == "" {
+			continue
+		}
+		if cfg, ok := o.mcpManager.GetServerConfigForUser(ctx, request.UserID, name); ok {
+			serverLimits[name] = cfg.MaxParallelToolCalls
+		}
+	}
+
+	approvals := o.requestApprovals(ctx, request, toolCalls, toolLookup, events)
+
+	runner := newToolRunner(o.config.MaxParallelToolCalls, serverLimits)
+	runner.run(len(toolCalls), serverOf, func(i int) {
+		toolCall := toolCalls[i]
+
+		if approval := approvals[i]; approval != nil && !approval.Approved {
+			results[i] = o.deniedToolCall(toolCall, toolLookup, approval, events)
+			return
+		}
+
+		if events != nil {
+			start := ToolExecution{
+				ToolCallID: toolCall.ID,
+				ToolName:   toolCall.Function.Name,
+			}
+			if binding, ok := toolLookup[toolCall.Function.Name]; ok {
+				start.ServerName = binding.ServerName
+				start.ToolName = binding.Tool.Name
+			}
+			events <- StreamEvent{
+				Type: StreamEventTypeToolStart,
+				Tool: &start,
+			}
+		}
+
+		execution, content := o.handleToolCall(ctx, request, toolCall, toolLookup)
+		execution.ToolCallID = toolCall.ID
+		execution.Approval = approvals[i]
+
+		if events != nil {
+			completed := execution
+			events <- StreamEvent{
+				Type: StreamEventTypeToolComplete,
+				Tool: &completed,
+			}
+		}
+
+		results[i] = toolCallResult{
+			execution: execution,
+			message: llm.Message{
+				Role:       llm.RoleTool,
+				ToolCallID: toolCall.ID,
+				Content:    content,
+			},
+		}
+	})
+
+	return results
+}
+
+// requestApprovals asks the user to approve every tool call whose server policy requires it
+// and waits for all decisions. The result is indexed like toolCalls; entries are nil for
+// calls that need no approval. Without a client to ask, such calls are denied.
+func (o *Orchestrator) requestApprovals(
+	ctx context.Context,
+	request ChatRequest,
+	toolCalls []llm.ToolCall,
+	toolLookup map[string]manager.ToolWithServer,
+	events chan<- StreamEvent,
+) []*ToolApprovalDecision {
+	decisions := make([]*ToolApprovalDecision, len(toolCalls))
+	waiting := make(map[int]<-chan ToolApprovalDecision)
+
+	for i, toolCall := range toolCalls {
+		binding, ok := toolLookup[toolCall.Function.Name]
+		if !ok {
+			continue
+		}
+		serverCfg, ok := o.mcpManager.GetServerConfigForUser(ctx, request.UserID, binding.ServerName)
+		if !ok || !serverCfg.ToolRequiresApproval(binding.Tool.Name) {
+			continue
+		}
+		if events == nil || request.Approvals == nil {
+			decisions[i] = &ToolApprovalDecision{Reason: "approval required but no client can approve it"}
+			continue
+		}
+
+		waiting[i] = request.Approvals.register(toolCall.ID)
+
+		var args map[string]interface{}
+		_ = json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
+		events <- StreamEvent{
+			Type: StreamEventTypeToolApprovalRequired,
+			Tool: &ToolExecution{
+				ToolCallID: toolCall.ID,
+				ServerName: binding.ServerName,
+				ToolName:   binding.Tool.Name,
+				Arguments:  args,
+			},
+		}
+		logging.LogDebugf("Waiting for approval: %s.%s call=%s", binding.ServerName, binding.Tool.Name, toolCall.ID)
+	}
+
+	// All calls of the turn share one deadline, so the turn waits at most ApprovalTimeout
+	// however many calls need approval
+	var deadline time.Time
+	if o.config.ApprovalTimeout > 0 {
+		deadline = time.Now().Add(o.config.ApprovalTimeout)
+	}
+	for i, ch := range waiting {
+		decision, err := request.Approvals.wait(ctx, toolCalls[i].ID, ch, deadline)
+		if err != nil {
+			decision = ToolApprovalDecision{Reason: err.Error()}
+		}
+		decisions[i] = &decision
+	}
+
+	return decisions
+}
+
+// deniedToolCall builds the result for a tool call the user did not approve. The denial is
+// returned to the LLM as a tool error so it can adapt its plan, and streamed as a
+// tool_start/tool_complete pair like an executed call.
+func (o *Orchestrator) deniedToolCall(
+	toolCall llm.ToolCall,
+	toolLookup map[string]manager.ToolWithServer,
+	approval *ToolApprovalDecision,
+	events chan<- StreamEvent,
+) toolCallResult {
+	err := ErrToolDenied
+	if approval.Reason != "" {
+		err = fmt.Errorf("%w: %s", ErrToolDenied, approval.Reason)
+	}
+
+	execution := ToolExecution{
+		ToolCallID: toolCall.ID,
+		ToolName:   toolCall.Function.Name,
+		Error:      err,
+		Approval:   approval,
+	}
+	if binding, ok := toolLookup[toolCall.Function.Name]; ok {
+		execution.ServerName = binding.ServerName
+		execution.ToolName = binding.Tool.Name
+	}
+	_ = json.Unmarshal([]byte(toolCall.Function.Arguments), &execution.Arguments)
+
+	logging.LogDebugf("Tool call denied: %s.%s reason=%q", execution.ServerName, execution.ToolName, approval.Reason)
+
+	if events != nil {
+		events <- StreamEvent{
+			Type: StreamEventTypeToolStart,
+			Tool: &ToolExecution{
+				ToolCallID: execution.ToolCallID,
+				ServerName: execution.ServerName,
+				ToolName:   execution.ToolName,
+			},
+		}
+		completed := execution
+		events <- StreamEvent{
+			Type: StreamEventTypeToolComplete,
+			Tool: &completed,
+		}
+	}
+
+	return toolCallResult{
+		execution: execution,
+		message: llm.Message{
+			Role:       llm.RoleTool,
+			ToolCallID: toolCall.ID,
+			Content:    fmt.Sprintf("Error: %v", err),
+		},
+	}
+}
+
 // handleToolCall executes a tool and returns its execution record plus the message content to append.
 func (o *Orchestrator) handleToolCall(
 	ctx context.Context,
@@ -384,6 +775,25 @@ func (o *Orchestrator) executeTool(
 		ToolName:   binding.Tool.Name,
 	}
 
+	if !request.ToolFilter.AllowsTool(binding.ServerName, binding.Tool.Name) {
+		execution.Error = errors.Wrapf(ErrToolNotAllowed, "%s.%s", binding.ServerName, binding.Tool.Name)
+		return execution, execution.Error
+	}
+	if decision := o.config.ToolPolicy.Decide(request.Claims, binding.ServerName, binding.Tool.Name); !decision.Allowed {
+		logging.LogWarningf(
+			ErrToolForbidden,
+			"audit: refused tool call %s.%s: user=%s conversation=%s subject=%s reason=%s",
+			binding.ServerName,
+			binding.Tool.Name,
+			request.UserID,
+			request.ConversationID,
+			claimsSubject(request.Claims),
+			decision.Reason,
+		)
+		execution.Error = errors.Wrapf(ErrToolForbidden, "%s.%s", binding.ServerName, binding.Tool.Name)
+		return execution, execution.Error
+	}
+
 	// Parse arguments
 	var args map[string]interface{}
 	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
@@ -418,7 +828,7 @@ func (o *Orchestrator) executeTool(
 	logging.LogDebugf("Executing tool: %s.%s with args: %v", binding.ServerName, binding.Tool.Name, args)
 
 	// Ensure session exists for this server (just-in-time creation)
-	serverCfg, ok := o.mcpManager.GetServerConfig(binding.ServerName)
+	serverCfg, ok := o.mcpManager.GetServerConfigForUser(ctx, request.UserID, binding.ServerName)
 	if !ok {
 		execution.Error = errors.Errorf("unknown or disabled server: %s", binding.ServerName)
 		return execution, execution.Error
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+)
+
+func TestToolCallDeltaTracker(t *testing.T) {
+	lookup := map[string]manager.ToolWithServer{
+		"weather__forecast": {Tool: &mcp.Tool{Name: "forecast"}, ServerName: "weather"},
+	}
+	tracker := newToolCallDeltaTracker(lookup)
+
+	first := tracker.apply(llm.ToolCall{ID: "call_1", Index: 0, Function: llm.ToolCallFunction{Name: "weather__forecast"}})
+	assert.Equal(t, "call_1", first.ID)
+	assert.Equal(t, "weather", first.ServerName)
+	assert.Equal(t, "forecast", first.ToolName)
+	assert.Empty(t, first.Arguments)
+
+	tracker.apply(llm.ToolCall{Index: 0, Function: llm.ToolCallFunction{Arguments: `{"city":`}})
+	other := tracker.apply(llm.ToolCall{ID: "call_2", Index: 1, Function: llm.ToolCallFunction{Name: "unknown"}})
+	last := tracker.apply(llm.ToolCall{Index: 0, Function: llm.ToolCallFunction{Arguments: `"Berlin"}`}})
+
+	assert.Equal(t, "unknown", other.ToolName)
+	assert.Empty(t, other.ServerName)
+	assert.Equal(t, "call_1", last.ID)
+	assert.Equal(t, `"Berlin"}`, last.ArgumentsDelta)
+	assert.Equal(t, `{"city":"Berlin"}`, last.Arguments)
+}
+
+func TestToolApprovals(t *testing.T) {
+	approvals := NewToolApprovals()
+	assert.ErrorIs(t, approvals.Resolve("call_1", ToolApprovalDecision{Approved: true}), ErrNoPendingApproval)
+
+	ch := approvals.register("call_1")
+	assert.NoError(t, approvals.Resolve("call_1", ToolApprovalDecision{Reason: "no"}))
+	decision, err := approvals.wait(context.Background(), "call_1", ch, time.Time{})
+	assert.NoError(t, err)
+	assert.False(t, decision.Approved)
+	assert.Equal(t, "no", decision.Reason)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ch = approvals.register("call_2")
+	_, err = approvals.wait(ctx, "call_2", ch, time.Time{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, approvals.Resolve("call_2", ToolApprovalDecision{}), ErrNoPendingApproval)
+
+	ch = approvals.register("call_3")
+	_, err = approvals.wait(context.Background(), "call_3", ch, time.Now().Add(time.Millisecond))
+	assert.ErrorIs(t, err, ErrApprovalTimeout)
+	assert.ErrorIs(t, approvals.Resolve("call_3", ToolApprovalDecision{}), ErrNoPendingApproval)
+
+	// A decision that arrived while an earlier call was waiting counts after the deadline
+	ch = approvals.register("call_4")
+	assert.NoError(t, approvals.Resolve("call_4", ToolApprovalDecision{Approved: true}))
+	decision, err = approvals.wait(context.Background(), "call_4", ch, time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.True(t, decision.Approved)
+}
+
+func TestDeniedToolCall_StreamsStartAndComplete(t *testing.T) {
+	o := &Orchestrator{}
+	events := make(chan StreamEvent, 2)
+	result := o.deniedToolCall(
+		llm.ToolCall{ID: "call_1", Function: llm.ToolCallFunction{Name: "db__drop", Arguments: `{}`}},
+		nil,
+		&ToolApprovalDecision{Reason: "no"},
+		events,
+	)
+	close(events)
+
+	var types []StreamEventType
+	for event := range events {
+		assert.Equal(t, "call_1", event.Tool.ToolCallID)
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []StreamEventType{StreamEventTypeToolStart, StreamEventTypeToolComplete}, types)
+	assert.ErrorIs(t, result.execution.Error, ErrToolDenied)
+}
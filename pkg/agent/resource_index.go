@@ -0,0 +1,408 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+const (
+	// resourceChunkSize is the target size of a resource chunk in bytes
+	resourceChunkSize = 1500
+	// resourceChunkOverlap is how much of the previous chunk is repeated at a chunk's start
+	resourceChunkOverlap = 200
+	// defaultResourceIndexTTL is how long indexed content is used before it is read again
+	defaultResourceIndexTTL = 15 * time.Minute
+)
+
+// ResourceIndex stores embedded chunks of MCP resource contents per user and retrieves the
+// chunks most similar to a query. Vectors are searched with pgvector when the column exists
+// and ranked in-process otherwise.
+type ResourceIndex struct {
+	db       *gorm.DB
+	embedder llm.Embedder
+	ttl      time.Duration
+	pgvector bool
+
+	// mu guards users; synchronization is serialized per user so that concurrent requests
+	// do not index twice, without one user's reads and embeddings blocking everyone else
+	mu    sync.Mutex
+	users map[uuid.UUID]*userSyncLock
+}
+
+// userSyncLock serializes the synchronization of one user's index
+type userSyncLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// ResourceChunkMatch is an indexed chunk retrieved for a query
+type ResourceChunkMatch struct {
+	ServerName string
+	URI        string
+	ChunkIndex int
+	Content    string
+	Score      float64
+}
+
+// NewResourceIndex creates a resource index. Indexed content is read again after ttl
+// (default 15 minutes).
+func NewResourceIndex(db *gorm.DB, embedder llm.Embedder, ttl time.Duration) *ResourceIndex {
+	if ttl <= 0 {
+		ttl = defaultResourceIndexTTL
+	}
+	return &ResourceIndex{
+		db:       db,
+		embedder: embedder,
+		ttl:      ttl,
+		pgvector: db.Migrator().HasColumn(&models.ResourceChunk{}, models.ResourceChunkVectorColumn),
+		users:    make(map[uuid.UUID]*userSyncLock),
+	}
+}
+
+// lockUser acquires the synchronization lock of the user and returns its release function
+func (ri *ResourceIndex) lockUser(userID uuid.UUID) func() {
+	ri.mu.Lock()
+	lock, ok := ri.users[userID]
+	if !ok {
+		lock = &userSyncLock{}
+		ri.users[userID] = lock
+	}
+	lock.waiters++
+	ri.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		ri.mu.Lock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(ri.users, userID)
+		}
+		ri.mu.Unlock()
+	}
+}
+
+// Sync indexes the resources whose index entry is missing or older than the TTL, reading
+// them with read, and removes entries of resources that are no longer listed. Only servers
+// in scope are synchronized (nil = all); entries of other servers are left alone.
+func (ri *ResourceIndex) Sync(
+	ctx context.Context,
+	userID uuid.UUID,
+	resources []manager.ResourceWithServer,
+	scope func(serverName string) bool,
+	read func(ctx context.Context, resource manager.ResourceWithServer) (string, error),
+) error {
+	defer ri.lockUser(userID)()
+	if scope == nil {
+		scope = func(string) bool { return true }
+	}
+
+	var indexed []struct {
+		ServerName  string
+		URI         string
+		ContentHash string
+		UpdatedAt   time.Time
+	}
+	if err := ri.db.Model(&models.ResourceChunk{}).
+		Select("server_name, uri, content_hash, max(updated_at) AS updated_at").
+		Where("user_id = ?", userID).
+		Group("server_name, uri, content_hash").
+		Scan(&indexed).Error; err != nil {
+		return errors.Wrap(err, "failed to load resource index")
+	}
+
+	type entry struct {
+		hash      string
+		updatedAt time.Time
+	}
+	entries := make(map[string]entry, len(indexed))
+	for _, e := range indexed {
+		entries[resourceKey(e.ServerName, e.URI)] = entry{hash: e.ContentHash, updatedAt: e.UpdatedAt}
+	}
+
+	listed := make(map[string]bool, len(resources))
+	for _, r := range resources {
+		if r.Resource == nil || !scope(r.ServerName) {
+			continue
+		}
+		key := resourceKey(r.ServerName, r.Resource.URI)
+		listed[key] = true
+
+		existing, ok := entries[key]
+		if ok && time.Since(existing.updatedAt) < ri.ttl {
+			continue
+		}
+
+		content, err := read(ctx, r)
+		if err != nil {
+			logging.LogWarningf(err, "Failed to read resource %s for indexing", r.Resource.URI)
+			continue
+		}
+		hash := contentHash(content)
+		if ok && existing.hash == hash {
+			if err := ri.touch(userID, r.ServerName, r.Resource.URI); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := ri.index(ctx, userID, r.ServerName, r.Resource.URI, content, hash); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range indexed {
+		if scope(e.ServerName) && !listed[resourceKey(e.ServerName, e.URI)] {
+			if err := ri.remove(userID, e.ServerName, e.URI); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Search returns up to limit chunks of the user's indexed resources on the given servers
+// that are most similar to the query
+func (ri *ResourceIndex) Search(
+	ctx context.Context,
+	userID uuid.UUID,
+	serverNames []string,
+	query string,
+	limit int,
+) ([]ResourceChunkMatch, error) {
+	if limit <= 0 || len(serverNames) == 0 {
+		return nil, nil
+	}
+
+	vectors, err := ri.embedder.Embed(ctx, "", []string{query})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to embed query")
+	}
+	if len(vectors) != 1 || len(vectors[0]) == 0 {
+		return nil, errors.New("embedder returned no query vector")
+	}
+
+	if ri.pgvector {
+		return ri.searchPGVector(userID, serverNames, vectors[0], limit)
+	}
+	return ri.searchInProcess(userID, serverNames, vectors[0], limit)
+}
+
+// searchPGVector ranks chunks by cosine distance in the database, using the HNSW index of
+// the vector column
+func (ri *ResourceIndex) searchPGVector(userID uuid.UUID, serverNames []string, query []float32, limit int) ([]ResourceChunkMatch, error) {
+	literal := vectorLiteral(query)
+	var matches []ResourceChunkMatch
+	err := ri.db.Raw(
+		"SELECT server_name, uri, chunk_index, content, 1 - ("+models.ResourceChunkVectorColumn+" <=> ?::vector) AS score "+
+			"FROM resource_chunks "+
+			"WHERE user_id = ? AND server_name IN ? AND "+models.ResourceChunkVectorColumn+" IS NOT NULL "+
+			"ORDER BY "+models.ResourceChunkVectorColumn+" <=> ?::vector LIMIT ?",
+		literal, userID, serverNames, literal, limit,
+	).Scan(&matches).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search resource chunks")
+	}
+	return matches, nil
+}
+
+// searchInProcess loads the user's chunks and ranks them by cosine similarity
+func (ri *ResourceIndex) searchInProcess(userID uuid.UUID, serverNames []string, query []float32, limit int) ([]ResourceChunkMatch, error) {
+	var chunks []models.ResourceChunk
+	if err := ri.db.
+		Select("server_name, uri, chunk_index, content, embedding").
+		Where("user_id = ? AND server_name IN ? AND embedding IS NOT NULL", userID, serverNames).
+		Find(&chunks).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to load resource chunks")
+	}
+
+	matches := make([]ResourceChunkMatch, 0, len(chunks))
+	for _, chunk := range chunks {
+		var vector []float32
+		if err := json.Unmarshal(chunk.Embedding, &vector); err != nil || len(vector) != len(query) {
+			continue
+		}
+		matches = append(matches, ResourceChunkMatch{
+			ServerName: chunk.ServerName,
+			URI:        chunk.URI,
+			ChunkIndex: chunk.ChunkIndex,
+			Content:    chunk.Content,
+			Score:      llm.CosineSimilarity(query, vector),
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// index replaces the chunks of a resource with the chunks of its new content
+func (ri *ResourceIndex) index(ctx context.Context, userID uuid.UUID, serverName, uri, content, hash string) error {
+	texts := chunkText(content, resourceChunkSize, resourceChunkOverlap)
+
+	var vectors [][]float32
+	if len(texts) > 0 {
+		var err error
+		vectors, err = ri.embedder.Embed(ctx, "", texts)
+		if err != nil {
+			return errors.Wrapf(err, "failed to embed resource %s", uri)
+		}
+		if len(vectors) != len(texts) {
+			return errors.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(texts))
+		}
+	}
+
+	err := ri.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND server_name = ? AND uri = ?", userID, serverName, uri).
+			Delete(&models.ResourceChunk{}).Error; err != nil {
+			return err
+		}
+
+		// Resources without text keep an empty entry so that they are not read on every sync
+		if len(texts) == 0 {
+			return tx.Create(&models.ResourceChunk{
+				UserID:      userID,
+				ServerName:  serverName,
+				URI:         uri,
+				ContentHash: hash,
+			}).Error
+		}
+
+		for i, text := range texts {
+			embedding, err := json.Marshal(vectors[i])
+			if err != nil {
+				return err
+			}
+			chunk := models.ResourceChunk{
+				UserID:      userID,
+				ServerName:  serverName,
+				URI:         uri,
+				ChunkIndex:  i,
+				Content:     text,
+				ContentHash: hash,
+				Embedding:   datatypes.JSON(embedding),
+			}
+			if err := tx.Create(&chunk).Error; err != nil {
+				return err
+			}
+			if ri.pgvector {
+				if err := tx.Exec(
+					"UPDATE resource_chunks SET "+models.ResourceChunkVectorColumn+" = ?::vector WHERE id = ?",
+					vectorLiteral(vectors[i]), chunk.ID,
+				).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to store chunks of resource %s", uri)
+	}
+
+	logging.LogDebugf("Indexed resource %s of server %s: chunks=%d", uri, serverName, len(texts))
+	return nil
+}
+
+// touch marks the chunks of an unchanged resource as fresh
+func (ri *ResourceIndex) touch(userID uuid.UUID, serverName, uri string) error {
+	err := ri.db.Model(&models.ResourceChunk{}).
+		Where("user_id = ? AND server_name = ? AND uri = ?", userID, serverName, uri).
+		Update("updated_at", time.Now()).Error
+	return errors.Wrapf(err, "failed to refresh index of resource %s", uri)
+}
+
+// remove deletes the chunks of a resource
+func (ri *ResourceIndex) remove(userID uuid.UUID, serverName, uri string) error {
+	err := ri.db.Where("user_id = ? AND server_name = ? AND uri = ?", userID, serverName, uri).
+		Delete(&models.ResourceChunk{}).Error
+	return errors.Wrapf(err, "failed to remove resource %s from the index", uri)
+}
+
+// chunkText splits text into chunks of about size bytes that overlap by about overlap bytes.
+// Chunks end at paragraph, line or word boundaries where possible.
+func chunkText(text string, size, overlap int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var chunks []string
+	start := 0
+	for start < len(text) {
+		end := start + size
+		if end >= len(text) {
+			chunks = append(chunks, strings.TrimSpace(text[start:]))
+			break
+		}
+
+		// Cut at the last boundary in the second half of the chunk
+		cut := end
+		for _, sep := range []string{"\n\n", "\n", " "} {
+			if i := strings.LastIndex(text[start+size/2:end], sep); i >= 0 {
+				cut = start + size/2 + i + len(sep)
+				break
+			}
+		}
+		for cut > start && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		chunks = append(chunks, strings.TrimSpace(text[start:cut]))
+
+		// Start the next chunk at a word boundary within the overlap
+		next := max(cut-overlap, start+1)
+		if i := strings.IndexByte(text[next:cut], ' '); i >= 0 {
+			next += i + 1
+		}
+		for next < len(text) && !utf8.RuneStart(text[next]) {
+			next++
+		}
+		start = next
+	}
+	return chunks
+}
+
+// vectorLiteral renders a vector in pgvector's text format
+func vectorLiteral(vector []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%g", v)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func resourceKey(serverName, uri string) string {
+	return serverName + "\x00" + uri
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkText(t *testing.T) {
+	assert.Nil(t, chunkText("  \n ", 100, 20))
+	assert.Equal(t, []string{"short text"}, chunkText(" short text\n", 100, 20))
+
+	paragraph := strings.TrimSpace(strings.Repeat("lorem ipsum ", 6)) // 71 bytes
+	text := paragraph + "\n\n" + paragraph + "\n\n" + paragraph
+	chunks := chunkText(text, 100, 20)
+	require.Len(t, chunks, 3)
+
+	// Chunks end at paragraph boundaries and the next one repeats the tail of the previous
+	assert.Equal(t, paragraph, chunks[0])
+	assert.True(t, strings.HasPrefix(chunks[1], "ipsum"), chunks[1])
+	assert.True(t, strings.HasSuffix(chunks[1], paragraph), chunks[1])
+	assert.True(t, strings.HasSuffix(chunks[2], paragraph), chunks[2])
+
+	// Multi-byte runes are never split
+	for _, chunk := range chunkText(strings.Repeat("ä", 200), 51, 10) {
+		assert.True(t, strings.HasPrefix(chunk, "ä") && strings.HasSuffix(chunk, "ä"))
+	}
+}
+
+func TestVectorLiteral(t *testing.T) {
+	assert.Equal(t, "[1,-0.5,0.25]", vectorLiteral([]float32{1, -0.5, 0.25}))
+	assert.Equal(t, "[]", vectorLiteral(nil))
+}
+
+func TestResourceIndex_LocksPerUser(t *testing.T) {
+	ri := &ResourceIndex{users: make(map[uuid.UUID]*userSyncLock)}
+	alice, bob := uuid.New(), uuid.New()
+
+	unlockAlice := ri.lockUser(alice)
+	// Another user's synchronization does not wait for alice's
+	unlockBob := ri.lockUser(bob)
+	unlockBob()
+
+	locked := make(chan struct{})
+	go func() {
+		defer ri.lockUser(alice)()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("second synchronization of the same user did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockAlice()
+	<-locked
+
+	assert.Eventually(t, func() bool {
+		ri.mu.Lock()
+		defer ri.mu.Unlock()
+		return len(ri.users) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// Pruning strategies for history that exceeds the context budget
+const (
+	// PruningStrategyDrop drops the oldest messages
+	PruningStrategyDrop = "drop"
+	// PruningStrategySummarize condenses the oldest messages into a rolling summary
+	PruningStrategySummarize = "summarize"
+)
+
+const (
+	// summaryMetadataKey is the key of the summary in Conversation.Metadata
+	summaryMetadataKey = "summary"
+	// maxSummaryTokens caps the size of a summary
+	maxSummaryTokens = 1024
+	// maxSummarizedToolResultChars truncates long tool results in the summarization input
+	maxSummarizedToolResultChars = 2000
+)
+
+const summarySystemPrompt = `You are a conversation summarizer. Condense the conversation below into a concise summary that lets an assistant continue the conversation without the original messages.
+Keep every fact, name, number, decision, open question and user preference that may matter later, as well as the results of tool calls. Leave out pleasantries and repetition.
+If a previous summary is given, extend it with the new messages and return the complete updated summary.
+Return ONLY the summary text, written in the third person, no preamble.`
+
+// conversationSummary is the rolling summary stored in Conversation.Metadata
+type conversationSummary struct {
+	Content string `json:"content"`
+	// MessageCount is the number of history messages, from the start, the summary covers
+	MessageCount int `json:"messageCount"`
+	// Fingerprint identifies the covered messages, so that a summary of edited or deleted
+	// messages is not reused
+	Fingerprint string `json:"fingerprint"`
+}
+
+// summarizer condenses old history into a summary kept on the conversation
+type summarizer struct {
+	db        *gorm.DB
+	llmClient llm.Client
+	model     string
+}
+
+// summarize returns the summary of the first count history messages. The summary is reused
+// and extended from previous, or else from the summary stored on the conversation, when it
+// still matches the history; it may then cover more messages than requested.
+func (s *summarizer) summarize(
+	ctx context.Context,
+	conversationID uuid.UUID,
+	model string,
+	history []llm.Message,
+	count int,
+	maxTokens int,
+	previous *conversationSummary,
+) (*conversationSummary, error) {
+	if previous == nil {
+		previous = s.load(conversationID)
+	}
+
+	content := ""
+	covered := 0
+	if previous != nil && previous.MessageCount <= len(history) &&
+		previous.Fingerprint == fingerprintMessages(history[:previous.MessageCount]) {
+		content = previous.Content
+		covered = previous.MessageCount
+	}
+	if covered >= count {
+		logging.LogDebugf("Reusing summary of %d messages for conversation %s", covered, conversationID)
+		return previous, nil
+	}
+
+	content, err := s.generate(ctx, model, content, history[covered:count], maxTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &conversationSummary{
+		Content:      content,
+		MessageCount: count,
+		Fingerprint:  fingerprintMessages(history[:count]),
+	}
+	if err := s.store(conversationID, *summary); err != nil {
+		logging.LogWarningf(err, "Failed to store summary of conversation %s", conversationID)
+	}
+
+	logging.LogDebugf("Summarized %d messages of conversation %s", count-covered, conversationID)
+	return summary, nil
+}
+
+// generate asks the LLM to extend the previous summary with the messages
+func (s *summarizer) generate(ctx context.Context, model, previous string, messages []llm.Message, maxTokens int) (string, error) {
+	var input strings.Builder
+	if previous != "" {
+		input.WriteString("Previous summary:\n")
+		input.WriteString(previous)
+		input.WriteString("\n\nNew messages:\n")
+	} else {
+		input.WriteString("Messages:\n")
+	}
+	input.WriteString(formatTranscript(messages))
+
+	if model == "" {
+		model = s.model
+	}
+	response, err := s.llmClient.Chat(ctx, llm.ChatRequest{
+		Model: model,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: summarySystemPrompt},
+			{Role: llm.RoleUser, Content: input.String()},
+		},
+		Temperature: float64Ptr(0.2),
+		MaxTokens:   intPtr(maxTokens),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to summarize conversation")
+	}
+
+	content := strings.TrimSpace(response.Message.Content)
+	if content == "" {
+		return "", errors.New("LLM returned an empty summary")
+	}
+	return content, nil
+}
+
+// load reads the stored summary of a conversation
+func (s *summarizer) load(conversationID uuid.UUID) *conversationSummary {
+	if s.db == nil || conversationID == uuid.Nil {
+		return nil
+	}
+
+	var conversation models.Conversation
+	if err := s.db.Select("metadata").Where("id = ?", conversationID).First(&conversation).Error; err != nil {
+		return nil
+	}
+
+	var metadata map[string]json.RawMessage
+	if len(conversation.Metadata) == 0 || json.Unmarshal(conversation.Metadata, &metadata) != nil {
+		return nil
+	}
+	raw, ok := metadata[summaryMetadataKey]
+	if !ok {
+		return nil
+	}
+
+	var summary conversationSummary
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		logging.LogWarningf(err, "Ignoring malformed summary of conversation %s", conversationID)
+		return nil
+	}
+	return &summary
+}
+
+// store saves the summary in the conversation's metadata. Only the summary key is replaced
+// in the database, so concurrent changes of other metadata keys are kept.
+func (s *summarizer) store(conversationID uuid.UUID, summary conversationSummary) error {
+	if s.db == nil || conversationID == uuid.Nil {
+		return nil
+	}
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode summary")
+	}
+	result := s.db.Model(&models.Conversation{}).
+		Where("id = ?", conversationID).
+		Update("metadata", models.SetConversationMetadataKey(summaryMetadataKey, summaryJSON))
+	if result.Error != nil {
+		return errors.Wrap(result.Error, "failed to store summary")
+	}
+	if result.RowsAffected == 0 {
+		return errors.Wrap(gorm.ErrRecordNotFound, "failed to load conversation")
+	}
+	return nil
+}
+
+// formatTranscript renders messages as plain text for the summarizer
+func formatTranscript(messages []llm.Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		switch msg.Role {
+		case llm.RoleUser:
+			fmt.Fprintf(&b, "User: %s\n", msg.Content)
+		case llm.RoleAssistant:
+			if msg.Content != "" {
+				fmt.Fprintf(&b, "Assistant: %s\n", msg.Content)
+			}
+			for _, tc := range msg.ToolCalls {
+				fmt.Fprintf(&b, "Assistant called tool %s with %s\n", tc.Function.Name, tc.Function.Arguments)
+			}
+		case llm.RoleTool:
+			content := msg.Content
+			if len(content) > maxSummarizedToolResultChars {
+				content = content[:maxSummarizedToolResultChars] + "..."
+			}
+			fmt.Fprintf(&b, "Tool result: %s\n", content)
+		default:
+			fmt.Fprintf(&b, "%s: %s\n", msg.Role, msg.Content)
+		}
+	}
+	return b.String()
+}
+
+// fingerprintMessages hashes the parts of messages that a summary depends on
+func fingerprintMessages(messages []llm.Message) string {
+	h := sha256.New()
+	for _, msg := range messages {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", msg.Role, msg.Content, msg.ToolCallID)
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(h, "%s\x00%s\x00%s\x00", tc.ID, tc.Function.Name, tc.Function.Arguments)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
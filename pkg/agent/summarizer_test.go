@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+
+	"github.com/d4l-data4life/go-svc/pkg/db"
+)
+
+// summaryClient answers every chat request with a fixed summary and records the requests
+type summaryClient struct {
+	llm.Client
+	requests []llm.ChatRequest
+}
+
+func (c *summaryClient) Chat(_ context.Context, request llm.ChatRequest) (*llm.ChatResponse, error) {
+	c.requests = append(c.requests, request)
+	return &llm.ChatResponse{Message: llm.Message{Role: llm.RoleAssistant, Content: "summary"}}, nil
+}
+
+func TestSummarizeContext_ReplacesPrunedHistory(t *testing.T) {
+	long := strings.Repeat("x", 400) // ~100 tokens
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "system"},
+		{Role: llm.RoleUser, Content: long},
+		{Role: llm.RoleAssistant, Content: long},
+		{Role: llm.RoleUser, Content: long},
+		{Role: llm.RoleAssistant, Content: "ok"},
+		{Role: llm.RoleUser, Content: "and now?"},
+	}
+	client := &summaryClient{}
+	o := &Orchestrator{
+		contextManager: NewContextManager(nil, 0),
+		summarizer:     &summarizer{llmClient: client, model: "test-model"},
+	}
+	report := &ContextReport{}
+
+	// Nothing to summarize without a budget
+	prompt := o.fitContext(context.Background(), ChatRequest{}, messages, nil, report)
+	assert.Equal(t, messages, prompt)
+	assert.Empty(t, client.requests)
+
+	report.TokenBudget = 200
+	prompt = o.fitContext(context.Background(), ChatRequest{}, messages, nil, report)
+	require.Len(t, client.requests, 1)
+	assert.Equal(t, "test-model", client.requests[0].Model)
+	assert.Contains(t, client.requests[0].Messages[1].Content, "User: "+long+"\nAssistant: "+long)
+	assert.Equal(t, 2, report.SummarizedMessages)
+	assert.Equal(t, report.SummarizedMessages, report.PrunedMessages)
+	require.Len(t, prompt, 5)
+	assert.Equal(t, messages[0], prompt[0])
+	assert.Equal(t, llm.RoleSystem, prompt[1].Role)
+	assert.Contains(t, prompt[1].Content, "summary")
+	assert.Equal(t, messages[3:], prompt[2:])
+
+	// The summary of the request is reused while it covers the pruned messages
+	o.fitContext(context.Background(), ChatRequest{}, messages, nil, report)
+	assert.Len(t, client.requests, 1)
+
+	// Further pruned messages extend the previous summary
+	report.TokenBudget = 100
+	prompt = o.fitContext(context.Background(), ChatRequest{}, messages, nil, report)
+	require.Len(t, client.requests, 2)
+	assert.Contains(t, client.requests[1].Messages[1].Content, "Previous summary:\nsummary")
+	assert.NotContains(t, client.requests[1].Messages[1].Content, "Assistant: "+long)
+	assert.Equal(t, 3, report.SummarizedMessages)
+	assert.Equal(t, messages[4:], prompt[2:])
+}
+
+func TestSummarizerStore_KeepsOtherMetadata(t *testing.T) {
+	models.InitializeTestDB(t)
+	defer db.Close()
+
+	user := models.User{ID: uuid.New()}
+	require.NoError(t, db.Get().Create(&user).Error)
+	conversation := models.Conversation{UserID: user.ID, Metadata: datatypes.JSON(`{"pinnedTools": ["files"]}`)}
+	require.NoError(t, db.Get().Create(&conversation).Error)
+
+	s := &summarizer{db: db.Get()}
+	require.NoError(t, s.store(conversation.ID, conversationSummary{Content: "summary"}))
+
+	var stored models.Conversation
+	require.NoError(t, db.Get().First(&stored, "id = ?", conversation.ID).Error)
+	var metadata map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(stored.Metadata, &metadata))
+	assert.JSONEq(t, `["files"]`, string(metadata["pinnedTools"]))
+	assert.Equal(t, "summary", s.load(conversation.ID).Content)
+}
+
+func TestNewAgent_RejectsUnknownPruningStrategy(t *testing.T) {
+	_, err := NewAgent(nil, nil, nil, Config{PruningStrategy: "truncate", DefaultModel: "model"})
+	assert.ErrorIs(t, err, ErrUnknownPruningStrategy)
+}
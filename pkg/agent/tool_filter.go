@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"path"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+)
+
+// ToolFilter restricts the MCP servers and tools a conversation can use. Tool patterns
+// match qualified tool names (<server>__<tool>) and may use glob wildcards, e.g.
+// "files__read*". A nil filter allows everything.
+type ToolFilter struct {
+	// Servers lists the servers the conversation may use (empty = all enabled servers)
+	Servers []string
+	// AllowedTools lists the tool patterns the conversation may use (empty = all tools of
+	// the selected servers)
+	AllowedTools []string
+	// BlockedTools lists tool patterns the conversation may not use, even when allowed
+	BlockedTools []string
+}
+
+// AllowsServer reports whether the conversation may use the server
+func (f *ToolFilter) AllowsServer(serverName string) bool {
+	if f == nil || len(f.Servers) == 0 {
+		return true
+	}
+	for _, name := range f.Servers {
+		if name == serverName {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsTool reports whether the conversation may use the tool of the server
+func (f *ToolFilter) AllowsTool(serverName, toolName string) bool {
+	if f == nil {
+		return true
+	}
+	if !f.AllowsServer(serverName) {
+		return false
+	}
+
+	name := llm.QualifiedToolName(serverName, toolName)
+	if matchesToolPattern(name, f.BlockedTools) {
+		return false
+	}
+	return len(f.AllowedTools) == 0 || matchesToolPattern(name, f.AllowedTools)
+}
+
+// matchesToolPattern reports whether the qualified tool name matches one of the patterns.
+// Malformed patterns only match literally.
+func matchesToolPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == name {
+			return true
+		}
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
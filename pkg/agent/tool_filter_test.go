@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToolFilter_NilAllowsEverything(t *testing.T) {
+	var filter *ToolFilter
+	assert.True(t, filter.AllowsServer("files"))
+	assert.True(t, filter.AllowsTool("files", "write_file"))
+}
+
+func TestToolFilter_Servers(t *testing.T) {
+	filter := &ToolFilter{Servers: []string{"files"}}
+	assert.True(t, filter.AllowsServer("files"))
+	assert.False(t, filter.AllowsServer("github"))
+	assert.True(t, filter.AllowsTool("files", "write_file"))
+	assert.False(t, filter.AllowsTool("github", "list_issues"))
+}
+
+func TestToolFilter_ReadOnly(t *testing.T) {
+	filter := &ToolFilter{
+		AllowedTools: []string{"files__read*", "files__list_directory", "github__*"},
+		BlockedTools: []string{"github__create_*"},
+	}
+	assert.True(t, filter.AllowsTool("files", "read_file"))
+	assert.True(t, filter.AllowsTool("files", "list_directory"))
+	assert.False(t, filter.AllowsTool("files", "write_file"))
+	assert.True(t, filter.AllowsTool("github", "list_issues"))
+	assert.False(t, filter.AllowsTool("github", "create_issue"))
+
+	// Malformed patterns only match literally
+	assert.False(t, (&ToolFilter{AllowedTools: []string{"files__["}}).AllowsTool("files", "read_file"))
+}
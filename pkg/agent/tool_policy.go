@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"path"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+)
+
+// ToolPolicy decides which tools a user may see and call based on the claims of their
+// token. A nil policy allows everything.
+//
+// For a tool, a matching deny rule that applies to the user refuses it. Otherwise, when
+// allow rules match the tool, only users they apply to may use it. Tools no rule matches
+// follow the default.
+type ToolPolicy struct {
+	defaultAllow bool
+	rules        []config.ToolPolicyRule
+}
+
+// PolicyDecision is the outcome of a tool policy evaluation
+type PolicyDecision struct {
+	Allowed bool
+	// Reason names the deciding rule (by index) or the default
+	Reason string
+}
+
+// NewToolPolicy validates the configuration and creates the policy
+func NewToolPolicy(cfg config.ToolPolicyConfig) (*ToolPolicy, error) {
+	policy := &ToolPolicy{defaultAllow: true, rules: cfg.Rules}
+	switch cfg.Default {
+	case "", config.PolicyEffectAllow:
+	case config.PolicyEffectDeny:
+		policy.defaultAllow = false
+	default:
+		return nil, errors.Errorf("invalid tool policy default %q", cfg.Default)
+	}
+	for i, rule := range cfg.Rules {
+		switch rule.Effect {
+		case "", config.PolicyEffectAllow, config.PolicyEffectDeny:
+		default:
+			return nil, errors.Errorf("invalid effect %q of tool policy rule %d", rule.Effect, i)
+		}
+		if _, err := path.Match(rule.Server, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid server pattern of tool policy rule %d", i)
+		}
+		for _, tool := range rule.Tools {
+			if _, err := path.Match(tool, ""); err != nil {
+				return nil, errors.Wrapf(err, "invalid tool pattern of tool policy rule %d", i)
+			}
+		}
+	}
+	return policy, nil
+}
+
+// Decide evaluates the policy for a tool of a server and the user's claims (nil claims
+// carry no groups, roles or scopes)
+func (p *ToolPolicy) Decide(claims *auth.Claims, serverName, toolName string) PolicyDecision {
+	if p == nil {
+		return PolicyDecision{Allowed: true, Reason: "no policy"}
+	}
+
+	allowRuleMatched := false
+	allowedBy := -1
+	for i, rule := range p.rules {
+		if !ruleMatchesTool(rule, serverName, toolName) {
+			continue
+		}
+		applies := ruleAppliesTo(rule, claims)
+		if rule.Effect == config.PolicyEffectDeny {
+			if applies {
+				return PolicyDecision{Allowed: false, Reason: ruleReason(i)}
+			}
+			continue
+		}
+		allowRuleMatched = true
+		if applies && allowedBy < 0 {
+			allowedBy = i
+		}
+	}
+
+	switch {
+	case allowedBy >= 0:
+		return PolicyDecision{Allowed: true, Reason: ruleReason(allowedBy)}
+	case allowRuleMatched:
+		return PolicyDecision{Allowed: false, Reason: "no allow rule applies"}
+	default:
+		return PolicyDecision{Allowed: p.defaultAllow, Reason: "default"}
+	}
+}
+
+// Allows reports whether the user may use the tool of the server
+func (p *ToolPolicy) Allows(claims *auth.Claims, serverName, toolName string) bool {
+	return p.Decide(claims, serverName, toolName).Allowed
+}
+
+func ruleMatchesTool(rule config.ToolPolicyRule, serverName, toolName string) bool {
+	if rule.Server != "" && rule.Server != "*" && !matchesPattern(rule.Server, serverName) {
+		return false
+	}
+	if len(rule.Tools) == 0 {
+		return true
+	}
+	for _, tool := range rule.Tools {
+		if matchesPattern(tool, toolName) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleAppliesTo(rule config.ToolPolicyRule, claims *auth.Claims) bool {
+	if len(rule.Groups) == 0 && len(rule.Roles) == 0 && len(rule.Scopes) == 0 {
+		return true
+	}
+	return claims.HasGroup(rule.Groups...) || claims.HasRole(rule.Roles...) || claims.HasScope(rule.Scopes...)
+}
+
+func matchesPattern(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+func ruleReason(index int) string {
+	return "rule " + strconv.Itoa(index)
+}
+
+// claimsSubject returns the token subject for audit logs
+func claimsSubject(claims *auth.Claims) string {
+	if claims == nil {
+		return ""
+	}
+	return claims.Subject
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+)
+
+func TestToolPolicy_NilAllowsEverything(t *testing.T) {
+	var policy *ToolPolicy
+	assert.True(t, policy.Allows(nil, "files", "write_file"))
+}
+
+func TestToolPolicy_Rules(t *testing.T) {
+	policy, err := NewToolPolicy(config.ToolPolicyConfig{
+		Rules: []config.ToolPolicyRule{
+			{Server: "files", Tools: []string{"write_*", "delete_*"}, Groups: []string{"admins"}},
+			{Server: "github", Effect: config.PolicyEffectDeny, Groups: []string{"contractors"}},
+			{Server: "github", Tools: []string{"create_*"}, Scopes: []string{"issues:write"}},
+		},
+	})
+	require.NoError(t, err)
+
+	admin := &auth.Claims{Subject: "alice", Groups: []string{"admins"}}
+	contractor := &auth.Claims{Subject: "bob", Groups: []string{"contractors"}, Scopes: []string{"issues:write"}}
+	writer := &auth.Claims{Subject: "carol", Scopes: []string{"issues:write"}}
+
+	// Tools with allow rules are restricted to the users they apply to
+	assert.True(t, policy.Allows(admin, "files", "write_file"))
+	assert.False(t, policy.Allows(writer, "files", "write_file"))
+	assert.False(t, policy.Allows(nil, "files", "delete_file"))
+	assert.Equal(t, "rule 0", policy.Decide(admin, "files", "write_file").Reason)
+
+	// Tools no rule matches follow the default
+	assert.True(t, policy.Allows(nil, "files", "read_file"))
+	assert.Equal(t, "default", policy.Decide(nil, "files", "read_file").Reason)
+
+	// Deny rules take precedence over allow rules
+	assert.False(t, policy.Allows(contractor, "github", "list_issues"))
+	assert.False(t, policy.Allows(contractor, "github", "create_issue"))
+	assert.Equal(t, "rule 1", policy.Decide(contractor, "github", "create_issue").Reason)
+	assert.True(t, policy.Allows(writer, "github", "create_issue"))
+	assert.True(t, policy.Allows(writer, "github", "list_issues"))
+	assert.False(t, policy.Allows(admin, "github", "create_issue"))
+}
+
+func TestToolPolicy_DefaultDeny(t *testing.T) {
+	policy, err := NewToolPolicy(config.ToolPolicyConfig{
+		Default: config.PolicyEffectDeny,
+		Rules: []config.ToolPolicyRule{
+			{Server: "*", Tools: []string{"read_*", "list_*"}},
+			{Server: "github", Roles: []string{"Developer"}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, policy.Allows(nil, "files", "read_file"))
+	assert.False(t, policy.Allows(nil, "files", "write_file"))
+	assert.True(t, policy.Allows(&auth.Claims{Roles: []string{"Developer"}}, "github", "create_issue"))
+	assert.False(t, policy.Allows(&auth.Claims{Roles: []string{"Reader"}}, "github", "create_issue"))
+}
+
+func TestNewToolPolicy_Invalid(t *testing.T) {
+	_, err := NewToolPolicy(config.ToolPolicyConfig{Default: "maybe"})
+	assert.Error(t, err)
+
+	_, err = NewToolPolicy(config.ToolPolicyConfig{Rules: []config.ToolPolicyRule{{Effect: "block"}}})
+	assert.Error(t, err)
+
+	_, err = NewToolPolicy(config.ToolPolicyConfig{Rules: []config.ToolPolicyRule{{Tools: []string{"write_["}}}})
+	assert.Error(t, err)
+}
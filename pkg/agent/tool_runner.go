@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"sync"
+)
+
+// toolRunner executes the tool calls of one assistant turn with bounded concurrency.
+// A global limit caps the number of calls in flight; servers may further restrict how
+// many of their own tools run at the same time.
+type toolRunner struct {
+	limit        int
+	serverLimits map[string]int
+}
+
+func newToolRunner(limit int, serverLimits map[string]int) *toolRunner {
+	if limit < 1 {
+		limit = 1
+	}
+	return &toolRunner{
+		limit:        limit,
+		serverLimits: serverLimits,
+	}
+}
+
+// run calls fn for every index in [0, n). serverOf returns the server an index belongs to
+// ("" when unknown). run returns once all calls have finished.
+func (r *toolRunner) run(n int, serverOf func(i int) string, fn func(i int)) {
+	if n == 0 {
+		return
+	}
+	if r.limit == 1 || n == 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	global := make(chan struct{}, r.limit)
+	perServer := make(map[string]chan struct{})
+	for i := 0; i < n; i++ {
+		server := serverOf(i)
+		if _, ok := perServer[server]; ok {
+			continue
+		}
+		if limit := r.serverLimits[server]; limit > 0 {
+			perServer[server] = make(chan struct{}, limit)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// The server slot is taken first so that calls queued behind a busy server do
+			// not hold global slots that calls to other servers could use. Deferred
+			// releases run in reverse order.
+			if sem, ok := perServer[serverOf(i)]; ok {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+
+			global <- struct{}{}
+			defer func() { <-global }()
+
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToolRunner_RespectsLimits(t *testing.T) {
+	servers := []string{"a", "a", "a", "b", "b", "c"}
+	runner := newToolRunner(3, map[string]int{"a": 1})
+
+	var (
+		inFlight, maxInFlight, inA, maxInA int32
+		mu                                 sync.Mutex
+		done                               = make([]bool, len(servers))
+	)
+	track := func(cur int32, max *int32) {
+		for {
+			old := atomic.LoadInt32(max)
+			if cur <= old || atomic.CompareAndSwapInt32(max, old, cur) {
+				return
+			}
+		}
+	}
+
+	runner.run(len(servers), func(i int) string { return servers[i] }, func(i int) {
+		track(atomic.AddInt32(&inFlight, 1), &maxInFlight)
+		if servers[i] == "a" {
+			track(atomic.AddInt32(&inA, 1), &maxInA)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if servers[i] == "a" {
+			atomic.AddInt32(&inA, -1)
+		}
+		atomic.AddInt32(&inFlight, -1)
+
+		mu.Lock()
+		done[i] = true
+		mu.Unlock()
+	})
+
+	assert.LessOrEqual(t, maxInFlight, int32(3))
+	assert.Greater(t, maxInFlight, int32(1))
+	assert.Equal(t, int32(1), maxInA)
+	for i, d := range done {
+		assert.True(t, d, "call %d did not run", i)
+	}
+}
+
+func TestToolRunner_BusyServerDoesNotBlockOthers(t *testing.T) {
+	// Calls to "a" queue behind the first one, which only finishes after "b" ran. Had the
+	// queued "a" call taken the second global slot, "b" could never start.
+	servers := []string{"a", "a", "b"}
+	bDone := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		newToolRunner(2, map[string]int{"a": 1}).run(len(servers), func(i int) string { return servers[i] }, func(i int) {
+			switch {
+			case servers[i] == "b":
+				close(bDone)
+			case i == 0:
+				<-bDone
+			}
+		})
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call to an idle server waited for a busy server")
+	}
+}
+
+func TestToolRunner_SequentialWhenLimitIsOne(t *testing.T) {
+	var order []int
+	newToolRunner(1, nil).run(4, func(int) string { return "" }, func(i int) {
+		order = append(order, i)
+	})
+	assert.Equal(t, []int{0, 1, 2, 3}, order)
+}
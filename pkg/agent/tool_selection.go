@@ -0,0 +1,316 @@
+package agent
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// BM25 parameters: term frequency saturation and document length normalization
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// toolQueryUserMessages is the number of recent user messages that make up the query
+// tools are ranked against
+const toolQueryUserMessages = 3
+
+// stopWords are left out of BM25 terms
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "with": true,
+}
+
+// ToolSelector ranks tools by their relevance to a conversation so that only the most
+// relevant ones are sent to the LLM
+type ToolSelector struct {
+	embedder llm.Embedder
+
+	mu sync.Mutex
+	// embeddings caches tool embeddings by tool name
+	embeddings map[string]toolEmbedding
+}
+
+// toolEmbedding is the embedding of a tool's document
+type toolEmbedding struct {
+	document string
+	vector   []float32
+}
+
+// NewToolSelector creates a tool selector. Tools are ranked with BM25, or by embedding
+// similarity when embedder is non-nil.
+func NewToolSelector(embedder llm.Embedder) *ToolSelector {
+	return &ToolSelector{
+		embedder:   embedder,
+		embeddings: make(map[string]toolEmbedding),
+	}
+}
+
+// SelectTools returns the pinned tools plus the maxTools other tools most relevant to the
+// query, in their original order. A pinned entry is a qualified tool name (<server>__<tool>)
+// or a server name, which pins all tools of the server. All tools are returned when maxTools
+// is not positive or there are no more tools than that.
+func (s *ToolSelector) SelectTools(
+	ctx context.Context,
+	tools []llm.Tool,
+	query string,
+	maxTools int,
+	pinned []string,
+) []llm.Tool {
+	if maxTools <= 0 || len(tools) <= maxTools {
+		return tools
+	}
+
+	selected := make([]bool, len(tools))
+	candidates := make([]int, 0, len(tools))
+	for i, tool := range tools {
+		if isPinnedTool(tool.Function.Name, pinned) {
+			selected[i] = true
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+
+	if len(candidates) > maxTools {
+		documents := make([]string, len(candidates))
+		for j, i := range candidates {
+			documents[j] = toolDocument(tools[i])
+		}
+
+		scores, err := s.embeddingScores(ctx, candidates, tools, documents, query)
+		if err != nil {
+			logging.LogWarningf(err, "Failed to rank tools by embeddings, using BM25")
+		}
+		if scores == nil {
+			scores = bm25Scores(documents, query)
+		}
+
+		order := make([]int, len(candidates))
+		for j := range order {
+			order[j] = j
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return scores[order[a]] > scores[order[b]]
+		})
+		order = order[:maxTools]
+
+		for _, j := range order {
+			selected[candidates[j]] = true
+		}
+	} else {
+		for _, i := range candidates {
+			selected[i] = true
+		}
+	}
+
+	result := make([]llm.Tool, 0, maxTools+len(tools)-len(candidates))
+	for i, tool := range tools {
+		if selected[i] {
+			result = append(result, tool)
+		}
+	}
+	return result
+}
+
+// embeddingScores scores candidate tools by the similarity of their documents to the query.
+// It returns nil without an embedder.
+func (s *ToolSelector) embeddingScores(
+	ctx context.Context,
+	candidates []int,
+	tools []llm.Tool,
+	documents []string,
+	query string,
+) ([]float64, error) {
+	if s.embedder == nil {
+		return nil, nil
+	}
+
+	// Take the cached vectors and embed the query together with the tools whose document
+	// is not cached yet. The lock is not held while embedding, so concurrent requests may
+	// embed the same tool; the last one stores it.
+	vectors := make([][]float32, len(candidates))
+	texts := []string{query}
+	var missing []int
+	s.mu.Lock()
+	for j, i := range candidates {
+		if cached, ok := s.embeddings[tools[i].Function.Name]; ok && cached.document == documents[j] {
+			vectors[j] = cached.vector
+			continue
+		}
+		texts = append(texts, documents[j])
+		missing = append(missing, j)
+	}
+	s.mu.Unlock()
+
+	embedded, err := s.embedder.Embed(ctx, "", texts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to embed tools")
+	}
+	if len(embedded) != len(texts) {
+		return nil, errors.Errorf("embedder returned %d vectors for %d texts", len(embedded), len(texts))
+	}
+
+	s.mu.Lock()
+	for k, j := range missing {
+		vectors[j] = embedded[k+1]
+		s.embeddings[tools[candidates[j]].Function.Name] = toolEmbedding{
+			document: documents[j],
+			vector:   embedded[k+1],
+		}
+	}
+	s.mu.Unlock()
+
+	scores := make([]float64, len(candidates))
+	for j := range candidates {
+		scores[j] = llm.CosineSimilarity(embedded[0], vectors[j])
+	}
+	return scores, nil
+}
+
+// bm25Scores scores documents against the query with Okapi BM25
+func bm25Scores(documents []string, query string) []float64 {
+	scores := make([]float64, len(documents))
+	queryTerms := uniqueTerms(tokenizeTerms(query))
+	if len(queryTerms) == 0 || len(documents) == 0 {
+		return scores
+	}
+
+	frequencies := make([]map[string]int, len(documents))
+	lengths := make([]int, len(documents))
+	totalLength := 0
+	for i, document := range documents {
+		terms := tokenizeTerms(document)
+		frequencies[i] = make(map[string]int, len(terms))
+		for _, term := range terms {
+			frequencies[i][term]++
+		}
+		lengths[i] = len(terms)
+		totalLength += len(terms)
+	}
+	avgLength := float64(totalLength) / float64(len(documents))
+	if avgLength == 0 {
+		return scores
+	}
+
+	n := float64(len(documents))
+	for _, term := range queryTerms {
+		df := 0
+		for _, freq := range frequencies {
+			if freq[term] > 0 {
+				df++
+			}
+		}
+		if df == 0 {
+			continue
+		}
+		idf := math.Log((n-float64(df)+0.5)/(float64(df)+0.5) + 1)
+
+		for i, freq := range frequencies {
+			tf := float64(freq[term])
+			if tf == 0 {
+				continue
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(lengths[i])/avgLength)
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+	}
+	return scores
+}
+
+// toolDocument is the text a tool is ranked by: its name, description and parameters
+func toolDocument(tool llm.Tool) string {
+	parts := []string{tool.Function.Name, tool.Function.Description}
+	if properties, ok := tool.Function.Parameters["properties"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			parts = append(parts, name)
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				if description, ok := property["description"].(string); ok {
+					parts = append(parts, description)
+				}
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// tokenizeTerms splits text into lower-case terms at non-alphanumeric characters and
+// camelCase boundaries, leaving out stop words
+func tokenizeTerms(text string) []string {
+	var terms []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			term := strings.ToLower(string(current))
+			if !stopWords[term] {
+				terms = append(terms, term)
+			}
+			current = current[:0]
+		}
+	}
+
+	var prev rune
+	for _, r := range text {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+		prev = r
+	}
+	flush()
+	return terms
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := terms[:0:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// isPinnedTool reports whether the qualified tool name matches a pinned tool or server
+func isPinnedTool(name string, pinned []string) bool {
+	for _, p := range pinned {
+		if p == name || strings.HasPrefix(name, llm.QualifiedToolName(p, "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolQuery is the text tools are ranked against: the current user message and the
+// preceding user messages of the history
+func toolQuery(request ChatRequest) string {
+	parts := []string{request.UserMessage}
+	for i := len(request.Messages) - 1; i >= 0 && len(parts) < toolQueryUserMessages; i-- {
+		if request.Messages[i].Role == llm.RoleUser {
+			parts = append(parts, request.Messages[i].Content)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+)
+
+// keywordEmbedder embeds texts as one dimension per keyword occurrence
+type keywordEmbedder struct {
+	keywords []string
+	calls    int
+}
+
+func (e *keywordEmbedder) Embed(_ context.Context, _ string, texts []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e.keywords))
+		for k, keyword := range e.keywords {
+			for _, term := range tokenizeTerms(text) {
+				if term == keyword {
+					vectors[i][k]++
+				}
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func testTools() []llm.Tool {
+	tool := func(server, name, description string) llm.Tool {
+		return llm.Tool{Function: llm.ToolFunction{
+			Name:        llm.QualifiedToolName(server, name),
+			Description: description,
+			Parameters: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"city": map[string]interface{}{"description": "Name of the city"},
+				},
+			},
+		}}
+	}
+	return []llm.Tool{
+		tool("files", "readFile", "Read a file from disk"),
+		tool("weather", "getForecast", "Get the weather forecast"),
+		tool("files", "writeFile", "Write a file to disk"),
+		tool("calendar", "listEvents", "List calendar events"),
+	}
+}
+
+func toolNames(tools []llm.Tool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Function.Name
+	}
+	return names
+}
+
+func TestSelectTools_BM25(t *testing.T) {
+	tools := testTools()
+	selector := NewToolSelector(nil)
+
+	assert.Equal(t, tools, selector.SelectTools(context.Background(), tools, "anything", 0, nil))
+	assert.Equal(t, tools, selector.SelectTools(context.Background(), tools, "anything", 4, nil))
+
+	selected := selector.SelectTools(context.Background(), tools, "What is the weather forecast for Berlin?", 1, nil)
+	assert.Equal(t, []string{"weather__getForecast"}, toolNames(selected))
+
+	// camelCase names are split into terms; the original order is kept
+	selected = selector.SelectTools(context.Background(), tools, "please write the file and read it back", 2, nil)
+	assert.Equal(t, []string{"files__readFile", "files__writeFile"}, toolNames(selected))
+
+	// Pinned tools and servers are added to the ranked ones
+	selected = selector.SelectTools(context.Background(), tools, "weather", 1, []string{"calendar", "files__readFile"})
+	assert.Equal(t, []string{"files__readFile", "weather__getForecast", "calendar__listEvents"}, toolNames(selected))
+}
+
+func TestSelectTools_Embeddings(t *testing.T) {
+	tools := testTools()
+	embedder := &keywordEmbedder{keywords: []string{"calendar", "weather", "disk"}}
+	selector := NewToolSelector(embedder)
+
+	selected := selector.SelectTools(context.Background(), tools, "what is on my calendar", 1, nil)
+	assert.Equal(t, []string{"calendar__listEvents"}, toolNames(selected))
+
+	// Tool embeddings are cached, only the query is embedded again
+	selected = selector.SelectTools(context.Background(), tools, "how is the weather", 1, nil)
+	assert.Equal(t, []string{"weather__getForecast"}, toolNames(selected))
+	require.Equal(t, 2, embedder.calls)
+	assert.Len(t, selector.embeddings, len(tools))
+}
+
+// blockingEmbedder blocks the first call until release is closed
+type blockingEmbedder struct {
+	inner   *keywordEmbedder
+	mu      sync.Mutex
+	calls   int
+	release chan struct{}
+}
+
+func (e *blockingEmbedder) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	e.calls++
+	first := e.calls == 1
+	e.mu.Unlock()
+	if first {
+		<-e.release
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.inner.Embed(ctx, model, texts)
+}
+
+func TestSelectTools_EmbedsWithoutLock(t *testing.T) {
+	tools := testTools()
+	embedder := &blockingEmbedder{
+		inner:   &keywordEmbedder{keywords: []string{"calendar", "weather", "disk"}},
+		release: make(chan struct{}),
+	}
+	selector := NewToolSelector(embedder)
+
+	first := make(chan []llm.Tool)
+	go func() {
+		first <- selector.SelectTools(context.Background(), tools, "what is on my calendar", 1, nil)
+	}()
+	for {
+		embedder.mu.Lock()
+		started := embedder.calls == 1
+		embedder.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A slow embedding request does not block other requests
+	done := make(chan []llm.Tool)
+	go func() {
+		done <- selector.SelectTools(context.Background(), tools, "how is the weather", 1, nil)
+	}()
+	select {
+	case selected := <-done:
+		assert.Equal(t, []string{"weather__getForecast"}, toolNames(selected))
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool selection waited for another request's embeddings")
+	}
+
+	close(embedder.release)
+	assert.Equal(t, []string{"calendar__listEvents"}, toolNames(<-first))
+	assert.Len(t, selector.embeddings, len(tools))
+}
+
+func TestToolQuery(t *testing.T) {
+	query := toolQuery(ChatRequest{
+		UserMessage: "current",
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: "first"},
+			{Role: llm.RoleUser, Content: "second"},
+			{Role: llm.RoleAssistant, Content: "answer"},
+			{Role: llm.RoleUser, Content: "third"},
+		},
+	})
+	assert.Equal(t, "current\nthird\nsecond", query)
+}
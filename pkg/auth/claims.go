@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Claims are the claims of a validated token that the user and authorization decisions
+// are based on
+type Claims struct {
+	Subject string `json:"sub,omitempty"`
+	Issuer  string `json:"iss,omitempty"`
+	// UserID is the value of the claim that identifies the user (see ClaimMapping)
+	UserID string `json:"userId,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email,omitempty"`
+	// EmailVerified is the email_verified claim: the issuer checked that Email belongs to the user
+	EmailVerified bool     `json:"emailVerified,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	// Scopes come from "scp" (Azure AD) or "scope", space-separated or as a list
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// ClaimMapping names the claims the user's ID, display name and email are read from.
+// Empty fields use the defaults.
+type ClaimMapping struct {
+	// UserID defaults to the first of oid (Azure AD), sub and email
+	UserID string
+	// Name defaults to name
+	Name string
+	// Email defaults to email
+	Email string
+}
+
+// defaultUserIDClaims are tried in order when ClaimMapping.UserID is empty
+var defaultUserIDClaims = []string{"oid", "sub", "email"}
+
+// ClaimsFromToken extracts the claims of a validated token with the default mapping
+func ClaimsFromToken(token jwt.Token) *Claims {
+	return ClaimMapping{}.Claims(token)
+}
+
+// Claims extracts the user and the groups, roles and scopes of a validated token
+func (m ClaimMapping) Claims(token jwt.Token) *Claims {
+	userIDClaims := defaultUserIDClaims
+	if m.UserID != "" {
+		userIDClaims = []string{m.UserID}
+	}
+	claims := &Claims{
+		Subject: token.Subject(),
+		Issuer:  token.Issuer(),
+		UserID:  stringClaim(token, userIDClaims...),
+		Name:    stringClaim(token, valueOr(m.Name, "name")),
+		Email:   stringClaim(token, valueOr(m.Email, "email")),
+		Groups:  stringsClaim(token, "groups"),
+		Roles:   stringsClaim(token, "roles"),
+		Scopes:  stringsClaim(token, "scp"),
+
+		EmailVerified: boolClaim(token, "email_verified"),
+	}
+	if len(claims.Scopes) == 0 {
+		claims.Scopes = stringsClaim(token, "scope")
+	}
+	return claims
+}
+
+// HasGroup reports whether the claims contain one of the groups
+func (c *Claims) HasGroup(groups ...string) bool {
+	return c != nil && containsAny(c.Groups, groups)
+}
+
+// HasRole reports whether the claims contain one of the roles
+func (c *Claims) HasRole(roles ...string) bool {
+	return c != nil && containsAny(c.Roles, roles)
+}
+
+// HasScope reports whether the claims contain one of the scopes
+func (c *Claims) HasScope(scopes ...string) bool {
+	return c != nil && containsAny(c.Scopes, scopes)
+}
+
+// ClaimsExtractor is implemented by token validators that map claims themselves, such as
+// the validator returned by WithClaimMapping
+type ClaimsExtractor interface {
+	ExtractClaims(token jwt.Token) *Claims
+}
+
+// UserIDMapper is implemented by token validators that derive the user's UUID from more
+// than the user ID claim, such as an OIDCValidator with scoped issuers
+type UserIDMapper interface {
+	UserUUID(claims *Claims) uuid.UUID
+}
+
+// UserUUID returns the UUID of a user ID claim: the claim itself when it is a UUID, a
+// name-based UUID of it otherwise
+func UserUUID(userID string) uuid.UUID {
+	if id, err := uuid.Parse(userID); err == nil {
+		return id
+	}
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(userID))
+}
+
+// mappedValidator validates tokens with another validator and extracts their claims with
+// a ClaimMapping
+type mappedValidator struct {
+	TokenValidator
+	mapping ClaimMapping
+}
+
+// WithClaimMapping returns a validator that validates tokens with validator and reads the
+// user from the claims named by mapping
+func WithClaimMapping(validator TokenValidator, mapping ClaimMapping) TokenValidator {
+	return &mappedValidator{TokenValidator: validator, mapping: mapping}
+}
+
+// ExtractClaims implements ClaimsExtractor
+func (v *mappedValidator) ExtractClaims(token jwt.Token) *Claims {
+	return v.mapping.Claims(token)
+}
+
+// UserUUID implements UserIDMapper by deferring to the wrapped validator
+func (v *mappedValidator) UserUUID(claims *Claims) uuid.UUID {
+	if mapper, ok := v.TokenValidator.(UserIDMapper); ok {
+		return mapper.UserUUID(claims)
+	}
+	return UserUUID(claims.UserID)
+}
+
+// stringClaim returns the first of the claims that is a non-empty string
+func stringClaim(token jwt.Token, names ...string) string {
+	for _, name := range names {
+		if value, ok := token.Get(name); ok {
+			if s, ok := value.(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// boolClaim reads a claim that is a boolean or, as some issuers send it, the string "true"
+func boolClaim(token jwt.Token, name string) bool {
+	value, ok := token.Get(name)
+	if !ok {
+		return false
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// stringsClaim reads a claim that is a list of strings or a space-separated string
+func stringsClaim(token jwt.Token, name string) []string {
+	value, ok := token.Get(name)
+	if !ok {
+		return nil
+	}
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, w := range wanted {
+		for _, v := range values {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+	"gorm.io/gorm"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+)
+
+const (
+	// LocalTokenIssuer is the issuer claim of access tokens issued by LocalAuth
+	// #nosec G101 -- This is not a credential, just an identifier
+	LocalTokenIssuer = "go-mcp-host"
+	// DefaultAccessTokenTTL is how long access tokens are valid unless configured
+	DefaultAccessTokenTTL = 15 * time.Minute
+	// DefaultRefreshTokenTTL is how long a session can be refreshed without use unless configured
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+	// DefaultSessionLifetime is how long a session lasts at most unless configured
+	DefaultSessionLifetime = 90 * 24 * time.Hour
+
+	sessionIDClaim = "sid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrSessionRevoked      = errors.New("session expired or revoked")
+)
+
+// TokenPair is an access token and the refresh token that renews it
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// LocalAuth issues and validates the tokens of locally registered users. Every login is a
+// models.AuthSession: access tokens are HS256 JWTs naming the session and are rejected once
+// it is revoked, refresh tokens are stored hashed and rotated on every use. Presenting a
+// rotated refresh token again revokes the session, and no session outlives its lifetime.
+type LocalAuth struct {
+	db              *gorm.DB
+	validator       *LocalJWTValidator
+	jwtSecret       []byte
+	accessTTL       time.Duration
+	refreshTTL      time.Duration
+	sessionLifetime time.Duration
+}
+
+// NewLocalAuth creates the local auth for sessions stored in db and tokens signed with
+// jwtSecret. Zero durations use the defaults.
+func NewLocalAuth(db *gorm.DB, jwtSecret []byte, cfg config.LocalAuthSettings) (*LocalAuth, error) {
+	validator, err := NewLocalJWTValidator(jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+	a := &LocalAuth{
+		db:              db,
+		validator:       validator,
+		jwtSecret:       jwtSecret,
+		accessTTL:       cfg.AccessTokenTTL,
+		refreshTTL:      cfg.RefreshTokenTTL,
+		sessionLifetime: cfg.SessionLifetime,
+	}
+	if a.accessTTL <= 0 {
+		a.accessTTL = DefaultAccessTokenTTL
+	}
+	if a.refreshTTL <= 0 {
+		a.refreshTTL = DefaultRefreshTokenTTL
+	}
+	if a.sessionLifetime <= 0 {
+		a.sessionLifetime = DefaultSessionLifetime
+	}
+	return a, nil
+}
+
+// Issue starts a session for the user and returns its tokens
+func (a *LocalAuth) Issue(ctx context.Context, userID uuid.UUID) (*TokenPair, error) {
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &models.AuthSession{
+		UserID:           userID,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		MaxExpiresAt:     now.Add(a.sessionLifetime),
+	}
+	session.ExpiresAt = a.refreshExpiry(session, now)
+
+	err = a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Expired sessions of the user are no longer needed
+		if err := tx.Where("user_id = ? AND expires_at < ?", userID, now).Delete(&models.AuthSession{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(session).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return a.tokenPair(session, refreshToken, now)
+}
+
+// Refresh rotates the refresh token of a session and issues a new access token. The old
+// refresh token is no longer accepted; presenting it again revokes the session. The
+// session is extended by the refresh TTL, up to its lifetime.
+func (a *LocalAuth) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := time.Now()
+	tokenHash := hashRefreshToken(refreshToken)
+	var session models.AuthSession
+	err := a.db.WithContext(ctx).
+		Where("refresh_token_hash = ? AND expires_at > ?", tokenHash, now).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, a.revokeRotatedToken(ctx, tokenHash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	newToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := a.refreshExpiry(&session, now)
+	err = a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The old hash is part of the condition, so of two concurrent refreshes only one wins
+		result := tx.Model(&models.AuthSession{}).
+			Where("id = ? AND refresh_token_hash = ?", session.ID, session.RefreshTokenHash).
+			Updates(map[string]interface{}{
+				"refresh_token_hash": hashRefreshToken(newToken),
+				"expires_at":         expiresAt,
+				"updated_at":         now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidRefreshToken
+		}
+		return tx.Create(&models.AuthRotatedRefreshToken{
+			TokenHash: session.RefreshTokenHash,
+			SessionID: session.ID,
+		}).Error
+	})
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	session.ExpiresAt = expiresAt
+
+	return a.tokenPair(&session, newToken, now)
+}
+
+// revokeRotatedToken handles a refresh token that matches no active session. When it is a
+// token the session already rotated, it leaked and the session is revoked.
+func (a *LocalAuth) revokeRotatedToken(ctx context.Context, tokenHash string) error {
+	var rotated models.AuthRotatedRefreshToken
+	err := a.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&rotated).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load rotated refresh token: %w", err)
+	}
+	if err := a.RevokeSession(ctx, rotated.SessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return fmt.Errorf("%w: reused after rotation, session revoked", ErrInvalidRefreshToken)
+}
+
+// refreshExpiry returns when a session refreshed at now expires: after the refresh TTL,
+// but not after its lifetime
+func (a *LocalAuth) refreshExpiry(session *models.AuthSession, now time.Time) time.Time {
+	expiresAt := now.Add(a.refreshTTL)
+	if expiresAt.After(session.MaxExpiresAt) {
+		return session.MaxExpiresAt
+	}
+	return expiresAt
+}
+
+// RevokeRefreshToken ends the session of the refresh token
+func (a *LocalAuth) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	return a.db.WithContext(ctx).
+		Where("refresh_token_hash = ?", hashRefreshToken(refreshToken)).
+		Delete(&models.AuthSession{}).Error
+}
+
+// RevokeSession ends the session an access token was issued for
+func (a *LocalAuth) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	return a.db.WithContext(ctx).Where("id = ?", sessionID).Delete(&models.AuthSession{}).Error
+}
+
+// ValidateJWT validates an access token with the local key and checks that its session has
+// not been revoked and its user still exists
+func (a *LocalAuth) ValidateJWT(token string) (*jwt.Token, error) {
+	t, err := a.validator.ValidateJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	if (*t).Issuer() != LocalTokenIssuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrTokenValidation, (*t).Issuer())
+	}
+	sessionID, ok := SessionID(*t)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing session", ErrTokenValidation)
+	}
+
+	var count int64
+	err = a.db.Model(&models.AuthSession{}).
+		Joins("JOIN users ON users.id = auth_sessions.user_id AND users.deleted_at IS NULL").
+		Where("auth_sessions.id = ? AND auth_sessions.user_id = ? AND auth_sessions.expires_at > ?", sessionID, (*t).Subject(), time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check session: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrSessionRevoked
+	}
+	return t, nil
+}
+
+// SessionID returns the session an access token of LocalAuth was issued for
+func SessionID(token jwt.Token) (uuid.UUID, bool) {
+	value, ok := token.Get(sessionIDClaim)
+	if !ok {
+		return uuid.Nil, false
+	}
+	s, ok := value.(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	sessionID, err := uuid.Parse(s)
+	return sessionID, err == nil
+}
+
+// tokenPair signs an access token for the session
+func (a *LocalAuth) tokenPair(session *models.AuthSession, refreshToken string, now time.Time) (*TokenPair, error) {
+	expiresAt := now.Add(a.accessTTL)
+	token := jwt.New()
+	claims := map[string]interface{}{
+		jwt.SubjectKey:    session.UserID.String(),
+		jwt.IssuedAtKey:   now.Unix(),
+		jwt.ExpirationKey: expiresAt.Unix(),
+		jwt.IssuerKey:     LocalTokenIssuer,
+		jwt.JwtIDKey:      uuid.New().String(),
+		sessionIDClaim:    session.ID.String(),
+	}
+	for name, value := range claims {
+		if err := token.Set(name, value); err != nil {
+			return nil, fmt.Errorf("failed to set %s claim: %w", name, err)
+		}
+	}
+
+	signed, err := jwt.Sign(token, jwa.HS256, a.jwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return &TokenPair{
+		AccessToken:      string(signed),
+		AccessExpiresAt:  expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// newRefreshToken returns a random opaque refresh token
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken returns the hex SHA-256 a refresh token is stored as
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+var (
+	ErrUntrustedIssuer   = errors.New("token issuer is not trusted")
+	ErrInvalidAudience   = errors.New("token audience is not accepted")
+	ErrUnauthorizedParty = errors.New("token authorized party is not accepted")
+	ErrInvalidOIDCConfig = errors.New("OIDC validation requires at least one issuer and audience")
+)
+
+// OIDCConfig configures an OIDCValidator
+type OIDCConfig struct {
+	// Issuers are the trusted issuer URLs; their signing keys are discovered from
+	// {issuer}/.well-known/openid-configuration
+	Issuers []string
+	// Audiences are accepted values of the aud claim; a token must carry one of them
+	Audiences []string
+	// AuthorizedParties restricts the azp claim to these client IDs when set
+	AuthorizedParties []string
+	// ScopedIssuers are issuers whose user IDs are derived from the issuer and the user ID
+	// claim, so that equal subjects of different issuers are different users. The user IDs
+	// of the other issuers are derived from the claim alone, like those of RemoteKeyStore,
+	// and do not change when issuers are added.
+	ScopedIssuers []string
+}
+
+// OIDCValidator validates JWTs of one or more OpenID Connect issuers, with keys discovered
+// from the issuers' metadata. Tokens must be issued by a trusted issuer for an accepted
+// audience.
+type OIDCValidator struct {
+	keyStore          *jwk.AutoRefresh
+	jwksURIs          map[string]string
+	audiences         []string
+	authorizedParties []string
+	scopedIssuers     map[string]bool
+}
+
+// oidcMetadata is the part of the OpenID provider metadata the validator uses
+type oidcMetadata struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+var oidcHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// NewOIDCValidator discovers the signing keys of the configured issuers
+func NewOIDCValidator(ctx context.Context, cfg OIDCConfig) (*OIDCValidator, error) {
+	return newOIDCValidator(ctx, cfg, oidcHTTPClient)
+}
+
+func newOIDCValidator(ctx context.Context, cfg OIDCConfig, client *http.Client) (*OIDCValidator, error) {
+	if len(cfg.Issuers) == 0 || len(cfg.Audiences) == 0 {
+		return nil, ErrInvalidOIDCConfig
+	}
+
+	v := &OIDCValidator{
+		keyStore:          jwk.NewAutoRefresh(ctx),
+		jwksURIs:          make(map[string]string, len(cfg.Issuers)),
+		audiences:         cfg.Audiences,
+		authorizedParties: cfg.AuthorizedParties,
+		scopedIssuers:     make(map[string]bool, len(cfg.ScopedIssuers)),
+	}
+	for _, issuer := range cfg.ScopedIssuers {
+		if !slices.Contains(cfg.Issuers, issuer) {
+			return nil, fmt.Errorf("scoped OIDC issuer %s is not a trusted issuer", issuer)
+		}
+		v.scopedIssuers[issuer] = true
+	}
+	if unscoped := len(cfg.Issuers) - len(v.scopedIssuers); unscoped > 1 {
+		logging.LogWarningf(nil, "%d OIDC issuers share user IDs: equal subjects of these issuers are the same user", unscoped)
+	}
+	for _, issuer := range cfg.Issuers {
+		metadata, err := discoverOIDC(ctx, client, issuer)
+		if err != nil {
+			return nil, err
+		}
+		v.keyStore.Configure(metadata.JWKSURI, jwk.WithHTTPClient(client))
+		set, err := v.keyStore.Refresh(ctx, metadata.JWKSURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch keys of issuer %s: %w", issuer, err)
+		}
+		v.jwksURIs[issuer] = metadata.JWKSURI
+		logging.LogInfofCtx(ctx, "OIDC issuer %s initialized. # of retrieved keys: %d", issuer, set.Len())
+	}
+	return v, nil
+}
+
+// discoverOIDC reads the OpenID provider metadata of the issuer
+func discoverOIDC(ctx context.Context, client *http.Client, issuer string) (*oidcMetadata, error) {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("OIDC issuer %s must use HTTPS protocol", issuer)
+	}
+
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery of %s failed: %w", issuer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OIDC discovery of %s failed: status %d", issuer, resp.StatusCode)
+	}
+
+	var metadata oidcMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode OIDC metadata of %s: %w", issuer, err)
+	}
+	if metadata.Issuer != issuer {
+		return nil, fmt.Errorf("OIDC metadata of %s names issuer %q", issuer, metadata.Issuer)
+	}
+	if jwksURL, err := url.Parse(metadata.JWKSURI); err != nil || jwksURL.Scheme != "https" {
+		return nil, fmt.Errorf("OIDC metadata of %s has no HTTPS jwks_uri", issuer)
+	}
+	return &metadata, nil
+}
+
+// UserUUID implements UserIDMapper. The UUIDs of scoped issuers are derived from the issuer
+// and the user ID claim, those of other issuers are the UUIDs of UserUUID.
+func (v *OIDCValidator) UserUUID(claims *Claims) uuid.UUID {
+	if !v.scopedIssuers[claims.Issuer] {
+		return UserUUID(claims.UserID)
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(claims.Issuer+"|"+claims.UserID))
+}
+
+// ValidateJWT verifies the token with the keys of its issuer and checks the issuer,
+// audience and authorized party
+func (v *OIDCValidator) ValidateJWT(token string) (*jwt.Token, error) {
+	// The issuer selects the keys, so it is read before the signature is verified
+	unverified, err := jwt.Parse([]byte(token))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenValidation, err)
+	}
+	issuer := unverified.Issuer()
+	jwksURI, ok := v.jwksURIs[issuer]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUntrustedIssuer, issuer)
+	}
+
+	// Fetch will honor all HTTP cache headers that may be sent by the keys endpoint
+	set, err := v.keyStore.Fetch(context.Background(), jwksURI)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := jwt.Parse([]byte(token),
+		jwt.WithValidate(true),
+		jwt.InferAlgorithmFromKey(true),
+		jwt.WithKeySet(set),
+		jwt.WithIssuer(issuer),
+		jwt.WithValidator(jwt.ValidatorFunc(v.validateParties)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenValidation, err)
+	}
+	return &t, nil
+}
+
+// validateParties checks the aud and azp claims
+func (v *OIDCValidator) validateParties(_ context.Context, t jwt.Token) error {
+	if !containsAny(t.Audience(), v.audiences) {
+		return ErrInvalidAudience
+	}
+	if len(v.authorizedParties) == 0 {
+		return nil
+	}
+	azp, _ := t.Get("azp")
+	if party, ok := azp.(string); !ok || !containsAny(v.authorizedParties, []string{party}) {
+		return ErrUnauthorizedParty
+	}
+	return nil
+}
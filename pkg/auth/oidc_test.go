@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIssuer serves OIDC metadata and the keys of an issuer
+type testIssuer struct {
+	server *httptest.Server
+	key    jwk.Key
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := jwk.New(rsaKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, "test-key"))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.RS256))
+	public, err := key.PublicKey()
+	require.NoError(t, err)
+
+	issuer := &testIssuer{key: key}
+	mux := http.NewServeMux()
+	issuer.server = httptest.NewTLSServer(mux)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(oidcMetadata{Issuer: issuer.server.URL, JWKSURI: issuer.server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, _ *http.Request) {
+		set := jwk.NewSet()
+		set.Add(public)
+		_ = json.NewEncoder(w).Encode(set)
+	})
+	return issuer
+}
+
+func (i *testIssuer) sign(t *testing.T, claims map[string]interface{}) string {
+	token := jwt.New()
+	require.NoError(t, token.Set(jwt.IssuerKey, i.server.URL))
+	require.NoError(t, token.Set(jwt.ExpirationKey, time.Now().Add(time.Hour).Unix()))
+	for name, value := range claims {
+		require.NoError(t, token.Set(name, value))
+	}
+	signed, err := jwt.Sign(token, jwa.RS256, i.key)
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func TestOIDCValidator(t *testing.T) {
+	first := newTestIssuer(t)
+	defer first.server.Close()
+	second := newTestIssuer(t)
+	defer second.server.Close()
+	untrusted := newTestIssuer(t)
+	defer untrusted.server.Close()
+
+	// The test servers share the test CA, so one client trusts all of them
+	v, err := newOIDCValidator(context.Background(), OIDCConfig{
+		Issuers:           []string{first.server.URL, second.server.URL},
+		Audiences:         []string{"api://mcp-host"},
+		AuthorizedParties: []string{"web-client"},
+	}, first.server.Client())
+	require.NoError(t, err)
+
+	valid := map[string]interface{}{"sub": "user-1", "aud": "api://mcp-host", "azp": "web-client"}
+	token, err := v.ValidateJWT(first.sign(t, valid))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", (*token).Subject())
+
+	_, err = v.ValidateJWT(second.sign(t, valid))
+	assert.NoError(t, err)
+
+	_, err = v.ValidateJWT(untrusted.sign(t, valid))
+	assert.ErrorIs(t, err, ErrUntrustedIssuer)
+
+	_, err = v.ValidateJWT(first.sign(t, map[string]interface{}{"sub": "user-1", "aud": "other", "azp": "web-client"}))
+	assert.ErrorIs(t, err, ErrTokenValidation)
+
+	_, err = v.ValidateJWT(first.sign(t, map[string]interface{}{"sub": "user-1", "aud": "api://mcp-host", "azp": "cli"}))
+	assert.ErrorIs(t, err, ErrTokenValidation)
+
+	// A token of the second issuer signed with the first issuer's key
+	forged := jwt.New()
+	require.NoError(t, forged.Set(jwt.IssuerKey, second.server.URL))
+	require.NoError(t, forged.Set(jwt.AudienceKey, "api://mcp-host"))
+	require.NoError(t, forged.Set("azp", "web-client"))
+	signed, err := jwt.Sign(forged, jwa.RS256, first.key)
+	require.NoError(t, err)
+	_, err = v.ValidateJWT(string(signed))
+	assert.ErrorIs(t, err, ErrTokenValidation)
+
+	_, err = newOIDCValidator(context.Background(), OIDCConfig{Issuers: []string{first.server.URL}}, first.server.Client())
+	assert.ErrorIs(t, err, ErrInvalidOIDCConfig)
+}
+
+func TestOIDCValidator_UserUUID(t *testing.T) {
+	first := newTestIssuer(t)
+	defer first.server.Close()
+	second := newTestIssuer(t)
+	defer second.server.Close()
+
+	fromFirst := &Claims{Issuer: first.server.URL, UserID: "user-1"}
+	fromSecond := &Claims{Issuer: second.server.URL, UserID: "user-1"}
+
+	single, err := newOIDCValidator(context.Background(), OIDCConfig{
+		Issuers:   []string{first.server.URL},
+		Audiences: []string{"api://mcp-host"},
+	}, first.server.Client())
+	require.NoError(t, err)
+	assert.Equal(t, UserUUID("user-1"), single.UserUUID(fromFirst))
+
+	v, err := newOIDCValidator(context.Background(), OIDCConfig{
+		Issuers:       []string{first.server.URL, second.server.URL},
+		Audiences:     []string{"api://mcp-host"},
+		ScopedIssuers: []string{second.server.URL},
+	}, first.server.Client())
+	require.NoError(t, err)
+	mapped := WithClaimMapping(v, ClaimMapping{UserID: "sub"}).(UserIDMapper)
+
+	// Adding an issuer keeps the IDs of existing users
+	assert.Equal(t, single.UserUUID(fromFirst), v.UserUUID(fromFirst))
+	assert.Equal(t, v.UserUUID(fromFirst), mapped.UserUUID(fromFirst))
+
+	// The same subject at a scoped issuer is another user
+	assert.NotEqual(t, v.UserUUID(fromFirst), v.UserUUID(fromSecond))
+	assert.Equal(t, v.UserUUID(fromSecond), mapped.UserUUID(fromSecond))
+
+	_, err = newOIDCValidator(context.Background(), OIDCConfig{
+		Issuers:       []string{first.server.URL},
+		Audiences:     []string{"api://mcp-host"},
+		ScopedIssuers: []string{second.server.URL},
+	}, first.server.Client())
+	assert.ErrorContains(t, err, "not a trusted issuer")
+}
+
+func TestClaimMapping(t *testing.T) {
+	token := jwt.New()
+	require.NoError(t, token.Set("sub", "subject"))
+	require.NoError(t, token.Set("oid", "object-id"))
+	require.NoError(t, token.Set("email", "ada@example.com"))
+	require.NoError(t, token.Set("preferred_username", "ada"))
+	require.NoError(t, token.Set("name", "Ada Lovelace"))
+	require.NoError(t, token.Set("scp", "notes.read notes.write"))
+
+	claims := ClaimsFromToken(token)
+	assert.Equal(t, "object-id", claims.UserID)
+	assert.Equal(t, "Ada Lovelace", claims.Name)
+	assert.Equal(t, "ada@example.com", claims.Email)
+	assert.Equal(t, []string{"notes.read", "notes.write"}, claims.Scopes)
+
+	claims = ClaimMapping{UserID: "sub", Name: "preferred_username"}.Claims(token)
+	assert.Equal(t, "subject", claims.UserID)
+	assert.Equal(t, "ada", claims.Name)
+
+	validator := WithClaimMapping(&LocalJWTValidator{}, ClaimMapping{UserID: "email"})
+	extractor, ok := validator.(ClaimsExtractor)
+	require.True(t, ok)
+	assert.Equal(t, "ada@example.com", extractor.ExtractClaims(token).UserID)
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoSecretsKey     = errors.New("no secrets key configured")
+	ErrSecretDecryption = errors.New("secret decryption failed")
+)
+
+// SecretBox encrypts secrets stored in the database with AES-256-GCM. The key is derived
+// from a configured passphrase, so changing it makes stored secrets unreadable. Callers pass
+// additional data identifying the row a secret belongs to, so that a ciphertext copied into
+// another row does not decrypt.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a secret box keyed by the SHA-256 of secret
+func NewSecretBox(secret string) (*SecretBox, error) {
+	if secret == "" {
+		return nil, ErrNoSecretsKey
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts plaintext bound to the additional data; the random nonce is prepended to
+// the ciphertext
+func (b *SecretBox) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts a ciphertext produced by Seal with the same additional data
+func (b *SecretBox) Open(ciphertext, additionalData []byte) ([]byte, error) {
+	size := b.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, ErrSecretDecryption
+	}
+	plaintext, err := b.aead.Open(nil, ciphertext[:size], ciphertext[size:], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSecretDecryption, err)
+	}
+	return plaintext, nil
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/go-chi/cors"
 	"github.com/joho/godotenv"
@@ -57,8 +58,12 @@ const (
 	DefaultDBSSLMode  = "verify-full"
 
 	// ##### AUTHENTICATION VARIABLES
-	DefaultJWTSecret     = "" // Base64-encoded JWT secret
-	DefaultRemoteKeysURL = "" // Empty by default; set to use Azure AD or similar
+	DefaultJWTSecret       = "" // Base64-encoded JWT secret
+	DefaultAccessTokenTTL  = "15m"
+	DefaultRefreshTokenTTL = "720h"
+	DefaultSessionLifetime = "2160h"
+	DefaultRemoteKeysURL   = "" // Empty by default; set to use Azure AD or similar
+	DefaultOIDCIssuers     = "" // Space-separated trusted OIDC issuers; takes precedence over REMOTE_KEYS_URL
 )
 
 func bindEnvVariable(name string, fallback interface{}) {
@@ -148,8 +153,17 @@ func SetupEnv() {
 	// Authentication
 	bindEnvVariable("SERVICE_SECRET", "")
 	bindEnvVariable("JWT_SECRET", DefaultJWTSecret)
-	bindEnvVariable("JWT_EXPIRATION_HOURS", 24)
+	bindEnvVariable("AUTH_ACCESS_TOKEN_TTL", DefaultAccessTokenTTL)
+	bindEnvVariable("AUTH_REFRESH_TOKEN_TTL", DefaultRefreshTokenTTL)
+	bindEnvVariable("AUTH_SESSION_LIFETIME", DefaultSessionLifetime)
 	bindEnvVariable("REMOTE_KEYS_URL", DefaultRemoteKeysURL)
+	bindEnvVariable("OIDC_ISSUERS", DefaultOIDCIssuers)
+	bindEnvVariable("OIDC_AUDIENCES", "")
+	bindEnvVariable("OIDC_AUTHORIZED_PARTIES", "")
+	bindEnvVariable("OIDC_SCOPED_ISSUERS", "")
+	bindEnvVariable("AUTH_USER_ID_CLAIM", "")
+	bindEnvVariable("AUTH_NAME_CLAIM", "")
+	bindEnvVariable("AUTH_EMAIL_CLAIM", "")
 	// MCP and Agent configuration
 	SetupMCPEnv()
 }
@@ -179,3 +193,22 @@ func CorsConfig(corsHosts []string) cors.Options {
 		Debug:            viper.GetBool("DEBUG_CORS"),
 	}
 }
+
+// LocalAuthSettings configures the sessions of locally registered users
+type LocalAuthSettings struct {
+	// AccessTokenTTL is how long access tokens are valid
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is how long a session can be refreshed without use
+	RefreshTokenTTL time.Duration
+	// SessionLifetime is how long a session lasts at most, however often it is refreshed
+	SessionLifetime time.Duration
+}
+
+// GetLocalAuthSettings returns the session settings of local auth from viper
+func GetLocalAuthSettings() LocalAuthSettings {
+	return LocalAuthSettings{
+		AccessTokenTTL:  viper.GetDuration("AUTH_ACCESS_TOKEN_TTL"),
+		RefreshTokenTTL: viper.GetDuration("AUTH_REFRESH_TOKEN_TTL"),
+		SessionLifetime: viper.GetDuration("AUTH_SESSION_LIFETIME"),
+	}
+}
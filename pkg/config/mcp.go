@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -14,6 +15,11 @@ const (
 	HTTPServerModeStream = "stream"
 )
 
+const (
+	LLMProviderOpenAI    = "openai"
+	LLMProviderAnthropic = "anthropic"
+)
+
 // MCPServerConfig represents configuration for an MCP server connection
 type MCPServerConfig struct {
 	Name          string            `yaml:"name"                  json:"name"`
@@ -27,17 +33,153 @@ type MCPServerConfig struct {
 	ForwardBearer bool              `yaml:"forwardBearer"         json:"forwardBearer"` // When true, the current user's bearer token will be forwarded as Authorization header.
 	Enabled       bool              `yaml:"enabled"               json:"enabled"`
 	Description   string            `yaml:"description,omitempty" json:"description,omitempty"`
+	// MaxParallelToolCalls limits concurrent tool calls against this server within one LLM turn (0 = agent default).
+	MaxParallelToolCalls int `yaml:"maxParallelToolCalls,omitempty" json:"maxParallelToolCalls,omitempty"`
+	// RequireApproval pauses every tool call to this server until the user approves it.
+	RequireApproval bool `yaml:"requireApproval,omitempty" json:"requireApproval,omitempty"`
+	// ApprovalTools lists individual tools (MCP tool names) that require approval.
+	ApprovalTools []string `yaml:"approvalTools,omitempty" json:"approvalTools,omitempty"`
+	// Sampling lets the server request LLM completions through the host (opt-in).
+	Sampling *SamplingConfig `yaml:"sampling,omitempty" json:"sampling,omitempty"`
+	// Roots are advertised to the server via roots/list (see RootConfig for templating).
+	Roots []RootConfig `yaml:"roots,omitempty" json:"roots,omitempty"`
+	// OAuth overrides the client used for the MCP authorization flow of an HTTP server
+	// (optional; clients are registered dynamically by default).
+	OAuth *OAuthConfig `yaml:"oauth,omitempty" json:"oauth,omitempty"`
+	// TokenExchange swaps the user's bearer token for a token scoped to this server (RFC 8693)
+	// instead of forwarding it; it takes precedence over ForwardBearer.
+	TokenExchange *TokenExchangeConfig `yaml:"tokenExchange,omitempty" json:"tokenExchange,omitempty"`
+}
+
+// Token types of RFC 8693
+const (
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchangeConfig configures the RFC 8693 token exchange for one HTTP MCP server
+type TokenExchangeConfig struct {
+	// TokenURL is the token endpoint of the security token service
+	TokenURL string `yaml:"tokenUrl" json:"tokenUrl"`
+	// ClientID and ClientSecret authenticate the host at the token endpoint (optional)
+	ClientID     string `yaml:"clientId,omitempty"     json:"clientId,omitempty"`
+	ClientSecret string `yaml:"clientSecret,omitempty" json:"-"`
+	// Audience and Resource identify the server; Resource defaults to the server URL when
+	// neither is set
+	Audience string   `yaml:"audience,omitempty" json:"audience,omitempty"`
+	Resource string   `yaml:"resource,omitempty" json:"resource,omitempty"`
+	Scopes   []string `yaml:"scopes,omitempty"   json:"scopes,omitempty"`
+	// SubjectTokenType and RequestedTokenType default to TokenTypeAccessToken
+	SubjectTokenType   string `yaml:"subjectTokenType,omitempty"   json:"subjectTokenType,omitempty"`
+	RequestedTokenType string `yaml:"requestedTokenType,omitempty" json:"requestedTokenType,omitempty"`
+}
+
+// OAuthConfig configures the OAuth client for one HTTP MCP server
+type OAuthConfig struct {
+	// ClientID and ClientSecret identify a pre-registered client. Without ClientID, a
+	// client is registered dynamically when the authorization server supports it.
+	ClientID     string `yaml:"clientId,omitempty"     json:"clientId,omitempty"`
+	ClientSecret string `yaml:"clientSecret,omitempty" json:"-"`
+	// Scopes replace the scopes the server announces in its challenge or metadata.
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+}
+
+// Placeholders that can be used in RootConfig URIs and names
+const (
+	RootPlaceholderUserID         = "{userId}"
+	RootPlaceholderConversationID = "{conversationId}"
+)
+
+// RootConfig describes a directory or URI the server may operate on. URI and Name may
+// contain {userId} and {conversationId}, which are replaced per session.
+type RootConfig struct {
+	URI  string `yaml:"uri"            json:"uri"`
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+}
+
+// Expand replaces the placeholders of the root. It returns false when the root uses a
+// placeholder whose value is empty (e.g. {conversationId} outside of a conversation).
+func (r RootConfig) Expand(userID, conversationID string) (RootConfig, bool) {
+	template := r.URI + r.Name
+	if (userID == "" && strings.Contains(template, RootPlaceholderUserID)) ||
+		(conversationID == "" && strings.Contains(template, RootPlaceholderConversationID)) {
+		return RootConfig{}, false
+	}
+	replacer := strings.NewReplacer(RootPlaceholderUserID, userID, RootPlaceholderConversationID, conversationID)
+	return RootConfig{URI: replacer.Replace(r.URI), Name: replacer.Replace(r.Name)}, true
+}
+
+// SamplingConfig configures MCP sampling (sampling/createMessage) for one server
+type SamplingConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Models maps model hints sent by the server to host model names. A key matches
+	// when the hint contains it; hints are evaluated in the server's order.
+	Models map[string]string `yaml:"models,omitempty" json:"models,omitempty"`
+	// DefaultModel is used when no hint matches (empty = the LLM client's default model).
+	DefaultModel string `yaml:"defaultModel,omitempty" json:"defaultModel,omitempty"`
+	// MaxTokens caps the tokens a server may request per completion (0 = 1024).
+	MaxTokens int `yaml:"maxTokens,omitempty" json:"maxTokens,omitempty"`
+}
+
+// SamplingEnabled reports whether the server may request LLM completions
+func (c MCPServerConfig) SamplingEnabled() bool {
+	return c.Sampling != nil && c.Sampling.Enabled
+}
+
+// ToolRequiresApproval reports whether calls to the given tool must be approved by the user
+func (c MCPServerConfig) ToolRequiresApproval(toolName string) bool {
+	if c.RequireApproval {
+		return true
+	}
+	for _, name := range c.ApprovalTools {
+		if name == toolName {
+			return true
+		}
+	}
+	return false
 }
 
 // OpenAIConfig represents configuration for OpenAI models
 type OpenAIConfig struct {
-	APIKey         string  `yaml:"apiKey"         json:"apiKey"`
-	BaseURL        string  `yaml:"baseUrl"        json:"baseUrl"`
-	DefaultModel   string  `yaml:"defaultModel"   json:"defaultModel"`
-	Temperature    float64 `yaml:"temperature"    json:"temperature"`
-	MaxTokens      int     `yaml:"maxTokens"      json:"maxTokens"`
-	TopP           float64 `yaml:"topP"           json:"topP"`
-	RequestTimeout string  `yaml:"requestTimeout" json:"requestTimeout"`
+	APIKey         string `yaml:"apiKey"         json:"apiKey"`
+	BaseURL        string `yaml:"baseUrl"        json:"baseUrl"`
+	DefaultModel   string `yaml:"defaultModel"   json:"defaultModel"`
+	EmbeddingModel string `yaml:"embeddingModel" json:"embeddingModel"`
+	// EmbeddingDimensions is the vector length of EmbeddingModel (0 = known for the model)
+	EmbeddingDimensions int     `yaml:"embeddingDimensions" json:"embeddingDimensions"`
+	Temperature         float64 `yaml:"temperature"         json:"temperature"`
+	MaxTokens           int     `yaml:"maxTokens"           json:"maxTokens"`
+	TopP                float64 `yaml:"topP"                json:"topP"`
+	RequestTimeout      string  `yaml:"requestTimeout"      json:"requestTimeout"`
+}
+
+// embeddingDimensions are the vector lengths of common embedding models
+var embeddingDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+	"nomic-embed-text":       768,
+	"mxbai-embed-large":      1024,
+	"all-minilm":             384,
+}
+
+// EmbeddingDims returns the vector length of the embedding model, or 0 when it is neither
+// configured nor known
+func (c OpenAIConfig) EmbeddingDims() int {
+	if c.EmbeddingDimensions > 0 {
+		return c.EmbeddingDimensions
+	}
+	model, _, _ := strings.Cut(c.EmbeddingModel, ":")
+	return embeddingDimensions[model]
+}
+
+// AnthropicConfig represents configuration for Anthropic models (native Messages API)
+type AnthropicConfig struct {
+	APIKey         string `yaml:"apiKey"         json:"apiKey"`
+	BaseURL        string `yaml:"baseUrl"        json:"baseUrl"`
+	DefaultModel   string `yaml:"defaultModel"   json:"defaultModel"`
+	MaxTokens      int    `yaml:"maxTokens"      json:"maxTokens"`
+	RequestTimeout string `yaml:"requestTimeout" json:"requestTimeout"`
 }
 
 // MCPConfig represents configuration for MCP-related settings
@@ -53,7 +195,16 @@ type MCPConfig struct {
 type AgentConfig struct {
 	MaxIterations        int    `yaml:"maxIterations"        json:"maxIterations"`
 	MaxContextTokens     int    `yaml:"maxContextTokens"     json:"maxContextTokens"`
+	MaxContextResources  int    `yaml:"maxContextResources"  json:"maxContextResources"`
+	PruningStrategy      string `yaml:"pruningStrategy"      json:"pruningStrategy"`
+	MaxTools             int    `yaml:"maxTools"             json:"maxTools"`
+	ToolEmbeddings       bool   `yaml:"toolEmbeddings"       json:"toolEmbeddings"`
+	ResourceRetrieval    bool   `yaml:"resourceRetrieval"    json:"resourceRetrieval"`
+	ResourceIndexTTL     string `yaml:"resourceIndexTtl"     json:"resourceIndexTtl"`
 	ToolExecutionTimeout string `yaml:"toolExecutionTimeout" json:"toolExecutionTimeout"`
+	MaxParallelToolCalls int    `yaml:"maxParallelToolCalls" json:"maxParallelToolCalls"`
+	ElicitationTimeout   string `yaml:"elicitationTimeout"   json:"elicitationTimeout"`
+	ApprovalTimeout      string `yaml:"approvalTimeout"      json:"approvalTimeout"`
 	DefaultModel         string `yaml:"defaultModel"         json:"defaultModel"`
 }
 
@@ -93,47 +244,197 @@ func GetOpenAIConfig() OpenAIConfig {
 		baseURL = "https://api.openai.com/v1"
 	}
 	return OpenAIConfig{
-		APIKey:         viper.GetString("OPENAI_API_KEY"),
+		APIKey:              viper.GetString("OPENAI_API_KEY"),
+		BaseURL:             baseURL,
+		DefaultModel:        defaultModel,
+		EmbeddingModel:      viper.GetString("OPENAI_EMBEDDING_MODEL"),
+		EmbeddingDimensions: viper.GetInt("OPENAI_EMBEDDING_DIMENSIONS"),
+		Temperature:         viper.GetFloat64("OPENAI_TEMPERATURE"),
+		MaxTokens:           viper.GetInt("OPENAI_MAX_TOKENS"),
+		TopP:                viper.GetFloat64("OPENAI_TOP_P"),
+		RequestTimeout:      viper.GetString("OPENAI_REQUEST_TIMEOUT"),
+	}
+}
+
+// GetAnthropicConfig returns Anthropic configuration from viper
+func GetAnthropicConfig() AnthropicConfig {
+	defaultModel := viper.GetString("ANTHROPIC_DEFAULT_MODEL")
+	if defaultModel == "" {
+		defaultModel = "claude-sonnet-4-5"
+	}
+	baseURL := viper.GetString("ANTHROPIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com/v1"
+	}
+	return AnthropicConfig{
+		APIKey:         viper.GetString("ANTHROPIC_API_KEY"),
 		BaseURL:        baseURL,
 		DefaultModel:   defaultModel,
-		Temperature:    viper.GetFloat64("OPENAI_TEMPERATURE"),
-		MaxTokens:      viper.GetInt("OPENAI_MAX_TOKENS"),
-		TopP:           viper.GetFloat64("OPENAI_TOP_P"),
-		RequestTimeout: viper.GetString("OPENAI_REQUEST_TIMEOUT"),
+		MaxTokens:      viper.GetInt("ANTHROPIC_MAX_TOKENS"),
+		RequestTimeout: viper.GetString("ANTHROPIC_REQUEST_TIMEOUT"),
+	}
+}
+
+// GetLLMProvider returns the configured LLM provider ("openai" or "anthropic")
+func GetLLMProvider() string {
+	provider := strings.ToLower(strings.TrimSpace(viper.GetString("LLM_PROVIDER")))
+	if provider == "" {
+		return LLMProviderOpenAI
+	}
+	return provider
+}
+
+// GetDefaultModel returns the default model of the configured LLM provider
+func GetDefaultModel() string {
+	if GetLLMProvider() == LLMProviderAnthropic {
+		return GetAnthropicConfig().DefaultModel
+	}
+	return viper.GetString("OPENAI_DEFAULT_MODEL")
+}
+
+// GetSecretsKey returns the key that encrypts secrets of user-registered MCP servers
+// (empty = user servers are disabled)
+func GetSecretsKey() string {
+	return viper.GetString("MCP_SECRETS_KEY")
+}
+
+// GetUserServerAllowedNetworks returns the internal networks (CIDR notation) that
+// user-registered MCP servers may connect to despite the address guard
+func GetUserServerAllowedNetworks() []string {
+	return strings.Fields(viper.GetString("MCP_USER_SERVER_ALLOWED_NETWORKS"))
+}
+
+// OAuthSettings configures the OAuth flow for HTTP MCP servers
+type OAuthSettings struct {
+	// RedirectURL is the externally reachable URL of /api/v1/oauth/callback (empty = disabled)
+	RedirectURL string
+	// SuccessURL is where the browser is sent after a completed flow (optional)
+	SuccessURL string
+}
+
+// GetOAuthSettings returns the OAuth settings for HTTP MCP servers from viper
+func GetOAuthSettings() OAuthSettings {
+	return OAuthSettings{
+		RedirectURL: viper.GetString("MCP_OAUTH_REDIRECT_URL"),
+		SuccessURL:  viper.GetString("MCP_OAUTH_SUCCESS_URL"),
 	}
 }
 
+// Effects of tool policy rules
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
+
+// ToolPolicyConfig restricts the tools users may see and call by the claims of their token
+// (YAML key tool_policy)
+type ToolPolicyConfig struct {
+	// Default applies to tools no rule matches: "allow" (default) or "deny"
+	Default string `yaml:"default,omitempty" json:"default,omitempty"`
+	// Rules are evaluated together: a matching deny rule that applies to the user refuses
+	// the tool, and matching allow rules admit only the users they apply to
+	Rules []ToolPolicyRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// ToolPolicyRule matches tools of servers and applies to users with any of the listed
+// groups, roles or scopes (to everyone when none are listed)
+type ToolPolicyRule struct {
+	// Server is a server name or glob pattern ("" or "*" = all servers)
+	Server string `yaml:"server,omitempty" json:"server,omitempty"`
+	// Tools are MCP tool names or glob patterns (empty = all tools of the server)
+	Tools []string `yaml:"tools,omitempty" json:"tools,omitempty"`
+	// Effect is "allow" (default) or "deny"
+	Effect string   `yaml:"effect,omitempty" json:"effect,omitempty"`
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+	Roles  []string `yaml:"roles,omitempty"  json:"roles,omitempty"`
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+}
+
+// GetToolPolicy returns the tool policy from the config file (key tool_policy)
+func GetToolPolicy() ToolPolicyConfig {
+	var policy ToolPolicyConfig
+	if err := viper.UnmarshalKey("tool_policy", &policy); err != nil {
+		// Fail closed rather than dropping the restrictions
+		logging.LogErrorf(err, "Failed to unmarshal tool policy configuration, denying all tools")
+		return ToolPolicyConfig{Default: PolicyEffectDeny}
+	}
+	return policy
+}
+
 // GetAgentConfig returns agent configuration from viper
 func GetAgentConfig() AgentConfig {
 	return AgentConfig{
 		MaxIterations:        viper.GetInt("AGENT_MAX_ITERATIONS"),
 		MaxContextTokens:     viper.GetInt("AGENT_MAX_CONTEXT_TOKENS"),
+		MaxContextResources:  viper.GetInt("AGENT_MAX_CONTEXT_RESOURCES"),
+		PruningStrategy:      viper.GetString("AGENT_PRUNING_STRATEGY"),
+		MaxTools:             viper.GetInt("AGENT_MAX_TOOLS"),
+		ToolEmbeddings:       viper.GetBool("AGENT_TOOL_EMBEDDINGS"),
+		ResourceRetrieval:    viper.GetBool("AGENT_RESOURCE_RETRIEVAL"),
+		ResourceIndexTTL:     viper.GetString("AGENT_RESOURCE_INDEX_TTL"),
 		ToolExecutionTimeout: viper.GetString("AGENT_TOOL_EXECUTION_TIMEOUT"),
-		DefaultModel:         viper.GetString("OPENAI_DEFAULT_MODEL"),
+		MaxParallelToolCalls: viper.GetInt("AGENT_MAX_PARALLEL_TOOL_CALLS"),
+		ElicitationTimeout:   viper.GetString("AGENT_ELICITATION_TIMEOUT"),
+		ApprovalTimeout:      viper.GetString("AGENT_APPROVAL_TIMEOUT"),
+		DefaultModel:         GetDefaultModel(),
 	}
 }
 
 // SetupMCPEnv configures MCP-related environment variables
 func SetupMCPEnv() {
+	// LLM provider selection: "openai" (OpenAI-compatible, incl. Ollama) or "anthropic"
+	bindEnvVariable("LLM_PROVIDER", LLMProviderOpenAI)
+
 	// OpenAI configuration
 	bindEnvVariable("OPENAI_API_KEY", "")
 	bindEnvVariable("OPENAI_BASE_URL", "https://api.openai.com/v1")
 	bindEnvVariable("OPENAI_DEFAULT_MODEL", "gpt-4o-mini")
+	bindEnvVariable("OPENAI_EMBEDDING_MODEL", "text-embedding-3-small")
+	// Vector length of the embedding model, sizes the pgvector column (0 = known models only)
+	bindEnvVariable("OPENAI_EMBEDDING_DIMENSIONS", 0)
 	bindEnvVariable("OPENAI_TEMPERATURE", 0.7)
 	bindEnvVariable("OPENAI_MAX_TOKENS", 4096)
 	bindEnvVariable("OPENAI_TOP_P", 1.0)
 	bindEnvVariable("OPENAI_REQUEST_TIMEOUT", "120s")
 
+	// Anthropic configuration
+	bindEnvVariable("ANTHROPIC_API_KEY", "")
+	bindEnvVariable("ANTHROPIC_BASE_URL", "https://api.anthropic.com/v1")
+	bindEnvVariable("ANTHROPIC_DEFAULT_MODEL", "claude-sonnet-4-5")
+	bindEnvVariable("ANTHROPIC_MAX_TOKENS", 4096)
+	bindEnvVariable("ANTHROPIC_REQUEST_TIMEOUT", "120s")
+
 	// MCP configuration
 	bindEnvVariable("MCP_SESSION_TIMEOUT", "1h")
 	bindEnvVariable("MCP_MAX_SESSIONS_PER_USER", 10)
 	bindEnvVariable("MCP_RECONNECT_ATTEMPTS", 3)
 	bindEnvVariable("MCP_RECONNECT_DELAY", "5s")
+	// Enables MCP servers registered by users and encrypts their headers and tokens
+	bindEnvVariable("MCP_SECRETS_KEY", "")
+	// Space-separated CIDRs of internal networks user servers may reach (loopback, private
+	// and link-local addresses are denied otherwise)
+	bindEnvVariable("MCP_USER_SERVER_ALLOWED_NETWORKS", "")
+	// OAuth for HTTP MCP servers (requires MCP_SECRETS_KEY to store the tokens)
+	bindEnvVariable("MCP_OAUTH_REDIRECT_URL", "")
+	bindEnvVariable("MCP_OAUTH_SUCCESS_URL", "")
 
 	// Agent configuration
 	bindEnvVariable("AGENT_MAX_ITERATIONS", 10)
 	bindEnvVariable("AGENT_MAX_CONTEXT_TOKENS", 8192)
+	bindEnvVariable("AGENT_MAX_CONTEXT_RESOURCES", 0)
+	// How history over the context budget is pruned: "drop" or "summarize"
+	bindEnvVariable("AGENT_PRUNING_STRATEGY", "drop")
+	// Number of most relevant tools sent to the LLM per request (0 = all)
+	bindEnvVariable("AGENT_MAX_TOOLS", 0)
+	// Rank tools by embedding similarity (OPENAI_EMBEDDING_MODEL) instead of BM25
+	bindEnvVariable("AGENT_TOOL_EMBEDDINGS", false)
+	// Index resource chunks with embeddings and inject the most similar ones as context
+	bindEnvVariable("AGENT_RESOURCE_RETRIEVAL", false)
+	bindEnvVariable("AGENT_RESOURCE_INDEX_TTL", "15m")
 	bindEnvVariable("AGENT_TOOL_EXECUTION_TIMEOUT", "60s")
+	bindEnvVariable("AGENT_MAX_PARALLEL_TOOL_CALLS", 4)
+	bindEnvVariable("AGENT_ELICITATION_TIMEOUT", "2m")
+	bindEnvVariable("AGENT_APPROVAL_TIMEOUT", "5m")
 
 	// MCP Servers configuration (can be overridden via config file)
 	// Example servers are commented out by default
@@ -142,11 +443,17 @@ func SetupMCPEnv() {
 
 // FullMCPConfig combines all MCP-related configurations
 type FullMCPConfig struct {
-	Servers           []MCPServerConfig
-	OpenAI            OpenAIConfig
-	Agent             AgentConfig
-	ReconnectAttempts int
-	ReconnectDelay    time.Duration
+	Servers                   []MCPServerConfig
+	SecretsKey                string
+	UserServerAllowedNetworks []string
+	OAuth                     OAuthSettings
+	ToolPolicy                ToolPolicyConfig
+	LLMProvider               string
+	OpenAI                    OpenAIConfig
+	Anthropic                 AnthropicConfig
+	Agent                     AgentConfig
+	ReconnectAttempts         int
+	ReconnectDelay            time.Duration
 }
 
 // LoadMCPConfig loads all MCP-related configuration
@@ -168,11 +475,17 @@ func LoadMCPConfig() (*FullMCPConfig, error) {
 	}
 
 	cfg := &FullMCPConfig{
-		Servers:           GetMCPServers(),
-		OpenAI:            GetOpenAIConfig(),
-		Agent:             GetAgentConfig(),
-		ReconnectAttempts: viper.GetInt("MCP_RECONNECT_ATTEMPTS"),
-		ReconnectDelay:    reconnectDelay,
+		Servers:                   GetMCPServers(),
+		SecretsKey:                GetSecretsKey(),
+		UserServerAllowedNetworks: GetUserServerAllowedNetworks(),
+		OAuth:                     GetOAuthSettings(),
+		ToolPolicy:                GetToolPolicy(),
+		LLMProvider:               GetLLMProvider(),
+		OpenAI:                    GetOpenAIConfig(),
+		Anthropic:                 GetAnthropicConfig(),
+		Agent:                     GetAgentConfig(),
+		ReconnectAttempts:         viper.GetInt("MCP_RECONNECT_ATTEMPTS"),
+		ReconnectDelay:            reconnectDelay,
 	}
 
 	return cfg, nil
@@ -2,34 +2,33 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
-	"github.com/lestrrat-go/jwx/jwa"
-	"github.com/lestrrat-go/jwx/jwt"
-	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
 	"github.com/d4l-data4life/go-mcp-host/pkg/models"
 
 	"github.com/d4l-data4life/go-svc/pkg/logging"
 )
 
-// AuthHandler handles authentication endpoints
+// AuthHandler handles authentication endpoints of local users
 type AuthHandler struct {
 	db        *gorm.DB
-	jwtSecret []byte
+	localAuth *auth.LocalAuth
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(db *gorm.DB, jwtSecret []byte) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, localAuth *auth.LocalAuth) *AuthHandler {
 	return &AuthHandler{
 		db:        db,
-		jwtSecret: jwtSecret,
+		localAuth: localAuth,
 	}
 }
 
@@ -39,19 +38,13 @@ func (h *AuthHandler) Routes() chi.Router {
 
 	r.Post("/register", h.Register)
 	r.Post("/login", h.Login)
-	r.Get("/me", h.GetCurrentUser)
+	r.Post("/refresh", h.Refresh)
+	r.Post("/logout", h.Logout)
+	r.With(AuthMiddleware(h.db, h.localAuth)).Get("/me", h.GetCurrentUser)
 
 	return r
 }
 
-const (
-	// TokenExpirationDuration defines how long a JWT token is valid
-	TokenExpirationDuration = 24 * time.Hour
-	// TokenIssuer is the issuer claim for generated JWTs
-	// #nosec G101 -- This is not a credential, just an identifier
-	TokenIssuer = "go-mcp-host"
-)
-
 // RegisterRequest represents a registration request
 type RegisterRequest struct {
 	Username string `json:"username"`
@@ -65,10 +58,24 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// RefreshRequest represents a token refresh or logout request
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// TokenResponse carries the tokens of a session
+type TokenResponse struct {
+	// Token is the access token, sent as Authorization: Bearer
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
 // AuthResponse represents an authentication response
 type AuthResponse struct {
-	User  models.PublicUser `json:"user"`
-	Token string            `json:"token"`
+	User models.PublicUser `json:"user"`
+	TokenResponse
 }
 
 // Register handles user registration
@@ -135,10 +142,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateJWT(user.ID, h.jwtSecret)
+	// Start a session
+	tokens, err := h.localAuth.Issue(r.Context(), user.ID)
 	if err != nil {
-		logging.LogErrorf(err, "Failed to generate JWT")
+		logging.LogErrorf(err, "Failed to issue tokens")
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, map[string]string{"error": "Failed to generate token"})
 		return
@@ -152,8 +159,8 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	render.Status(r, http.StatusCreated)
 	render.JSON(w, r, AuthResponse{
-		User:  user.ToPublic(),
-		Token: token,
+		User:          user.ToPublic(),
+		TokenResponse: newTokenResponse(tokens),
 	})
 }
 
@@ -186,10 +193,10 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateJWT(user.ID, h.jwtSecret)
+	// Start a session
+	tokens, err := h.localAuth.Issue(r.Context(), user.ID)
 	if err != nil {
-		logging.LogErrorf(err, "Failed to generate JWT")
+		logging.LogErrorf(err, "Failed to issue tokens")
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, map[string]string{"error": "Failed to generate token"})
 		return
@@ -202,8 +209,8 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	logging.LogDebugf("User logged in: %s", username)
 
 	render.JSON(w, r, AuthResponse{
-		User:  user.ToPublic(),
-		Token: token,
+		User:          user.ToPublic(),
+		TokenResponse: newTokenResponse(tokens),
 	})
 }
 
@@ -226,40 +233,73 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, user.ToPublic())
 }
 
-// generateJWT generates a JWT token for a user with proper claims and signing
-func generateJWT(userID uuid.UUID, jwtSecret []byte) (string, error) {
-	// Validate that jwtSecret is not empty
-	if len(jwtSecret) == 0 {
-		return "", errors.New("JWT secret is empty")
+// Refresh exchanges a refresh token for new tokens. The refresh token is rotated, so the
+// one sent is no longer valid afterwards.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
 	}
 
-	now := time.Now()
+	tokens, err := h.localAuth.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, map[string]string{"error": "Invalid or expired refresh token"})
+			return
+		}
+		logging.LogErrorf(err, "Failed to refresh tokens")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to refresh token"})
+		return
+	}
 
-	// Create a new JWT token with standard claims
-	token := jwt.New()
+	render.JSON(w, r, newTokenResponse(tokens))
+}
 
-	// Set standard claims
-	if err := token.Set(jwt.SubjectKey, userID.String()); err != nil {
-		return "", errors.Wrap(err, "failed to set subject claim")
-	}
-	if err := token.Set(jwt.IssuedAtKey, now.Unix()); err != nil {
-		return "", errors.Wrap(err, "failed to set issued at claim")
-	}
-	if err := token.Set(jwt.ExpirationKey, now.Add(TokenExpirationDuration).Unix()); err != nil {
-		return "", errors.Wrap(err, "failed to set expiration claim")
-	}
-	if err := token.Set(jwt.IssuerKey, TokenIssuer); err != nil {
-		return "", errors.Wrap(err, "failed to set issuer claim")
-	}
-	if err := token.Set(jwt.JwtIDKey, uuid.New().String()); err != nil {
-		return "", errors.Wrap(err, "failed to set jwt id claim")
+// Logout revokes the session of the refresh token in the body or, without one, of the
+// access token. Its access tokens are rejected from then on.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+			return
+		}
 	}
 
-	// Sign the token with HS256 algorithm
-	signed, err := jwt.Sign(token, jwa.HS256, jwtSecret)
+	var err error
+	if req.RefreshToken != "" {
+		err = h.localAuth.RevokeRefreshToken(r.Context(), req.RefreshToken)
+	} else {
+		token, validationErr := h.localAuth.ValidateJWT(bearerTokenFromRequest(r))
+		if validationErr != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, map[string]string{"error": "Invalid or expired token"})
+			return
+		}
+		sessionID, _ := auth.SessionID(*token)
+		err = h.localAuth.RevokeSession(r.Context(), sessionID)
+	}
 	if err != nil {
-		return "", errors.Wrap(err, "failed to sign JWT token")
+		logging.LogErrorf(err, "Failed to revoke session")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to log out"})
+		return
 	}
 
-	return string(signed), nil
+	render.Status(r, http.StatusNoContent)
+	_, _ = w.Write([]byte{})
+}
+
+func newTokenResponse(tokens *auth.TokenPair) TokenResponse {
+	return TokenResponse{
+		Token:            tokens.AccessToken,
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
 }
@@ -0,0 +1,110 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/handlers"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+	"github.com/d4l-data4life/go-svc/pkg/db"
+)
+
+func TestAuthHandler_Sessions(t *testing.T) {
+	models.InitializeTestDB(t)
+	defer db.Close()
+	localAuth, err := auth.NewLocalAuth(db.Get(), []byte("test-secret-key-for-testing-only-32bytes!!"), config.LocalAuthSettings{})
+	require.NoError(t, err)
+	router := handlers.NewAuthHandler(db.Get(), localAuth).Routes()
+
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/register", `{"username": "ada", "email": "ada@example.com", "password": "correct-horse"}`, "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var registered handlers.AuthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registered))
+	assert.NotEmpty(t, registered.Token)
+	assert.NotEmpty(t, registered.RefreshToken)
+
+	w = do("GET", "/me", "", registered.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Refresh tokens are rotated
+	w = do("POST", "/refresh", `{"refreshToken": "`+registered.RefreshToken+`"}`, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var refreshed handlers.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+	assert.NotEqual(t, registered.RefreshToken, refreshed.RefreshToken)
+
+	// Reusing a rotated refresh token revokes the session
+	w = do("POST", "/refresh", `{"refreshToken": "`+registered.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = do("GET", "/me", "", refreshed.Token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = do("POST", "/refresh", `{"refreshToken": "`+refreshed.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	login := func() handlers.AuthResponse {
+		w := do("POST", "/login", `{"username": "ada", "password": "correct-horse"}`, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var loggedIn handlers.AuthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &loggedIn))
+		return loggedIn
+	}
+
+	// Logging out revokes the session's access tokens
+	first := login()
+	w = do("POST", "/logout", "", first.Token)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = do("GET", "/me", "", first.Token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "SESSION_REVOKED")
+
+	w = do("POST", "/refresh", `{"refreshToken": "`+first.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Other sessions are not affected
+	second := login()
+	w = do("GET", "/me", "", second.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLocalAuth_SessionLifetime(t *testing.T) {
+	models.InitializeTestDB(t)
+	defer db.Close()
+	localAuth, err := auth.NewLocalAuth(db.Get(), []byte("test-secret-key-for-testing-only-32bytes!!"), config.LocalAuthSettings{
+		RefreshTokenTTL: time.Hour,
+		SessionLifetime: time.Minute,
+	})
+	require.NoError(t, err)
+
+	user := models.User{ID: uuid.New()}
+	require.NoError(t, db.Get().Create(&user).Error)
+	issued, err := localAuth.Issue(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), issued.RefreshExpiresAt, 5*time.Second)
+
+	// Refreshing does not extend the session beyond its lifetime
+	refreshed, err := localAuth.Refresh(context.Background(), issued.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, issued.RefreshExpiresAt.Unix(), refreshed.RefreshExpiresAt.Unix())
+}
@@ -2,14 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
-	"github.com/spf13/viper"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
+	"github.com/d4l-data4life/go-mcp-host/pkg/agent"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
 	"github.com/d4l-data4life/go-mcp-host/pkg/models"
 
 	"github.com/d4l-data4life/go-svc/pkg/logging"
@@ -45,12 +48,29 @@ type CreateConversationRequest struct {
 	Title        string `json:"title"`
 	Model        string `json:"model"`
 	SystemPrompt string `json:"systemPrompt"`
+	// PinnedTools are always offered to the LLM: qualified tool names (<server>__<tool>)
+	// or server names
+	PinnedTools []string `json:"pinnedTools,omitempty"`
+	// Servers restricts the conversation to these MCP servers (empty = all enabled servers)
+	Servers []string `json:"servers,omitempty"`
+	// AllowedTools restricts the conversation to these tools: qualified tool names or glob
+	// patterns such as "files__read*" (empty = all tools)
+	AllowedTools []string `json:"allowedTools,omitempty"`
+	// BlockedTools are never available in the conversation, in the same format
+	BlockedTools []string `json:"blockedTools,omitempty"`
 }
 
 // UpdateConversationRequest represents a request to update a conversation
 type UpdateConversationRequest struct {
 	Title        string `json:"title"`
 	SystemPrompt string `json:"systemPrompt"`
+	// PinnedTools replaces the pinned tools when set (an empty list unpins all)
+	PinnedTools *[]string `json:"pinnedTools,omitempty"`
+	// Servers, AllowedTools and BlockedTools replace the conversation's server and tool
+	// selection when set (an empty list removes the restriction)
+	Servers      *[]string `json:"servers,omitempty"`
+	AllowedTools *[]string `json:"allowedTools,omitempty"`
+	BlockedTools *[]string `json:"blockedTools,omitempty"`
 }
 
 // ListConversations returns all conversations for the current user
@@ -114,7 +134,7 @@ func (h *ConversationsHandler) CreateConversation(w http.ResponseWriter, r *http
 		req.Title = "New Conversation"
 	}
 	if req.Model == "" {
-		req.Model = viper.GetString("OPENAI_DEFAULT_MODEL")
+		req.Model = config.GetDefaultModel()
 	}
 
 	// Create conversation
@@ -124,6 +144,17 @@ func (h *ConversationsHandler) CreateConversation(w http.ResponseWriter, r *http
 		Title:        req.Title,
 		Model:        req.Model,
 		SystemPrompt: req.SystemPrompt,
+		Servers:      req.Servers,
+		AllowedTools: req.AllowedTools,
+		BlockedTools: req.BlockedTools,
+	}
+	if len(req.PinnedTools) > 0 {
+		if err := setConversationPinnedTools(&conversation, req.PinnedTools); err != nil {
+			logging.LogErrorf(err, "Failed to set pinned tools for user: %s", userID)
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]string{"error": "Failed to create conversation"})
+			return
+		}
 	}
 
 	if err := h.db.Create(&conversation).Error; err != nil {
@@ -206,19 +237,50 @@ func (h *ConversationsHandler) UpdateConversation(w http.ResponseWriter, r *http
 		return
 	}
 
-	// Update fields
+	// Only the changed columns are written, so that concurrent changes of other columns
+	// and metadata keys (e.g. the history summary) are kept
+	updates := make(map[string]interface{})
 	if req.Title != "" {
-		conversation.Title = req.Title
+		updates["title"] = req.Title
 	}
 	if req.SystemPrompt != "" {
-		conversation.SystemPrompt = req.SystemPrompt
+		updates["system_prompt"] = req.SystemPrompt
+	}
+	if req.PinnedTools != nil {
+		if len(*req.PinnedTools) > 0 {
+			pinned, err := json.Marshal(*req.PinnedTools)
+			if err != nil {
+				logging.LogErrorf(err, "Failed to encode pinned tools of conversation %s", convID)
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, map[string]string{"error": "Failed to update conversation"})
+				return
+			}
+			updates["metadata"] = models.SetConversationMetadataKey(pinnedToolsMetadataKey, pinned)
+		} else {
+			updates["metadata"] = models.DeleteConversationMetadataKey(pinnedToolsMetadataKey)
+		}
+	}
+	if req.Servers != nil {
+		updates["servers"] = datatypes.JSONSlice[string](*req.Servers)
+	}
+	if req.AllowedTools != nil {
+		updates["allowed_tools"] = datatypes.JSONSlice[string](*req.AllowedTools)
+	}
+	if req.BlockedTools != nil {
+		updates["blocked_tools"] = datatypes.JSONSlice[string](*req.BlockedTools)
 	}
 
-	if err := h.db.Save(&conversation).Error; err != nil {
-		logging.LogErrorf(err, "Failed to update conversation")
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to update conversation"})
-		return
+	if len(updates) > 0 {
+		err := h.db.Model(&conversation).Updates(updates).Error
+		if err == nil {
+			err = h.db.Where("id = ?", convID).First(&conversation).Error
+		}
+		if err != nil {
+			logging.LogErrorf(err, "Failed to update conversation")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]string{"error": "Failed to update conversation"})
+			return
+		}
 	}
 
 	logging.LogDebugf("Updated conversation: %s", convID)
@@ -271,3 +333,55 @@ func (h *ConversationsHandler) DeleteConversation(w http.ResponseWriter, r *http
 	render.Status(r, http.StatusNoContent)
 	_, _ = w.Write([]byte{})
 }
+
+// conversationToolFilter returns the conversation's server and tool selection, or nil when
+// the conversation is not restricted
+func conversationToolFilter(conversation *models.Conversation) *agent.ToolFilter {
+	if len(conversation.Servers) == 0 && len(conversation.AllowedTools) == 0 && len(conversation.BlockedTools) == 0 {
+		return nil
+	}
+	return &agent.ToolFilter{
+		Servers:      conversation.Servers,
+		AllowedTools: conversation.AllowedTools,
+		BlockedTools: conversation.BlockedTools,
+	}
+}
+
+// pinnedToolsMetadataKey is the key of the pinned tools in Conversation.Metadata
+const pinnedToolsMetadataKey = "pinnedTools"
+
+// conversationPinnedTools returns the tools pinned in the conversation metadata
+func conversationPinnedTools(conversation *models.Conversation) []string {
+	var meta struct {
+		PinnedTools []string `json:"pinnedTools"`
+	}
+	if len(conversation.Metadata) > 0 {
+		_ = json.Unmarshal(conversation.Metadata, &meta)
+	}
+	return meta.PinnedTools
+}
+
+// setConversationPinnedTools stores the pinned tools in the conversation metadata, keeping
+// its other keys
+func setConversationPinnedTools(conversation *models.Conversation, pinnedTools []string) error {
+	var meta map[string]interface{}
+	if len(conversation.Metadata) > 0 {
+		if err := json.Unmarshal(conversation.Metadata, &meta); err != nil {
+			return fmt.Errorf("invalid conversation metadata: %w", err)
+		}
+	}
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	if len(pinnedTools) > 0 {
+		meta[pinnedToolsMetadataKey] = pinnedTools
+	} else {
+		delete(meta, pinnedToolsMetadataKey)
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation metadata: %w", err)
+	}
+	conversation.Metadata = datatypes.JSON(b)
+	return nil
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/oauth"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// oauthNonceMaxAge is how long the nonce cookie of a started authorization lives
+const oauthNonceMaxAge = 10 * time.Minute
+
+// AuthorizeServer starts the OAuth flow of the current user for an HTTP MCP server and
+// returns the URL the user must open to authorize the host. The flow is bound to the
+// calling browser with a nonce cookie that the callback must present.
+func (h *MCPServersHandler) AuthorizeServer(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAuthorizer(w, r) {
+		return
+	}
+	userID := GetUserIDFromContext(r.Context())
+	serverName := chi.URLParam(r, "server")
+
+	server, ok := h.mcpManager.GetServerConfigForUser(r.Context(), userID, serverName)
+	if !ok {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "MCP server not found"})
+		return
+	}
+	if server.ForwardBearer || server.TokenExchange != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "MCP server is authorized with the user's bearer token"})
+		return
+	}
+
+	challenge, _ := h.mcpManager.AuthorizationChallenge(userID, serverName)
+	authorization, err := h.authorizer.Start(r.Context(), userID, server, challenge)
+	if err != nil {
+		if errors.Is(err, oauth.ErrNotHTTPServer) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": err.Error()})
+			return
+		}
+		logging.LogErrorf(err, "Failed to start OAuth authorization for MCP server %s", serverName)
+		render.Status(r, http.StatusBadGateway)
+		render.JSON(w, r, map[string]string{"error": "Failed to start authorization with the MCP server"})
+		return
+	}
+
+	http.SetCookie(w, nonceCookie(h.authorizer.RedirectURL(), authorization.State, authorization.Nonce, int(oauthNonceMaxAge.Seconds())))
+	render.JSON(w, r, map[string]string{"authorizationUrl": authorization.URL})
+}
+
+// DisconnectServer deletes the OAuth token of the current user for an MCP server
+func (h *MCPServersHandler) DisconnectServer(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAuthorizer(w, r) {
+		return
+	}
+	userID := GetUserIDFromContext(r.Context())
+	serverName := chi.URLParam(r, "server")
+
+	if err := h.authorizer.Disconnect(r.Context(), userID, serverName); err != nil {
+		logging.LogErrorf(err, "Failed to disconnect MCP server %s", serverName)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to disconnect MCP server"})
+		return
+	}
+	server, ok := h.mcpManager.GetServerConfigForUser(r.Context(), userID, serverName)
+	if !ok {
+		server = config.MCPServerConfig{Name: serverName}
+	}
+	h.mcpManager.AuthorizationChanged(userID, server)
+
+	logging.LogDebugf("Disconnected MCP server %s of user %s", serverName, userID)
+
+	render.Status(r, http.StatusNoContent)
+	_, _ = w.Write([]byte{})
+}
+
+// requireAuthorizer responds with 501 when OAuth for MCP servers is disabled
+func (h *MCPServersHandler) requireAuthorizer(w http.ResponseWriter, r *http.Request) bool {
+	if h.authorizer != nil {
+		return true
+	}
+	render.Status(r, http.StatusNotImplemented)
+	render.JSON(w, r, map[string]string{"error": "OAuth for MCP servers is not enabled"})
+	return false
+}
+
+// nonceCookie returns the cookie binding an authorization to the browser. It is only sent
+// to the callback, and SameSite=Lax still sends it on the top-level redirect from the
+// authorization server. A negative maxAge deletes it.
+func nonceCookie(redirectURL, state, nonce string, maxAge int) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     oauth.NonceCookieName(state),
+		Value:    nonce,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if u, err := url.Parse(redirectURL); err == nil {
+		if u.Path != "" {
+			cookie.Path = u.Path
+		}
+		cookie.Secure = strings.EqualFold(u.Scheme, "https")
+	}
+	return cookie
+}
+
+// OAuthCallbackHandler completes OAuth flows started with MCPServersHandler.AuthorizeServer.
+// The callback is public: the browser arrives from the authorization server without the
+// host's credentials, and the state identifies the user.
+type OAuthCallbackHandler struct {
+	mcpManager *manager.Manager
+	authorizer *oauth.Authorizer
+}
+
+// NewOAuthCallbackHandler creates the callback handler, or returns nil when the manager
+// was created without an oauth.Authorizer
+func NewOAuthCallbackHandler(mcpManager *manager.Manager) *OAuthCallbackHandler {
+	if mcpManager == nil {
+		return nil
+	}
+	authorizer, ok := mcpManager.OAuth().(*oauth.Authorizer)
+	if !ok {
+		return nil
+	}
+	return &OAuthCallbackHandler{mcpManager: mcpManager, authorizer: authorizer}
+}
+
+// Routes returns OAuth callback routes
+func (h *OAuthCallbackHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/callback", h.Callback)
+
+	return r
+}
+
+// Callback exchanges the authorization code for tokens when the browser presents the nonce
+// cookie of the flow. The browser is redirected to the configured success URL with the
+// server name, or receives a JSON status.
+func (h *OAuthCallbackHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		logging.LogWarningf(nil, "OAuth authorization failed: %s: %s", errCode, query.Get("error_description"))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Authorization failed: " + errCode})
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oauth.NonceCookieName(state))
+	if err != nil || state == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Authorization was not started in this browser"})
+		return
+	}
+
+	userID, serverName, err := h.authorizer.Complete(r.Context(), state, query.Get("code"), cookie.Value)
+	if err != nil {
+		if errors.Is(err, oauth.ErrUnknownState) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Unknown or expired authorization"})
+			return
+		}
+		if errors.Is(err, oauth.ErrStateMismatch) {
+			logging.LogWarningf(nil, "OAuth callback with the nonce of another authorization")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Authorization was not started in this browser"})
+			return
+		}
+		logging.LogErrorf(err, "Failed to complete OAuth authorization")
+		render.Status(r, http.StatusBadGateway)
+		render.JSON(w, r, map[string]string{"error": "Failed to complete authorization"})
+		return
+	}
+	http.SetCookie(w, nonceCookie(h.authorizer.RedirectURL(), state, "", -1))
+	server, ok := h.mcpManager.GetServerConfigForUser(r.Context(), userID, serverName)
+	if !ok {
+		server = config.MCPServerConfig{Name: serverName}
+	}
+	h.mcpManager.AuthorizationChanged(userID, server)
+
+	if successURL := h.authorizer.SuccessURL(); successURL != "" {
+		if u, err := url.Parse(successURL); err == nil {
+			q := u.Query()
+			q.Set("server", server.Name)
+			u.RawQuery = q.Encode()
+			http.Redirect(w, r, u.String(), http.StatusFound)
+			return
+		}
+		logging.LogWarningf(nil, "Invalid MCP_OAUTH_SUCCESS_URL %q", successURL)
+	}
+	render.JSON(w, r, map[string]string{"server": server.Name, "status": "authorized"})
+}
@@ -6,9 +6,13 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
 	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/oauth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/registry"
 	schemautil "github.com/d4l-data4life/go-mcp-host/pkg/mcp/schemautil"
 
 	"github.com/d4l-data4life/go-svc/pkg/logging"
@@ -18,14 +22,23 @@ import (
 type MCPServersHandler struct {
 	db         *gorm.DB
 	mcpManager *manager.Manager
+	registry   *registry.Registry // nil when user servers are disabled
+	authorizer *oauth.Authorizer  // nil when OAuth for MCP servers is disabled
 }
 
-// NewMCPServersHandler creates a new MCP servers handler
+// NewMCPServersHandler creates a new MCP servers handler. Users can register their own
+// servers when the manager was created with a registry.Registry as user server store, and
+// authorize servers with OAuth when it was created with an oauth.Authorizer.
 func NewMCPServersHandler(db *gorm.DB, mcpManager *manager.Manager) *MCPServersHandler {
-	return &MCPServersHandler{
+	h := &MCPServersHandler{
 		db:         db,
 		mcpManager: mcpManager,
 	}
+	if mcpManager != nil {
+		h.registry, _ = mcpManager.UserServers().(*registry.Registry)
+		h.authorizer, _ = mcpManager.OAuth().(*oauth.Authorizer)
+	}
+	return h
 }
 
 // Routes returns MCP server routes
@@ -33,20 +46,39 @@ func (h *MCPServersHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
 	r.Get("/servers", h.ListServers)
+	r.Post("/servers", h.CreateUserServer)
+	r.Get("/servers/{id}", h.GetUserServer)
+	r.Put("/servers/{id}", h.UpdateUserServer)
+	r.Delete("/servers/{id}", h.DeleteUserServer)
+	r.Post("/oauth/{server}/authorize", h.AuthorizeServer)
+	r.Delete("/oauth/{server}", h.DisconnectServer)
 	r.Get("/tools", h.ListTools)
 	r.Get("/resources", h.ListResources)
+	r.Get("/prompts", h.ListPrompts)
 
 	return r
 }
 
+// Scopes of the servers returned by ListServers
+const (
+	ServerScopeGlobal = "global"
+	ServerScopeUser   = "user"
+)
+
 // ServerInfo represents information about an MCP server
 type ServerInfo struct {
-	Name         string   `json:"name"`
-	Description  string   `json:"description"`
-	Type         string   `json:"type"`
-	Enabled      bool     `json:"enabled"`
-	Capabilities []string `json:"capabilities"`
-	Connected    bool     `json:"connected"`
+	// ID is set for servers registered by the user
+	ID           *uuid.UUID `json:"id,omitempty"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Type         string     `json:"type"`
+	Scope        string     `json:"scope"`
+	Enabled      bool       `json:"enabled"`
+	Capabilities []string   `json:"capabilities"`
+	Connected    bool       `json:"connected"`
+	// AuthorizationRequired is set when the server rejected the user and can be authorized
+	// with POST /mcp/oauth/{server}/authorize
+	AuthorizationRequired bool `json:"authorizationRequired,omitempty"`
 }
 
 // ToolInfo represents information about an MCP tool
@@ -66,9 +98,25 @@ type ResourceInfo struct {
 	Server      string `json:"server"`
 }
 
-// ListServers returns all configured MCP servers and their status
+// PromptInfo represents information about an MCP prompt
+type PromptInfo struct {
+	Name        string               `json:"name"`
+	Title       string               `json:"title,omitempty"`
+	Description string               `json:"description"`
+	Server      string               `json:"server"`
+	Arguments   []PromptArgumentInfo `json:"arguments"`
+}
+
+// PromptArgumentInfo describes an argument of an MCP prompt
+type PromptArgumentInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// ListServers returns the configured MCP servers and the servers of the user with their status
 func (h *MCPServersHandler) ListServers(w http.ResponseWriter, r *http.Request) {
-	_ = GetUserIDFromContext(r.Context())
+	userID := GetUserIDFromContext(r.Context())
 	bearer := GetBearerTokenFromContext(r.Context())
 
 	// Get configured servers
@@ -80,33 +128,75 @@ func (h *MCPServersHandler) ListServers(w http.ResponseWriter, r *http.Request)
 			Name:         cfg.Name,
 			Description:  cfg.Description,
 			Type:         cfg.Type,
+			Scope:        ServerScopeGlobal,
 			Enabled:      cfg.Enabled,
 			Capabilities: []string{},
-			Connected:    false,
 		}
-
 		if cfg.Enabled {
-			// Short-lived probe (no long-lived sessions)
-			if caps, err := h.mcpManager.ProbeServer(r.Context(), cfg, bearer); err == nil {
-				info.Connected = true
-				if caps.Tools != nil {
-					info.Capabilities = append(info.Capabilities, "tools")
-				}
-				if caps.Resources != nil {
-					info.Capabilities = append(info.Capabilities, "resources")
-				}
-				if caps.Prompts != nil {
-					info.Capabilities = append(info.Capabilities, "prompts")
+			h.probeServer(r, userID, cfg, bearer, &info)
+		}
+		serverInfos = append(serverInfos, info)
+	}
+
+	if h.registry != nil {
+		userServers, err := h.registry.List(r.Context(), userID)
+		if err != nil {
+			logging.LogErrorf(err, "Failed to list user MCP servers")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]string{"error": "Failed to list MCP servers"})
+			return
+		}
+		for i := range userServers {
+			server := &userServers[i]
+			info := ServerInfo{
+				ID:           &server.ID,
+				Name:         server.Name,
+				Description:  server.Description,
+				Type:         "http",
+				Scope:        ServerScopeUser,
+				Enabled:      server.Enabled,
+				Capabilities: []string{},
+			}
+			if server.Enabled {
+				if cfg, err := h.registry.ServerConfig(server); err == nil {
+					h.probeServer(r, userID, cfg, bearer, &info)
 				}
 			}
+			serverInfos = append(serverInfos, info)
 		}
-
-		serverInfos = append(serverInfos, info)
 	}
 
 	render.JSON(w, r, serverInfos)
 }
 
+// probeServer marks the server as connected and lists its capabilities when it responds
+// to a short-lived probe (no long-lived sessions)
+func (h *MCPServersHandler) probeServer(
+	r *http.Request,
+	userID uuid.UUID,
+	cfg config.MCPServerConfig,
+	bearer string,
+	info *ServerInfo,
+) {
+	caps, err := h.mcpManager.ProbeServerForUser(r.Context(), userID, cfg, bearer)
+	if err != nil {
+		if h.authorizer != nil {
+			_, info.AuthorizationRequired = h.mcpManager.AuthorizationChallenge(userID, cfg.Name)
+		}
+		return
+	}
+	info.Connected = true
+	if caps.Tools != nil {
+		info.Capabilities = append(info.Capabilities, "tools")
+	}
+	if caps.Resources != nil {
+		info.Capabilities = append(info.Capabilities, "resources")
+	}
+	if caps.Prompts != nil {
+		info.Capabilities = append(info.Capabilities, "prompts")
+	}
+}
+
 // ListTools returns all available tools from MCP servers
 func (h *MCPServersHandler) ListTools(w http.ResponseWriter, r *http.Request) {
 	userID := GetUserIDFromContext(r.Context())
@@ -161,3 +251,41 @@ func (h *MCPServersHandler) ListResources(w http.ResponseWriter, r *http.Request
 
 	render.JSON(w, r, resourceInfos)
 }
+
+// ListPrompts returns all available prompts from MCP servers
+func (h *MCPServersHandler) ListPrompts(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	bearer := GetBearerTokenFromContext(r.Context())
+
+	prompts, err := h.mcpManager.ListAllPromptsForUser(r.Context(), userID, bearer)
+	if err != nil {
+		logging.LogErrorf(err, "Failed to list prompts")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to list prompts"})
+		return
+	}
+
+	var promptInfos []PromptInfo
+	for _, prompt := range prompts {
+		arguments := make([]PromptArgumentInfo, 0, len(prompt.Prompt.Arguments))
+		for _, arg := range prompt.Prompt.Arguments {
+			if arg == nil {
+				continue
+			}
+			arguments = append(arguments, PromptArgumentInfo{
+				Name:        arg.Name,
+				Description: arg.Description,
+				Required:    arg.Required,
+			})
+		}
+		promptInfos = append(promptInfos, PromptInfo{
+			Name:        prompt.Prompt.Name,
+			Title:       prompt.Prompt.Title,
+			Description: prompt.Prompt.Description,
+			Server:      prompt.ServerName,
+			Arguments:   arguments,
+		})
+	}
+
+	render.JSON(w, r, promptInfos)
+}
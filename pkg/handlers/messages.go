@@ -5,16 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/patrickmn/go-cache"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
 	"github.com/d4l-data4life/go-mcp-host/pkg/agent"
 	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
 	"github.com/d4l-data4life/go-mcp-host/pkg/models"
 
 	"github.com/d4l-data4life/go-svc/pkg/logging"
@@ -22,9 +26,10 @@ import (
 
 // MessagesHandler handles message endpoints
 type MessagesHandler struct {
-	db       *gorm.DB
-	agent    *agent.Agent
-	upgrader websocket.Upgrader
+	db         *gorm.DB
+	agent      *agent.Agent
+	upgrader   websocket.Upgrader
+	sseStreams *cache.Cache
 }
 
 // NewMessagesHandler creates a new messages handler
@@ -37,6 +42,7 @@ func NewMessagesHandler(db *gorm.DB, agent *agent.Agent) *MessagesHandler {
 				return true // Allow all origins in development
 			},
 		},
+		sseStreams: cache.New(sseRetention, time.Minute),
 	}
 }
 
@@ -47,14 +53,24 @@ func (h *MessagesHandler) Routes() chi.Router {
 	r.Get("/", h.ListMessages)
 	r.Post("/", h.SendMessage)
 	r.Get("/stream", h.StreamMessages)
+	r.Post("/sse", h.StreamMessagesSSE)
+	r.Post("/elicitations/{elicitationId}", h.RespondToElicitation)
 
 	return r
 }
 
 // SendMessageRequest represents a request to send a message
 type SendMessageRequest struct {
-	Content   string     `json:"content"`
-	MessageID *uuid.UUID `json:"messageId,omitempty"` // If present, edit/retry existing message
+	Content   string           `json:"content"`
+	MessageID *uuid.UUID       `json:"messageId,omitempty"` // If present, edit/retry existing message
+	Prompt    *PromptReference `json:"prompt,omitempty"`    // If present, send an MCP prompt instead of Content
+}
+
+// PromptReference selects an MCP server prompt that is sent as if the user had typed it
+type PromptReference struct {
+	Server    string            `json:"server"`
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
 }
 
 // SendMessageResponse represents the response to sending a message
@@ -141,12 +157,21 @@ func (h *MessagesHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Content == "" {
+	if req.Content == "" && req.Prompt == nil {
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, map[string]string{"error": "Message content is required"})
 		return
 	}
 
+	if req.Prompt != nil {
+		if err := h.applyPrompt(r.Context(), convID, userID, &req); err != nil {
+			logging.LogErrorf(err, "Failed to render prompt")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Failed to get prompt"})
+			return
+		}
+	}
+
 	// Save user message
 	userMessage := models.Message{
 		ID:             uuid.New(),
@@ -162,9 +187,9 @@ func (h *MessagesHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get message history
+	// Get message history up to (but not including) the current user message
 	var messages []models.Message
-	h.db.Where("conversation_id = ?", convID).
+	h.db.Where("conversation_id = ? AND created_at < ?", convID, userMessage.CreatedAt).
 		Order("created_at ASC").
 		Find(&messages)
 
@@ -179,6 +204,9 @@ func (h *MessagesHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		UserMessage:    req.Content,
 		Messages:       agentMessages,
 		Model:          conversation.Model,
+		PinnedTools:    conversationPinnedTools(&conversation),
+		ToolFilter:     conversationToolFilter(&conversation),
+		Claims:         GetClaimsFromContext(r.Context()),
 	})
 
 	if err != nil {
@@ -188,22 +216,11 @@ func (h *MessagesHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Save assistant message
-	toolCallsJSON, _ := json.Marshal(response.Message.ToolCalls)
+	// Save the tool transcript and the assistant message
 	// Attach tool execution metadata for frontend display
 	toolExecs := make([]map[string]interface{}, 0, len(response.ToolsUsed))
 	for _, te := range response.ToolsUsed {
-		entry := map[string]interface{}{
-			"serverName": te.ServerName,
-			"toolName":   te.ToolName,
-			"arguments":  te.Arguments,
-			"result":     te.Result,
-			"durationMs": te.Duration.Milliseconds(),
-		}
-		if te.Error != nil {
-			entry["error"] = te.Error.Error()
-		}
-		toolExecs = append(toolExecs, entry)
+		toolExecs = append(toolExecs, toolExecutionMetadata(te))
 	}
 	metaJSON, _ := json.Marshal(map[string]interface{}{
 		"toolExecutions": toolExecs,
@@ -213,11 +230,10 @@ func (h *MessagesHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		ConversationID: convID,
 		Role:           models.MessageRoleAssistant,
 		Content:        response.Message.Content,
-		ToolCalls:      datatypes.JSON(toolCallsJSON),
 		Metadata:       datatypes.JSON(metaJSON),
 	}
 
-	if err := h.db.Create(&assistantMessage).Error; err != nil {
+	if err := h.saveAgentTurn(convID, response.Messages, &assistantMessage); err != nil {
 		logging.LogErrorf(err, "Failed to save assistant message")
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, map[string]string{"error": "Failed to save response"})
@@ -225,28 +241,7 @@ func (h *MessagesHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Auto-generate conversation title if this is the first message and title is still default
-	if conversation.Title == "New Chat" || conversation.Title == "New Conversation" {
-		// Count total messages (should be 2: user + assistant)
-		var messageCount int64
-		h.db.Model(&models.Message{}).Where("conversation_id = ?", convID).Count(&messageCount)
-
-		if messageCount == 2 {
-			// Generate title based on user's first message
-			go func() {
-				title := h.agent.GenerateChatTitle(context.Background(), req.Content)
-				if title != "" {
-					// Update conversation title
-					if err := h.db.Model(&models.Conversation{}).
-						Where("id = ?", convID).
-						Update("title", title).Error; err != nil {
-						logging.LogErrorf(err, "Failed to update conversation title")
-					} else {
-						logging.LogDebugf("Auto-generated title for conversation %s: %s", convID, title)
-					}
-				}
-			}()
-		}
-	}
+	h.maybeGenerateTitle(convID, &conversation, req.Content)
 
 	logging.LogDebugf("Message processed: conversation=%s iterations=%d tools=%d",
 		convID, response.Iterations, len(response.ToolsUsed))
@@ -287,30 +282,224 @@ func (h *MessagesHandler) StreamMessages(w http.ResponseWriter, r *http.Request)
 
 	logging.LogDebugf("WebSocket connection established: conversation=%s user=%s", convID, userID)
 
+	session := newStreamSession(conn, h.handleWebSocketReadError)
+	defer session.cancel()
+	session.respondToElicitation = func(id string, response agent.ElicitationResponse) error {
+		return h.agent.RespondToElicitation(convID, id, response)
+	}
+	defer h.agent.WatchResourceUpdates(convID, forwardResourceUpdates(session.conn))()
+
 	// Handle WebSocket messages
 	for {
 		// Read message from client
-		var req SendMessageRequest
-		err := conn.ReadJSON(&req)
-		if err != nil {
-			h.handleWebSocketReadError(err)
+		req, ok := session.next()
+		if !ok {
 			break
 		}
 
 		// Validate input
-		if !h.validateStreamRequest(conn, &req) {
+		if !h.validateStreamRequest(session.conn, &req) {
 			continue
 		}
 
 		// Process user message (edit/retry or new)
-		userMessage, currentContent, ok := h.processUserMessage(conn, convID, &req)
+		userMessage, currentContent, ok := h.processUserMessage(r.Context(), session.conn, convID, userID, &req)
 		if !ok {
 			continue
 		}
 
 		// Build and send agent response
-		h.streamAgentResponse(r.Context(), conn, convID, userID, &conversation, userMessage, currentContent, &req)
+		h.streamAgentResponse(r.Context(), session, convID, userID, &conversation, userMessage, currentContent, &req)
+	}
+}
+
+// Client frame types on the message stream WebSocket. Frames without a type are message requests.
+const (
+	// streamFrameToolApproval approves or denies a paused tool call
+	streamFrameToolApproval = "tool_approval"
+	// streamFrameElicitationResponse answers an elicitation_request event
+	streamFrameElicitationResponse = "elicitation_response"
+)
+
+// streamClientFrame is a frame sent by the client over the message stream WebSocket
+type streamClientFrame struct {
+	SendMessageRequest
+	Type       string `json:"type,omitempty"`
+	ToolCallID string `json:"toolCallId,omitempty"`
+	Approved   bool   `json:"approved,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+
+	ElicitationID string                     `json:"elicitationId,omitempty"`
+	Response      *agent.ElicitationResponse `json:"response,omitempty"`
+}
+
+// streamWriter delivers stream events to the client (a WebSocket or an SSE stream)
+type streamWriter interface {
+	WriteJSON(v interface{}) error
+}
+
+// lockedStreamWriter serializes writes, as a WebSocket connection supports only one
+// concurrent writer and resource updates are written from outside the request loop
+type lockedStreamWriter struct {
+	mu sync.Mutex
+	w  streamWriter
+}
+
+func (l *lockedStreamWriter) WriteJSON(v interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.WriteJSON(v)
+}
+
+// forwardResourceUpdates writes a resource_updated event for every update of a resource the
+// conversation is subscribed to
+func forwardResourceUpdates(conn streamWriter) func(manager.ResourceUpdate) {
+	return func(update manager.ResourceUpdate) {
+		if err := conn.WriteJSON(map[string]interface{}{
+			"type":   "resource_updated",
+			"server": update.ServerName,
+			"uri":    update.URI,
+		}); err != nil {
+			logging.LogDebugf("Failed to forward resource update for %s: %v", update.URI, err)
+		}
+	}
+}
+
+// streamSession reads client frames in the background so that approval decisions can
+// arrive while a response is streaming. Message requests received meanwhile are queued.
+// The session context is cancelled once the client goes away.
+type streamSession struct {
+	conn      streamWriter
+	frames    chan streamClientFrame
+	approvals *agent.ToolApprovals
+	queued    []SendMessageRequest
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	respondToElicitation func(id string, response agent.ElicitationResponse) error
+}
+
+func newStreamSession(conn *websocket.Conn, onReadError func(error)) *streamSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &streamSession{
+		conn:      &lockedStreamWriter{w: conn},
+		frames:    make(chan streamClientFrame),
+		approvals: agent.NewToolApprovals(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	go func() {
+		defer close(s.frames)
+		defer cancel()
+		for {
+			var frame streamClientFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				onReadError(err)
+				return
+			}
+			select {
+			case s.frames <- frame:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return s
+}
+
+// newWriteOnlyStreamSession creates a session for transports without a client-to-server
+// channel. It never receives frames, so tool calls that require approval are denied.
+func newWriteOnlyStreamSession(w streamWriter) *streamSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &streamSession{
+		conn:   w,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// next returns the next message request, resolving approval frames received in between.
+// It returns false once the connection is closed.
+func (s *streamSession) next() (SendMessageRequest, bool) {
+	for {
+		if len(s.queued) > 0 {
+			req := s.queued[0]
+			s.queued = s.queued[1:]
+			return req, true
+		}
+		frame, ok := <-s.frames
+		if !ok {
+			return SendMessageRequest{}, false
+		}
+		s.handle(frame)
+	}
+}
+
+// handle resolves approval and elicitation frames and queues message requests
+func (s *streamSession) handle(frame streamClientFrame) {
+	switch frame.Type {
+	case streamFrameToolApproval:
+		decision := agent.ToolApprovalDecision{Approved: frame.Approved, Reason: frame.Reason}
+		if err := s.approvals.Resolve(frame.ToolCallID, decision); err != nil {
+			logging.LogWarningf(err, "Ignoring tool approval for call %s", frame.ToolCallID)
+		}
+	case streamFrameElicitationResponse:
+		if frame.Response == nil || s.respondToElicitation == nil {
+			return
+		}
+		if err := s.respondToElicitation(frame.ElicitationID, *frame.Response); err != nil {
+			logging.LogWarningf(err, "Ignoring elicitation response %s", frame.ElicitationID)
+		}
+	default:
+		s.queued = append(s.queued, frame.SendMessageRequest)
+	}
+}
+
+// RespondToElicitation answers an elicitation_request event received over a stream without
+// a client-to-server channel (SSE). The body is an agent.ElicitationResponse.
+func (h *MessagesHandler) RespondToElicitation(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	conversationID := chi.URLParam(r, "id")
+	elicitationID := chi.URLParam(r, "elicitationId")
+
+	convID, err := uuid.Parse(conversationID)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid conversation ID"})
+		return
+	}
+
+	// Verify conversation belongs to user
+	var conversation models.Conversation
+	if err := h.db.Where("id = ? AND user_id = ?", convID, userID).First(&conversation).Error; err != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "Conversation not found"})
+		return
+	}
+
+	var response agent.ElicitationResponse
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
 	}
+
+	if err := h.agent.RespondToElicitation(convID, elicitationID, response); err != nil {
+		switch {
+		case errors.Is(err, agent.ErrInvalidElicitationAction):
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Action must be accept, decline or cancel"})
+		default:
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, map[string]string{"error": "Elicitation not found or expired"})
+		}
+		return
+	}
+
+	render.Status(r, http.StatusNoContent)
+	_, _ = w.Write([]byte{})
 }
 
 // handleWebSocketReadError logs WebSocket read errors
@@ -323,8 +512,8 @@ func (h *MessagesHandler) handleWebSocketReadError(err error) {
 }
 
 // validateStreamRequest validates the stream request and sends error if invalid
-func (h *MessagesHandler) validateStreamRequest(conn *websocket.Conn, req *SendMessageRequest) bool {
-	if req.Content == "" && req.MessageID == nil {
+func (h *MessagesHandler) validateStreamRequest(conn streamWriter, req *SendMessageRequest) bool {
+	if req.Content == "" && req.MessageID == nil && req.Prompt == nil {
 		if err := conn.WriteJSON(map[string]interface{}{"type": "error", "error": "Message content is required"}); err != nil {
 			logging.LogErrorf(err, "Failed to write error to WebSocket")
 		}
@@ -336,19 +525,81 @@ func (h *MessagesHandler) validateStreamRequest(conn *websocket.Conn, req *SendM
 
 // processUserMessage handles user message creation or editing
 func (h *MessagesHandler) processUserMessage(
-	conn *websocket.Conn,
-	convID uuid.UUID,
+	ctx context.Context,
+	conn streamWriter,
+	convID, userID uuid.UUID,
 	req *SendMessageRequest,
 ) (models.Message, string, bool) {
 	if req.MessageID != nil {
 		return h.handleEditOrRetryMessage(conn, convID, req)
 	}
+	if req.Prompt != nil {
+		if err := h.applyPrompt(ctx, convID, userID, req); err != nil {
+			logging.LogErrorf(err, "Failed to render prompt")
+			_ = conn.WriteJSON(map[string]interface{}{"type": "error", "error": "Failed to get prompt"})
+			_ = conn.WriteJSON(map[string]interface{}{"type": "done", "error": "Failed to get prompt"})
+			return models.Message{}, "", false
+		}
+	}
 	return h.handleNewMessage(conn, convID, req)
 }
 
+// applyPrompt renders req.Prompt. The prompt's messages before its last user message are
+// stored in the conversation and the last user message becomes req.Content.
+func (h *MessagesHandler) applyPrompt(ctx context.Context, convID, userID uuid.UUID, req *SendMessageRequest) error {
+	messages, err := h.agent.RenderPrompt(
+		ctx,
+		userID,
+		GetBearerTokenFromContext(ctx),
+		req.Prompt.Server,
+		req.Prompt.Name,
+		req.Prompt.Arguments,
+	)
+	if err != nil {
+		return err
+	}
+
+	last := -1
+	for i, msg := range messages {
+		if msg.Role == llm.RoleUser {
+			last = i
+		}
+	}
+	if last < 0 {
+		return errors.New("prompt contains no user message")
+	}
+	if trailing := len(messages) - last - 1; trailing > 0 {
+		logging.LogDebugf("Ignoring %d messages after the last user message of prompt %s", trailing, req.Prompt.Name)
+	}
+
+	createdAt := time.Now()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for _, msg := range messages[:last] {
+			record := models.Message{
+				ID:             uuid.New(),
+				ConversationID: convID,
+				Role:           models.MessageRole(msg.Role),
+				Content:        msg.Content,
+				CreatedAt:      createdAt,
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			createdAt = createdAt.Add(time.Microsecond)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	req.Content = messages[last].Content
+	return nil
+}
+
 // handleEditOrRetryMessage handles editing or retrying an existing message
 func (h *MessagesHandler) handleEditOrRetryMessage(
-	conn *websocket.Conn,
+	conn streamWriter,
 	convID uuid.UUID,
 	req *SendMessageRequest,
 ) (models.Message, string, bool) {
@@ -393,7 +644,7 @@ func (h *MessagesHandler) handleEditOrRetryMessage(
 }
 
 // handleNewMessage creates and sends a new user message
-func (h *MessagesHandler) handleNewMessage(conn *websocket.Conn, convID uuid.UUID, req *SendMessageRequest) (models.Message, string, bool) {
+func (h *MessagesHandler) handleNewMessage(conn streamWriter, convID uuid.UUID, req *SendMessageRequest) (models.Message, string, bool) {
 	userMessage := models.Message{
 		ID:             uuid.New(),
 		ConversationID: convID,
@@ -417,13 +668,15 @@ func (h *MessagesHandler) handleNewMessage(conn *websocket.Conn, convID uuid.UUI
 // streamAgentResponse streams the agent's response through WebSocket
 func (h *MessagesHandler) streamAgentResponse(
 	ctx context.Context,
-	conn *websocket.Conn,
+	session *streamSession,
 	convID, userID uuid.UUID,
 	conversation *models.Conversation,
 	userMessage models.Message,
 	currentContent string,
 	req *SendMessageRequest,
 ) {
+	conn := session.conn
+
 	// Build message history up to (but not including) the current user message
 	var messages []models.Message
 	h.db.Where("conversation_id = ? AND created_at < ?", convID, userMessage.CreatedAt).
@@ -434,13 +687,17 @@ func (h *MessagesHandler) streamAgentResponse(
 	agentMessages := h.convertToAgentMessages(messages)
 
 	// Stream agent response
-	streamChan, err := h.agent.ChatStream(context.Background(), agent.ChatRequest{
+	streamChan, err := h.agent.ChatStream(session.ctx, agent.ChatRequest{
 		ConversationID: convID,
 		UserID:         userID,
 		BearerToken:    GetBearerTokenFromContext(ctx),
 		UserMessage:    currentContent,
 		Messages:       agentMessages,
 		Model:          conversation.Model,
+		PinnedTools:    conversationPinnedTools(conversation),
+		ToolFilter:     conversationToolFilter(conversation),
+		Claims:         GetClaimsFromContext(ctx),
+		Approvals:      session.approvals,
 	})
 
 	if err != nil {
@@ -448,11 +705,11 @@ func (h *MessagesHandler) streamAgentResponse(
 		return
 	}
 
-	h.processStreamEvents(conn, convID, conversation, userMessage, req, streamChan)
+	h.processStreamEvents(session, convID, conversation, userMessage, req, streamChan)
 }
 
 // handleStreamError handles errors when starting the stream
-func (h *MessagesHandler) handleStreamError(conn *websocket.Conn, userMessage *models.Message, err error) {
+func (h *MessagesHandler) handleStreamError(conn streamWriter, userMessage *models.Message, err error) {
 	short := shortenUserError(err)
 	persistMessageError(h.db, userMessage, short)
 	if writeErr := conn.WriteJSON(map[string]interface{}{
@@ -467,22 +724,43 @@ func (h *MessagesHandler) handleStreamError(conn *websocket.Conn, userMessage *m
 	})
 }
 
-// processStreamEvents processes events from the agent stream
+// processStreamEvents processes events from the agent stream. Client frames arriving
+// meanwhile are handed to the session so that paused tool calls can be approved.
 func (h *MessagesHandler) processStreamEvents(
-	conn *websocket.Conn,
+	session *streamSession,
 	convID uuid.UUID,
 	conversation *models.Conversation,
 	userMessage models.Message,
 	req *SendMessageRequest,
 	streamChan <-chan agent.StreamEvent,
 ) {
-	var fullContent string
+	conn := session.conn
+	frames := session.frames
+	// Content of the current LLM iteration; earlier iterations are part of the transcript
+	var iterationContent string
 	var streamedToolExecs []map[string]interface{}
 
-	for event := range streamChan {
+	for {
+		var event agent.StreamEvent
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				// Client is gone; the session context stops the agent
+				frames = nil
+			} else {
+				session.handle(frame)
+			}
+			continue
+		case e, ok := <-streamChan:
+			if !ok {
+				return
+			}
+			event = e
+		}
+
 		switch event.Type {
 		case agent.StreamEventTypeContent:
-			fullContent += event.Content
+			iterationContent += event.Content
 			if err := conn.WriteJSON(map[string]interface{}{
 				"type":    "content",
 				"content": event.Content,
@@ -490,7 +768,16 @@ func (h *MessagesHandler) processStreamEvents(
 				logging.LogErrorf(err, "Failed to send content stream")
 				return
 			}
+		case agent.StreamEventTypeToolCallDelta:
+			if err := conn.WriteJSON(map[string]interface{}{
+				"type":     "tool_call_delta",
+				"toolCall": event.ToolCall,
+			}); err != nil {
+				logging.LogErrorf(err, "Failed to send tool call delta event")
+				return
+			}
 		case agent.StreamEventTypeToolStart:
+			iterationContent = ""
 			if err := conn.WriteJSON(map[string]interface{}{
 				"type": "tool_start",
 				"tool": event.Tool,
@@ -498,35 +785,41 @@ func (h *MessagesHandler) processStreamEvents(
 				logging.LogErrorf(err, "Failed to send tool start event")
 				return
 			}
+		case agent.StreamEventTypeToolApprovalRequired:
+			if err := conn.WriteJSON(map[string]interface{}{
+				"type": "tool_approval_required",
+				"tool": event.Tool,
+			}); err != nil {
+				logging.LogErrorf(err, "Failed to send tool approval request")
+				return
+			}
+		case agent.StreamEventTypeElicitation:
+			if err := conn.WriteJSON(map[string]interface{}{
+				"type":        "elicitation_request",
+				"elicitation": event.Elicitation,
+			}); err != nil {
+				logging.LogErrorf(err, "Failed to send elicitation request")
+				return
+			}
 		case agent.StreamEventTypeToolComplete:
 			streamedToolExecs = h.handleToolComplete(conn, event, streamedToolExecs)
 		case agent.StreamEventTypeDone:
-			h.handleStreamDone(conn, convID, conversation, fullContent, streamedToolExecs, req)
+			h.handleStreamDone(conn, convID, conversation, finalContent(event.Messages, iterationContent), event.Messages, streamedToolExecs, req)
 		case agent.StreamEventTypeError:
-			h.handleStreamEventError(conn, &userMessage, event.Error)
+			h.handleStreamEventError(conn, convID, &userMessage, event)
 		}
 	}
 }
 
 // handleToolComplete handles tool completion events
 func (h *MessagesHandler) handleToolComplete(
-	conn *websocket.Conn,
+	conn streamWriter,
 	event agent.StreamEvent,
 	streamedToolExecs []map[string]interface{},
 ) []map[string]interface{} {
 	// Collect tool execution for metadata and forward to client
 	if event.Tool != nil {
-		entry := map[string]interface{}{
-			"serverName": event.Tool.ServerName,
-			"toolName":   event.Tool.ToolName,
-			"arguments":  event.Tool.Arguments,
-			"result":     event.Tool.Result,
-			"durationMs": event.Tool.Duration.Milliseconds(),
-		}
-		if event.Tool.Error != nil {
-			entry["error"] = event.Tool.Error.Error()
-		}
-		streamedToolExecs = append(streamedToolExecs, entry)
+		streamedToolExecs = append(streamedToolExecs, toolExecutionMetadata(*event.Tool))
 	}
 	if err := conn.WriteJSON(map[string]interface{}{
 		"type": "tool_complete",
@@ -537,17 +830,27 @@ func (h *MessagesHandler) handleToolComplete(
 	return streamedToolExecs
 }
 
+// finalContent returns the answer of the last LLM iteration: the final message of the turn's
+// transcript, or the content streamed since the last tool calls
+func finalContent(turn []llm.Message, streamed string) string {
+	if n := len(turn); n > 0 && turn[n-1].Role == llm.RoleAssistant && len(turn[n-1].ToolCalls) == 0 {
+		return turn[n-1].Content
+	}
+	return streamed
+}
+
 // handleStreamDone handles the stream completion event
 func (h *MessagesHandler) handleStreamDone(
-	conn *websocket.Conn,
+	conn streamWriter,
 	convID uuid.UUID,
 	conversation *models.Conversation,
-	fullContent string,
+	content string,
+	turn []llm.Message,
 	streamedToolExecs []map[string]interface{},
 	req *SendMessageRequest,
 ) {
 	// Save assistant message
-	logging.LogDebugf("Saving assistant message: content=%s", fullContent)
+	logging.LogDebugf("Saving assistant message: content=%s", content)
 	metaJSON, _ := json.Marshal(map[string]interface{}{
 		"toolExecutions": streamedToolExecs,
 	})
@@ -555,10 +858,12 @@ func (h *MessagesHandler) handleStreamDone(
 		ID:             uuid.New(),
 		ConversationID: convID,
 		Role:           models.MessageRoleAssistant,
-		Content:        fullContent,
+		Content:        content,
 		Metadata:       datatypes.JSON(metaJSON),
 	}
-	h.db.Create(&assistantMessage)
+	if err := h.saveAgentTurn(convID, turn, &assistantMessage); err != nil {
+		logging.LogErrorf(err, "Failed to save assistant message")
+	}
 
 	// Auto-generate conversation title if this is the first message
 	h.maybeGenerateTitle(convID, conversation, req.Content)
@@ -571,9 +876,15 @@ func (h *MessagesHandler) handleStreamDone(
 	}
 }
 
-// handleStreamEventError handles error events from the stream
-func (h *MessagesHandler) handleStreamEventError(conn *websocket.Conn, userMessage *models.Message, err error) {
-	short := shortenUserError(err)
+// handleStreamEventError handles error events from the stream. Tool calls and results the
+// turn completed before the error are kept, so that the next turn can build on them.
+func (h *MessagesHandler) handleStreamEventError(conn streamWriter, convID uuid.UUID, userMessage *models.Message, event agent.StreamEvent) {
+	if len(event.Messages) > 0 {
+		if err := h.saveAgentTurn(convID, event.Messages, nil); err != nil {
+			logging.LogErrorf(err, "Failed to save partial agent turn")
+		}
+	}
+	short := shortenUserError(event.Error)
 	persistMessageError(h.db, userMessage, short)
 	if writeErr := conn.WriteJSON(map[string]interface{}{
 		"type":  "error",
@@ -590,10 +901,13 @@ func (h *MessagesHandler) handleStreamEventError(conn *websocket.Conn, userMessa
 // maybeGenerateTitle auto-generates a conversation title if needed
 func (h *MessagesHandler) maybeGenerateTitle(convID uuid.UUID, conversation *models.Conversation, content string) {
 	if conversation.Title == "New Chat" || conversation.Title == "New Conversation" {
-		var messageCount int64
-		h.db.Model(&models.Message{}).Where("conversation_id = ?", convID).Count(&messageCount)
+		// Only after the first exchange; tool transcripts add further messages, so count user turns
+		var userMessageCount int64
+		h.db.Model(&models.Message{}).
+			Where("conversation_id = ? AND role = ?", convID, models.MessageRoleUser).
+			Count(&userMessageCount)
 
-		if messageCount == 2 {
+		if userMessageCount == 1 {
 			go func() {
 				title := h.agent.GenerateChatTitle(context.Background(), content)
 				if title != "" {
@@ -610,11 +924,57 @@ func (h *MessagesHandler) maybeGenerateTitle(convID uuid.UUID, conversation *mod
 	}
 }
 
+// saveAgentTurn stores the assistant tool-call messages and tool results of one agent turn
+// in order, followed by the final assistant message, so that later turns can replay the
+// tool context. turn is the agent's transcript; its trailing final answer is replaced by
+// final. Without final (a failed turn) only the transcript is stored.
+func (h *MessagesHandler) saveAgentTurn(convID uuid.UUID, turn []llm.Message, final *models.Message) error {
+	intermediate := turn
+	if n := len(turn); n > 0 && turn[n-1].Role == llm.RoleAssistant && len(turn[n-1].ToolCalls) == 0 {
+		intermediate = turn[:n-1]
+	}
+
+	// Explicit, strictly increasing timestamps keep the transcript order stable
+	createdAt := time.Now()
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		for _, msg := range intermediate {
+			record := models.Message{
+				ID:             uuid.New(),
+				ConversationID: convID,
+				Role:           models.MessageRole(msg.Role),
+				Content:        msg.Content,
+				ToolCallID:     msg.ToolCallID,
+				Name:           msg.Name,
+				CreatedAt:      createdAt,
+			}
+			if len(msg.ToolCalls) > 0 {
+				toolCallsJSON, err := json.Marshal(msg.ToolCalls)
+				if err != nil {
+					return err
+				}
+				record.ToolCalls = datatypes.JSON(toolCallsJSON)
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			createdAt = createdAt.Add(time.Microsecond)
+		}
+
+		if final == nil {
+			return nil
+		}
+		final.CreatedAt = createdAt
+		return tx.Create(final).Error
+	})
+}
+
 // convertToAgentMessages converts database messages to agent messages
 func (h *MessagesHandler) convertToAgentMessages(dbMessages []models.Message) []llm.Message {
 	// Convert all messages to agent format
 	// Skip system messages as the orchestrator adds its own system prompt
 	// The current user message is passed separately via ChatRequest.UserMessage
+	// Tool-call pairs are sanitized afterwards so that a partially stored turn cannot
+	// produce a history the LLM API rejects
 	var agentMessages []llm.Message
 
 	for i := 0; i < len(dbMessages); i++ {
@@ -643,7 +1003,70 @@ func (h *MessagesHandler) convertToAgentMessages(dbMessages []models.Message) []
 		agentMessages = append(agentMessages, agentMsg)
 	}
 
-	return agentMessages
+	return pairToolMessages(agentMessages)
+}
+
+// pairToolMessages drops tool calls without a stored result and tool results without a
+// matching call. An assistant message left with neither tool calls nor content is removed.
+func pairToolMessages(messages []llm.Message) []llm.Message {
+	result := make([]llm.Message, 0, len(messages))
+
+	for i := 0; i < len(messages); i++ {
+		msg := messages[i]
+		if msg.Role == llm.RoleTool {
+			// Not preceded by an assistant tool call
+			continue
+		}
+		if msg.Role != llm.RoleAssistant || len(msg.ToolCalls) == 0 {
+			result = append(result, msg)
+			continue
+		}
+
+		// Collect the tool results that follow this assistant message
+		j := i + 1
+		results := make(map[string]llm.Message)
+		for ; j < len(messages) && messages[j].Role == llm.RoleTool; j++ {
+			results[messages[j].ToolCallID] = messages[j]
+		}
+
+		var calls []llm.ToolCall
+		var toolMessages []llm.Message
+		for _, call := range msg.ToolCalls {
+			if toolMsg, ok := results[call.ID]; ok {
+				calls = append(calls, call)
+				toolMessages = append(toolMessages, toolMsg)
+			}
+		}
+
+		msg.ToolCalls = calls
+		if len(calls) > 0 || msg.Content != "" {
+			result = append(result, msg)
+		}
+		result = append(result, toolMessages...)
+		i = j - 1
+	}
+
+	return result
+}
+
+// toolExecutionMetadata converts a tool execution into the entry stored in the
+// assistant message's "toolExecutions" metadata
+func toolExecutionMetadata(te agent.ToolExecution) map[string]interface{} {
+	entry := map[string]interface{}{
+		"toolCallId": te.ToolCallID,
+		"serverName": te.ServerName,
+		"toolName":   te.ToolName,
+		"arguments":  te.Arguments,
+		"result":     te.Result,
+		"durationMs": te.Duration.Milliseconds(),
+	}
+	if te.Error != nil {
+		entry["error"] = te.Error.Error()
+	}
+	if te.Approval != nil {
+		entry["approval"] = te.Approval
+	}
+	return entry
 }
 
 // persistMessageError stores a short error message in the user message metadata
@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+
 	"github.com/d4l-data4life/go-mcp-host/pkg/agent"
 	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
 )
 
 func TestShortenUserError(t *testing.T) {
@@ -78,3 +83,42 @@ func TestShortenUserError(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertToAgentMessages_ReplaysToolTranscript(t *testing.T) {
+	h := &MessagesHandler{}
+	dbMessages := []models.Message{
+		{Role: models.MessageRoleSystem, Content: "ignored"},
+		{Role: models.MessageRoleUser, Content: "weather in Berlin and Paris?"},
+		{
+			Role:      models.MessageRoleAssistant,
+			ToolCalls: datatypes.JSON(`[{"id":"call_1","type":"function","function":{"name":"weather__forecast","arguments":"{\"city\":\"Berlin\"}"}},{"id":"call_2","type":"function","function":{"name":"weather__forecast","arguments":"{\"city\":\"Paris\"}"}}]`),
+		},
+		{Role: models.MessageRoleTool, ToolCallID: "call_1", Content: "sunny"},
+		{Role: models.MessageRoleTool, ToolCallID: "call_x", Content: "orphan"},
+		{Role: models.MessageRoleAssistant, Content: "Berlin is sunny."},
+	}
+
+	messages := h.convertToAgentMessages(dbMessages)
+
+	require.Len(t, messages, 4)
+	assert.Equal(t, llm.RoleUser, messages[0].Role)
+	// call_2 has no stored result and is dropped, as is the orphaned tool message
+	require.Len(t, messages[1].ToolCalls, 1)
+	assert.Equal(t, "call_1", messages[1].ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city":"Berlin"}`, messages[1].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, llm.RoleTool, messages[2].Role)
+	assert.Equal(t, "call_1", messages[2].ToolCallID)
+	assert.Equal(t, "Berlin is sunny.", messages[3].Content)
+}
+
+func TestFinalContent_OnlyLastIteration(t *testing.T) {
+	turn := []llm.Message{
+		{Role: llm.RoleAssistant, Content: "Let me look that up.", ToolCalls: []llm.ToolCall{{ID: "call-1"}}},
+		{Role: llm.RoleTool, Content: "42", ToolCallID: "call-1"},
+		{Role: llm.RoleAssistant, Content: "The answer is 42."},
+	}
+	assert.Equal(t, "The answer is 42.", finalContent(turn, "The answer is 42."))
+
+	// Without a final message, the content streamed after the last tool calls is used
+	assert.Equal(t, "The answer", finalContent(turn[:2], "The answer"))
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
@@ -23,154 +22,150 @@ const (
 	ContextKeyUserID ContextKey = "userID"
 	// ContextKeyBearerToken is the context key for bearer token
 	ContextKeyBearerToken ContextKey = "bearerToken"
+	// ContextKeyClaims is the context key for the claims of the validated token
+	ContextKeyClaims ContextKey = "claims"
 )
 
-// AuthMiddleware verifies JWT tokens and adds user ID to context
-// If tokenValidator is provided (remote Azure AD keys), it validates using that.
-// Otherwise, falls back to simple token parsing (for local development).
+// AuthMiddleware verifies JWT tokens with tokenValidator and adds the user ID to the
+// context: remote keys (Azure AD, OIDC) or auth.LocalAuth for locally registered users.
+// Without a validator every request is rejected.
 func AuthMiddleware(db *gorm.DB, tokenValidator auth.TokenValidator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get token from Authorization header or query parameter (for WebSocket)
-			authHeader := r.Header.Get("Authorization")
-			token := ""
-			if authHeader != "" {
-				// Parse Bearer token
-				parts := strings.Split(authHeader, " ")
-				if len(parts) == 2 && parts[0] == "Bearer" {
-					token = parts[1]
-				}
-			}
-			if token == "" {
-				// Fallback to token query parameter (used for WebSocket where headers can be tricky)
-				token = r.URL.Query().Get("token")
-			}
+			token := bearerTokenFromRequest(r)
 			if token == "" {
 				render.Status(r, http.StatusUnauthorized)
 				render.JSON(w, r, map[string]string{"error": "Missing authorization token"})
 				return
 			}
+			if tokenValidator == nil {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{"error": "Authentication is not configured"})
+				return
+			}
 
-			var userID uuid.UUID
-			var err error
-
-			// If tokenValidator is configured (remote keys), use it
-			if tokenValidator != nil {
-				userID, err = validateRemoteToken(tokenValidator, token)
-				if err != nil {
-					render.Status(r, http.StatusUnauthorized)
-					render.JSON(w, r, map[string]string{"error": "Invalid or expired token"})
-					return
-				}
+			userID, claims, err := validateRemoteToken(tokenValidator, token)
+			if errors.Is(err, auth.ErrSessionRevoked) {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{
+					"error": "Session expired or revoked - please log in again",
+					"code":  "SESSION_REVOKED",
+				})
+				return
+			}
+			if err != nil {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{"error": "Invalid or expired token"})
+				return
+			}
 
-				// Ensure user exists in database (auto-create for Azure AD users)
-				if err := models.EnsureUser(userID); err != nil {
+			// Ensure user exists in database (auto-create for Azure AD users) with the
+			// profile the identity provider asserts. Local sessions imply the user exists.
+			if _, local := tokenValidator.(*auth.LocalAuth); !local {
+				profile := models.UserProfile{DisplayName: claims.Name, Email: claims.Email, EmailVerified: claims.EmailVerified}
+				if err := ensureUser(db, userID, profile); err != nil {
 					render.Status(r, http.StatusInternalServerError)
 					render.JSON(w, r, map[string]string{"error": "Failed to ensure user exists"})
 					return
 				}
-			} else {
-				// Fallback to simple token parsing (local development)
-				userID, err = parseToken(token)
-				if err != nil {
-					render.Status(r, http.StatusUnauthorized)
-					render.JSON(w, r, map[string]string{"error": "Invalid or expired token"})
-					return
-				}
-
-				// Validate that the user still exists in the database (only for simple tokens)
-				if db != nil {
-					var count int64
-					if err := db.Table("users").Where("id = ? AND deleted_at IS NULL", userID).Count(&count).Error; err != nil {
-						render.Status(r, http.StatusInternalServerError)
-						render.JSON(w, r, map[string]string{"error": "Failed to validate user"})
-						return
-					}
-					if count == 0 {
-						render.Status(r, http.StatusUnauthorized)
-						render.JSON(w, r, map[string]string{
-							"error": "User not found - please log in again",
-							"code":  "USER_NOT_FOUND",
-						})
-						return
-					}
-				}
 			}
 
-			// Add user ID and token to context
+			// Add user ID, token and claims to context
 			ctx := context.WithValue(r.Context(), ContextKeyUserID, userID)
 			ctx = context.WithValue(ctx, ContextKeyBearerToken, token)
+			ctx = context.WithValue(ctx, ContextKeyClaims, claims)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// validateRemoteToken validates a JWT token using remote keys (Azure AD, etc.)
-// and extracts the user ID from the token claims
-func validateRemoteToken(validator auth.TokenValidator, tokenStr string) (uuid.UUID, error) {
-	parsedToken, err := validator.ValidateJWT(tokenStr)
-	if err != nil {
-		return uuid.Nil, err
-	}
+// UserResolver authenticates a request and returns the ID of the user it acts for and,
+// optionally, the user's claims, which the tool policy evaluates. Returning an error
+// rejects the request as unauthorized.
+type UserResolver func(r *http.Request) (uuid.UUID, *auth.Claims, error)
 
-	token := *parsedToken // Dereference the pointer
-
-	// Extract user ID from token claims
-	// Azure AD tokens typically have 'oid' (object ID) or 'sub' (subject) claim
-	var userIDStr string
-	if oid, ok := token.Get("oid"); ok {
-		userIDStr = oid.(string)
-	} else if sub, ok := token.Get("sub"); ok {
-		userIDStr = sub.(string)
-	} else if email, ok := token.Get("email"); ok {
-		// Fallback to email if oid/sub not available
-		userIDStr = email.(string)
-	} else {
-		return uuid.Nil, errors.New("token missing required user identifier claims (oid, sub, or email)")
-	}
+// ResolverAuthMiddleware authenticates requests with a custom UserResolver, for hosts that
+// embed the API behind their own authentication. The user is created on first use with the
+// name and email of the claims, and the request's bearer token, if any, is kept in the
+// context for forwarding to MCP servers.
+func ResolverAuthMiddleware(db *gorm.DB, resolve UserResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, claims, err := resolve(r)
+			if err != nil || userID == uuid.Nil {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{"error": "Unauthorized"})
+				return
+			}
 
-	// Try to parse as UUID, or hash the string to create a deterministic UUID
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		// If not a valid UUID, create a deterministic UUID from the string
-		// This ensures consistent user IDs across sessions
-		userID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(userIDStr))
-	}
+			var profile models.UserProfile
+			if claims != nil {
+				profile = models.UserProfile{DisplayName: claims.Name, Email: claims.Email, EmailVerified: claims.EmailVerified}
+			}
+			if err := ensureUser(db, userID, profile); err != nil {
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, map[string]string{"error": "Failed to ensure user exists"})
+				return
+			}
 
-	return userID, nil
-}
+			ctx := context.WithValue(r.Context(), ContextKeyUserID, userID)
+			ctx = context.WithValue(ctx, ContextKeyBearerToken, bearerTokenFromRequest(r))
+			if claims != nil {
+				ctx = context.WithValue(ctx, ContextKeyClaims, claims)
+			}
 
-// parseToken parses a simple token and returns the user ID
-// In production, use a proper JWT library
-func parseToken(token string) (uuid.UUID, error) {
-	// Find the first colon to split UUID from timestamp
-	// Note: RFC3339 timestamps contain colons, so we can't use strings.Split
-	colonIndex := strings.Index(token, ":")
-	if colonIndex == -1 || colonIndex == len(token)-1 {
-		return uuid.Nil, nil
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
 	}
+}
 
-	userIDStr := token[:colonIndex]
-	timestampStr := token[colonIndex+1:]
+// bearerTokenFromRequest returns the token from the Authorization header or, for
+// WebSocket connections where headers can be tricky, the token query parameter
+func bearerTokenFromRequest(r *http.Request) string {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			return parts[1]
+		}
+	}
+	return r.URL.Query().Get("token")
+}
 
-	// Parse user ID
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		return uuid.Nil, err
+// ensureUser creates the user if needed and syncs the profile, in db when given and in the
+// service database otherwise
+func ensureUser(db *gorm.DB, userID uuid.UUID, profile models.UserProfile) error {
+	if db != nil {
+		return models.EnsureUserInDB(db, userID, profile)
 	}
+	return models.EnsureUser(userID, profile)
+}
 
-	// Check expiration
-	expiration, err := time.Parse(time.RFC3339, timestampStr)
+// validateRemoteToken validates a JWT token using remote keys (Azure AD, etc.)
+// and extracts the user ID and the claims from the token. Validators implementing
+// auth.ClaimsExtractor choose the claims; otherwise the user ID is read from the oid,
+// sub or email claim.
+func validateRemoteToken(validator auth.TokenValidator, tokenStr string) (uuid.UUID, *auth.Claims, error) {
+	parsedToken, err := validator.ValidateJWT(tokenStr)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, nil, err
 	}
 
-	if time.Now().After(expiration) {
-		return uuid.Nil, nil
+	var claims *auth.Claims
+	if extractor, ok := validator.(auth.ClaimsExtractor); ok {
+		claims = extractor.ExtractClaims(*parsedToken)
+	} else {
+		claims = auth.ClaimsFromToken(*parsedToken)
+	}
+	if claims.UserID == "" {
+		return uuid.Nil, nil, errors.New("token missing required user identifier claim")
 	}
 
-	return userID, nil
+	// The same claims must always map to the same user
+	if mapper, ok := validator.(auth.UserIDMapper); ok {
+		return mapper.UserUUID(claims), claims, nil
+	}
+	return auth.UserUUID(claims.UserID), claims, nil
 }
 
 // GetUserIDFromContext retrieves the user ID from the request context
@@ -182,6 +177,16 @@ func GetUserIDFromContext(ctx context.Context) uuid.UUID {
 	return userID
 }
 
+// GetClaimsFromContext retrieves the claims of the validated token from the request
+// context, or nil when the request was not authenticated with a validated JWT
+func GetClaimsFromContext(ctx context.Context) *auth.Claims {
+	claims, ok := ctx.Value(ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		return nil
+	}
+	return claims
+}
+
 // GetBearerTokenFromContext retrieves the bearer token from the request context
 func GetBearerTokenFromContext(ctx context.Context) string {
 	token, ok := ctx.Value(ContextKeyBearerToken).(string)
@@ -0,0 +1,43 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/handlers"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+	"github.com/d4l-data4life/go-svc/pkg/db"
+)
+
+func TestResolverAuthMiddleware_PassesClaims(t *testing.T) {
+	models.InitializeTestDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	claims := &auth.Claims{UserID: "ada", Name: "Ada", Groups: []string{"admins"}}
+	middleware := handlers.ResolverAuthMiddleware(db.Get(), func(*http.Request) (uuid.UUID, *auth.Claims, error) {
+		return userID, claims, nil
+	})
+
+	var seen *auth.Claims
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, userID, handlers.GetUserIDFromContext(r.Context()))
+		seen = handlers.GetClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, claims, seen)
+
+	var user models.User
+	require.NoError(t, db.Get().First(&user, "id = ?", userID).Error)
+	require.NotNil(t, user.DisplayName)
+	assert.Equal(t, "Ada", *user.DisplayName)
+}
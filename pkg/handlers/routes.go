@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi"
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
@@ -12,27 +14,73 @@ import (
 	"github.com/d4l-data4life/go-svc/pkg/middlewares"
 )
 
-// RegisterRoutes registers all API routes
+// RegisterRoutes registers all API routes. Requests are authenticated with tokenValidator
+// or, when it is nil, with the sessions of localAuth.
 func RegisterRoutes(
 	r chi.Router,
 	db *gorm.DB,
 	agent *agent.Agent,
 	mcpManager *manager.Manager,
 	tokenValidator auth.TokenValidator,
-	jwtSecret []byte,
+	localAuth *auth.LocalAuth,
 ) {
+	if tokenValidator == nil && localAuth != nil {
+		tokenValidator = localAuth
+	}
+
 	// External routes (ingress routes)
+	RegisterAPIRoutes(r, db, agent, mcpManager, AuthMiddleware(db, tokenValidator), localAuth)
+
+	// Internal routes (service-to-service)
+	r.Route(config.InternalPrefix, func(r chi.Router) {
+		// Service-authenticated routes (require service secret)
+		r.Group(func(r chi.Router) {
+			// Get service secret from config
+			serviceSecret := viper.GetString("SERVICE_SECRET")
+			if serviceSecret == "" {
+				// If no service secret is configured, skip service auth routes
+				return
+			}
+
+			// Create service auth middleware with proper logger
+			logger := NewServiceAuthLogger()
+			serviceAuth := middlewares.NewServiceSecretAuthenticator(serviceSecret, logger)
+			r.Use(serviceAuth.Authenticate())
+
+			// Users management
+			usersHandler := NewUsersHandler(db)
+			r.Mount("/users", usersHandler.Routes())
+		})
+	})
+}
+
+// RegisterAPIRoutes registers the external API (conversations, messages, MCP servers and,
+// when localAuth is set, local auth) under the v1 prefix. authMiddleware protects every
+// route except /auth and must put the user ID into the request context.
+func RegisterAPIRoutes(
+	r chi.Router,
+	db *gorm.DB,
+	agent *agent.Agent,
+	mcpManager *manager.Manager,
+	authMiddleware func(http.Handler) http.Handler,
+	localAuth *auth.LocalAuth,
+) {
 	r.Route(config.APIPrefixV1, func(r chi.Router) {
-		// If run with JWT secret, we'll also manage login and registration
-		if len(jwtSecret) > 0 {
+		// With local auth, we'll also manage login, registration and sessions
+		if localAuth != nil {
 			// Public routes (no authentication required)
-			authHandler := NewAuthHandler(db, jwtSecret)
+			authHandler := NewAuthHandler(db, localAuth)
 			r.Mount("/auth", authHandler.Routes())
 		}
 
+		// OAuth callback of MCP servers (public, the state identifies the user)
+		if oauthHandler := NewOAuthCallbackHandler(mcpManager); oauthHandler != nil {
+			r.Mount("/oauth", oauthHandler.Routes())
+		}
+
 		// Protected routes (authentication required)
 		r.Group(func(r chi.Router) {
-			r.Use(AuthMiddleware(db, tokenValidator))
+			r.Use(authMiddleware)
 
 			// Conversations
 			conversationsHandler := NewConversationsHandler(db)
@@ -44,31 +92,15 @@ func RegisterRoutes(
 				r.Mount("/", messagesHandler.Routes())
 			})
 
+			// Resource subscriptions (nested under conversations)
+			subscriptionsHandler := NewSubscriptionsHandler(db, mcpManager)
+			r.Route("/conversations/{id}/subscriptions", func(r chi.Router) {
+				r.Mount("/", subscriptionsHandler.Routes())
+			})
+
 			// MCP Servers
 			mcpServersHandler := NewMCPServersHandler(db, mcpManager)
 			r.Mount("/mcp", mcpServersHandler.Routes())
 		})
 	})
-
-	// Internal routes (service-to-service)
-	r.Route(config.InternalPrefix, func(r chi.Router) {
-		// Service-authenticated routes (require service secret)
-		r.Group(func(r chi.Router) {
-			// Get service secret from config
-			serviceSecret := viper.GetString("SERVICE_SECRET")
-			if serviceSecret == "" {
-				// If no service secret is configured, skip service auth routes
-				return
-			}
-
-			// Create service auth middleware with proper logger
-			logger := NewServiceAuthLogger()
-			serviceAuth := middlewares.NewServiceSecretAuthenticator(serviceSecret, logger)
-			r.Use(serviceAuth.Authenticate())
-
-			// Users management
-			usersHandler := NewUsersHandler(db)
-			r.Mount("/users", usersHandler.Routes())
-		})
-	})
 }
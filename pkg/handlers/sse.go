@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/patrickmn/go-cache"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+const (
+	// sseRetention is how long a finished stream can still be resumed
+	sseRetention = 5 * time.Minute
+	// sseKeepAlive is the interval of comment lines that keep idle proxies from closing the stream
+	sseKeepAlive = 15 * time.Second
+)
+
+// sseEvent is one buffered server-sent event
+type sseEvent struct {
+	seq  int
+	name string
+	data []byte
+}
+
+// sseStream buffers the events of one streamed agent response so that a client that
+// lost its connection can resume with Last-Event-ID. Event IDs have the form
+// "<stream id>:<sequence>".
+type sseStream struct {
+	id             string
+	conversationID uuid.UUID
+	userID         uuid.UUID
+
+	mu     sync.Mutex
+	events []sseEvent
+	done   bool
+	notify chan struct{} // closed and replaced whenever events or done change
+}
+
+func newSSEStream(conversationID, userID uuid.UUID) *sseStream {
+	return &sseStream{
+		id:             uuid.New().String(),
+		conversationID: conversationID,
+		userID:         userID,
+		notify:         make(chan struct{}),
+	}
+}
+
+// WriteJSON buffers an event; the SSE event name is taken from the payload's "type" field
+func (s *sseStream) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var name string
+	if payload, ok := v.(map[string]interface{}); ok {
+		name, _ = payload["type"].(string)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, sseEvent{seq: len(s.events) + 1, name: name, data: data})
+	close(s.notify)
+	s.notify = make(chan struct{})
+	return nil
+}
+
+// finish marks the stream as complete
+func (s *sseStream) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// since returns the events after seq, whether the stream is complete and a channel that
+// is closed on the next change
+func (s *sseStream) since(seq int) ([]sseEvent, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seq < 0 {
+		seq = 0
+	}
+	if seq > len(s.events) {
+		seq = len(s.events)
+	}
+	return s.events[seq:], s.done, s.notify
+}
+
+// parseLastEventID splits a Last-Event-ID header value into stream ID and sequence
+func parseLastEventID(value string) (string, int, bool) {
+	streamID, rawSeq, ok := strings.Cut(value, ":")
+	if !ok || streamID == "" {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(rawSeq)
+	if err != nil {
+		return "", 0, false
+	}
+	return streamID, seq, true
+}
+
+// StreamMessagesSSE streams the agent's response as Server-Sent Events. It accepts the
+// same SendMessageRequest as the WebSocket endpoint and emits the same event vocabulary.
+// A client that sends Last-Event-ID resumes the referenced stream instead of starting a new one.
+// Tool calls that require approval are denied, as SSE has no channel back to the server.
+func (h *MessagesHandler) StreamMessagesSSE(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	conversationID := chi.URLParam(r, "id")
+
+	convID, err := uuid.Parse(conversationID)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid conversation ID"})
+		return
+	}
+
+	// Verify conversation belongs to user
+	var conversation models.Conversation
+	if err := h.db.Where("id = ? AND user_id = ?", convID, userID).First(&conversation).Error; err != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "Conversation not found"})
+		return
+	}
+
+	if _, ok := w.(http.Flusher); !ok {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Streaming not supported"})
+		return
+	}
+
+	// Resume an existing stream
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		streamID, seq, ok := parseLastEventID(lastEventID)
+		if !ok {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		cached, found := h.sseStreams.Get(streamID)
+		stream, _ := cached.(*sseStream)
+		if !found || stream.conversationID != convID || stream.userID != userID {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, map[string]string{"error": "Stream not found or expired"})
+			return
+		}
+		h.serveSSE(w, r, stream, seq)
+		return
+	}
+
+	var req SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if req.Content == "" && req.MessageID == nil && req.Prompt == nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Message content is required"})
+		return
+	}
+
+	stream := newSSEStream(convID, userID)
+	h.sseStreams.Set(stream.id, stream, cache.NoExpiration)
+
+	// The agent keeps running when the client disconnects so that it can resume
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		defer func() {
+			stream.finish()
+			h.sseStreams.Set(stream.id, stream, sseRetention)
+		}()
+
+		session := newWriteOnlyStreamSession(stream)
+		defer session.cancel()
+		defer h.agent.WatchResourceUpdates(convID, forwardResourceUpdates(stream))()
+
+		userMessage, currentContent, ok := h.processUserMessage(ctx, stream, convID, userID, &req)
+		if !ok {
+			return
+		}
+		h.streamAgentResponse(ctx, session, convID, userID, &conversation, userMessage, currentContent, &req)
+	}()
+
+	h.serveSSE(w, r, stream, 0)
+}
+
+// serveSSE writes the stream's events after seq until the stream completes or the client disconnects
+func (h *MessagesHandler) serveSSE(w http.ResponseWriter, r *http.Request, stream *sseStream, seq int) {
+	flusher := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		events, done, changed := stream.since(seq)
+		for _, event := range events {
+			if _, err := fmt.Fprintf(w, "id: %s:%d\nevent: %s\ndata: %s\n\n", stream.id, event.seq, event.name, event.data); err != nil {
+				logging.LogDebugf("SSE client disconnected: stream=%s", stream.id)
+				return
+			}
+			seq = event.seq
+		}
+		flusher.Flush()
+
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
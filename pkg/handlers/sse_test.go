@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeSSE_ResumesAfterLastEventID(t *testing.T) {
+	stream := newSSEStream(uuid.New(), uuid.New())
+	require.NoError(t, stream.WriteJSON(map[string]interface{}{"type": "user_message"}))
+	require.NoError(t, stream.WriteJSON(map[string]interface{}{"type": "content", "content": "Hi"}))
+	require.NoError(t, stream.WriteJSON(map[string]interface{}{"type": "done"}))
+	stream.finish()
+
+	streamID, seq, ok := parseLastEventID(stream.id + ":1")
+	require.True(t, ok)
+	assert.Equal(t, stream.id, streamID)
+
+	h := &MessagesHandler{}
+	rec := httptest.NewRecorder()
+	h.serveSSE(rec, httptest.NewRequest("POST", "/sse", nil), stream, seq)
+
+	body := rec.Body.String()
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.NotContains(t, body, "event: user_message")
+	assert.Equal(t, 2, strings.Count(body, "id: "+stream.id+":"))
+	assert.Contains(t, body, "id: "+stream.id+":2\nevent: content\ndata: {\"content\":\"Hi\",\"type\":\"content\"}\n\n")
+	assert.Contains(t, body, "event: done")
+
+	_, _, ok = parseLastEventID("no-sequence")
+	assert.False(t, ok)
+}
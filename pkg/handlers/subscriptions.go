@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// SubscriptionsHandler handles resource subscription endpoints of a conversation.
+// Updates of subscribed resources are pushed as resource_updated events on the
+// conversation's open message stream.
+type SubscriptionsHandler struct {
+	db         *gorm.DB
+	mcpManager *manager.Manager
+}
+
+// NewSubscriptionsHandler creates a new subscriptions handler
+func NewSubscriptionsHandler(db *gorm.DB, mcpManager *manager.Manager) *SubscriptionsHandler {
+	return &SubscriptionsHandler{
+		db:         db,
+		mcpManager: mcpManager,
+	}
+}
+
+// Routes returns subscription routes
+func (h *SubscriptionsHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.ListSubscriptions)
+	r.Post("/", h.Subscribe)
+	r.Delete("/", h.Unsubscribe)
+
+	return r
+}
+
+// ListSubscriptions returns the resources the conversation is subscribed to
+func (h *SubscriptionsHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	convID, ok := h.conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, h.mcpManager.ListResourceSubscriptions(convID))
+}
+
+// Subscribe subscribes the conversation to a resource. The body is a manager.ResourceSubscription.
+func (h *SubscriptionsHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	convID, ok := h.conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var req manager.ResourceSubscription
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ServerName == "" || req.URI == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Server and URI are required"})
+		return
+	}
+
+	err := h.mcpManager.SubscribeResource(
+		r.Context(),
+		convID,
+		GetUserIDFromContext(r.Context()),
+		GetBearerTokenFromContext(r.Context()),
+		req.ServerName,
+		req.URI,
+	)
+	if err != nil {
+		if errors.Is(err, manager.ErrSubscriptionsNotSupported) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Server does not support resource subscriptions"})
+			return
+		}
+		logging.LogErrorf(err, "Failed to subscribe to resource")
+		render.Status(r, http.StatusBadGateway)
+		render.JSON(w, r, map[string]string{"error": "Failed to subscribe to resource"})
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, req)
+}
+
+// Unsubscribe removes a subscription given by the server and uri query parameters
+func (h *SubscriptionsHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	convID, ok := h.conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	serverName := r.URL.Query().Get("server")
+	uri := r.URL.Query().Get("uri")
+	if serverName == "" || uri == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Server and URI are required"})
+		return
+	}
+
+	if err := h.mcpManager.UnsubscribeResource(r.Context(), convID, serverName, uri); err != nil {
+		logging.LogWarningf(err, "Failed to unsubscribe from resource")
+	}
+
+	render.Status(r, http.StatusNoContent)
+	_, _ = w.Write([]byte{})
+}
+
+// conversationID parses the conversation ID and verifies it belongs to the user
+func (h *SubscriptionsHandler) conversationID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	convID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid conversation ID"})
+		return uuid.Nil, false
+	}
+
+	var conversation models.Conversation
+	if err := h.db.Where("id = ? AND user_id = ?", convID, GetUserIDFromContext(r.Context())).First(&conversation).Error; err != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]string{"error": "Conversation not found"})
+		return uuid.Nil, false
+	}
+	return convID, true
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/registry"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// userServerNamePattern restricts server names to characters that are valid in qualified
+// tool names; "__" separates the server from the tool and is rejected separately
+var userServerNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,62}$`)
+
+// UserServerRequest creates or updates an MCP server of the user. On update, empty fields
+// and omitted secrets are left unchanged.
+type UserServerRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	// Mode is "batch" (streamable HTTP, default) or "stream" (SSE)
+	Mode            string `json:"mode"`
+	Enabled         *bool  `json:"enabled,omitempty"`
+	RequireApproval *bool  `json:"requireApproval,omitempty"`
+	// Headers replace the server's headers when set (an empty object removes them)
+	Headers map[string]string `json:"headers,omitempty"`
+	// Token replaces the server's bearer token when set (an empty string removes it)
+	Token *string `json:"token,omitempty"`
+}
+
+// UserServerInfo describes an MCP server of the user. Secrets are never returned.
+type UserServerInfo struct {
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description,omitempty"`
+	URL             string    `json:"url"`
+	Mode            string    `json:"mode,omitempty"`
+	Enabled         bool      `json:"enabled"`
+	RequireApproval bool      `json:"requireApproval"`
+	HeaderNames     []string  `json:"headerNames"`
+	HasToken        bool      `json:"hasToken"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// CreateUserServer registers an HTTP MCP server for the current user
+func (h *MCPServersHandler) CreateUserServer(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRegistry(w, r) {
+		return
+	}
+	userID := GetUserIDFromContext(r.Context())
+
+	var req UserServerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	server := models.UserMCPServer{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		URL:         req.URL,
+		Mode:        req.Mode,
+		Enabled:     true,
+	}
+	if req.Enabled != nil {
+		server.Enabled = *req.Enabled
+	}
+	if req.RequireApproval != nil {
+		server.RequireApproval = *req.RequireApproval
+	}
+	var secrets registry.Secrets
+	secrets.Headers = req.Headers
+	if req.Token != nil {
+		secrets.Token = *req.Token
+	}
+
+	if !h.saveUserServer(w, r, &server, secrets, http.StatusCreated) {
+		return
+	}
+	h.mcpManager.InvalidateUserServer(userID, config.MCPServerConfig{Name: server.Name, URL: server.URL})
+	logging.LogDebugf("Registered MCP server %s for user %s", server.Name, userID)
+}
+
+// GetUserServer returns an MCP server of the current user
+func (h *MCPServersHandler) GetUserServer(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRegistry(w, r) {
+		return
+	}
+	server, ok := h.loadUserServer(w, r)
+	if !ok {
+		return
+	}
+	h.renderUserServer(w, r, server, http.StatusOK)
+}
+
+// UpdateUserServer updates an MCP server of the current user
+func (h *MCPServersHandler) UpdateUserServer(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRegistry(w, r) {
+		return
+	}
+
+	var req UserServerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	server, ok := h.loadUserServer(w, r)
+	if !ok {
+		return
+	}
+	previous := config.MCPServerConfig{Name: server.Name, URL: server.URL}
+
+	secrets, err := h.registry.Secrets(server)
+	if err != nil {
+		// Secrets that cannot be decrypted (e.g. after a key change) can only be replaced
+		logging.LogWarningf(err, "Discarding unreadable secrets of MCP server %s", server.ID)
+		secrets = registry.Secrets{}
+	}
+
+	// Update fields
+	if req.Name != "" {
+		server.Name = req.Name
+	}
+	if req.Description != "" {
+		server.Description = req.Description
+	}
+	if req.URL != "" {
+		server.URL = req.URL
+	}
+	if req.Mode != "" {
+		server.Mode = req.Mode
+	}
+	if req.Enabled != nil {
+		server.Enabled = *req.Enabled
+	}
+	if req.RequireApproval != nil {
+		server.RequireApproval = *req.RequireApproval
+	}
+	if req.Headers != nil {
+		secrets.Headers = req.Headers
+	}
+	if req.Token != nil {
+		secrets.Token = *req.Token
+	}
+
+	if !h.saveUserServer(w, r, server, secrets, http.StatusOK) {
+		return
+	}
+	h.mcpManager.InvalidateUserServer(server.UserID, previous)
+	logging.LogDebugf("Updated MCP server %s of user %s", server.ID, server.UserID)
+}
+
+// DeleteUserServer removes an MCP server of the current user
+func (h *MCPServersHandler) DeleteUserServer(w http.ResponseWriter, r *http.Request) {
+	if !h.requireRegistry(w, r) {
+		return
+	}
+	server, ok := h.loadUserServer(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.registry.Delete(r.Context(), server.UserID, server.ID); err != nil {
+		if errors.Is(err, registry.ErrServerNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, map[string]string{"error": "MCP server not found"})
+			return
+		}
+		logging.LogErrorf(err, "Failed to delete MCP server")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to delete MCP server"})
+		return
+	}
+	h.mcpManager.InvalidateUserServer(server.UserID, config.MCPServerConfig{Name: server.Name, URL: server.URL})
+
+	logging.LogDebugf("Deleted MCP server %s of user %s", server.ID, server.UserID)
+
+	render.Status(r, http.StatusNoContent)
+	_, _ = w.Write([]byte{})
+}
+
+// requireRegistry responds with 501 when user servers are disabled
+func (h *MCPServersHandler) requireRegistry(w http.ResponseWriter, r *http.Request) bool {
+	if h.registry != nil {
+		return true
+	}
+	render.Status(r, http.StatusNotImplemented)
+	render.JSON(w, r, map[string]string{"error": "User MCP servers are not enabled"})
+	return false
+}
+
+// loadUserServer loads the server of the {id} URL parameter, responding on failure
+func (h *MCPServersHandler) loadUserServer(w http.ResponseWriter, r *http.Request) (*models.UserMCPServer, bool) {
+	userID := GetUserIDFromContext(r.Context())
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid server ID"})
+		return nil, false
+	}
+
+	server, err := h.registry.Get(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, registry.ErrServerNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, map[string]string{"error": "MCP server not found"})
+		} else {
+			logging.LogErrorf(err, "Failed to get MCP server")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]string{"error": "Failed to get MCP server"})
+		}
+		return nil, false
+	}
+	return server, true
+}
+
+// saveUserServer validates the server, encrypts its secrets and stores it, then renders it
+// with status. It responds on failure and reports whether the server was saved.
+func (h *MCPServersHandler) saveUserServer(
+	w http.ResponseWriter,
+	r *http.Request,
+	server *models.UserMCPServer,
+	secrets registry.Secrets,
+	status int,
+) bool {
+	if msg := validateUserServer(server); msg != "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": msg})
+		return false
+	}
+	// Users must not reach the host's internal network through their servers
+	if err := h.registry.CheckURL(r.Context(), server.URL); err != nil {
+		logging.LogWarningf(err, "Rejected MCP server URL of user %s", server.UserID)
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "URL must not point to a loopback, private or link-local address"})
+		return false
+	}
+
+	// Names must be unique among the configured servers and the user's servers
+	if h.mcpManager.IsConfiguredServer(server.Name) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": "Server name is reserved by a configured server"})
+		return false
+	}
+	taken, err := h.registry.NameTaken(r.Context(), server.UserID, server.Name, server.ID)
+	if err != nil {
+		logging.LogErrorf(err, "Failed to check MCP server name")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to save MCP server"})
+		return false
+	}
+	if taken {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": "Server name already exists"})
+		return false
+	}
+
+	if err := h.registry.SetSecrets(server, secrets); err != nil {
+		logging.LogErrorf(err, "Failed to encrypt MCP server secrets")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to save MCP server"})
+		return false
+	}
+	if err := h.registry.Save(r.Context(), server); err != nil {
+		logging.LogErrorf(err, "Failed to save MCP server")
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]string{"error": "Failed to save MCP server"})
+		return false
+	}
+
+	h.renderUserServer(w, r, server, status)
+	return true
+}
+
+// renderUserServer renders a server of the user without its secrets
+func (h *MCPServersHandler) renderUserServer(w http.ResponseWriter, r *http.Request, server *models.UserMCPServer, status int) {
+	info := UserServerInfo{
+		ID:              server.ID,
+		Name:            server.Name,
+		Description:     server.Description,
+		URL:             server.URL,
+		Mode:            server.Mode,
+		Enabled:         server.Enabled,
+		RequireApproval: server.RequireApproval,
+		HeaderNames:     []string{},
+		CreatedAt:       server.CreatedAt,
+		UpdatedAt:       server.UpdatedAt,
+	}
+	if secrets, err := h.registry.Secrets(server); err == nil {
+		for name := range secrets.Headers {
+			info.HeaderNames = append(info.HeaderNames, name)
+		}
+		sort.Strings(info.HeaderNames)
+		info.HasToken = secrets.Token != ""
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, info)
+}
+
+// validateUserServer returns why the server cannot be saved, or "" when it is valid
+func validateUserServer(server *models.UserMCPServer) string {
+	if !userServerNamePattern.MatchString(server.Name) || strings.Contains(server.Name, "__") {
+		return "Name must start with a letter or digit and contain only letters, digits, '-' and single '_' (max 63 characters)"
+	}
+	u, err := url.Parse(server.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "URL must be an absolute http or https URL"
+	}
+	switch server.Mode {
+	case "", config.HTTPServerModeBatch, config.HTTPServerModeStream:
+	default:
+		return "Mode must be \"batch\" or \"stream\""
+	}
+	return ""
+}
@@ -0,0 +1,490 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+const (
+	defaultAPIBaseURL = "https://api.anthropic.com/v1"
+	defaultModel      = "claude-sonnet-4-5"
+	defaultMaxTokens  = 4096
+	defaultAPIVersion = "2023-06-01"
+)
+
+// Client implements the llm.Client interface against the Anthropic Messages API.
+type Client struct {
+	model      string
+	maxTokens  int
+	apiKey     string
+	apiVersion string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Config defines the settings for the Anthropic client.
+type Config struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+	// MaxTokens is used when a request does not set one; the Messages API requires it.
+	MaxTokens  int
+	APIVersion string
+	Timeout    time.Duration
+}
+
+// NewClient builds a new llm.Client that speaks the Anthropic Messages wire format.
+func NewClient(cfg Config) *Client {
+	if cfg.APIKey == "" {
+		cfg.APIKey = viper.GetString("ANTHROPIC_API_KEY")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = viper.GetString("ANTHROPIC_BASE_URL")
+	}
+	baseURL := normalizeBaseURL(cfg.BaseURL)
+	if cfg.Model == "" {
+		model := viper.GetString("ANTHROPIC_DEFAULT_MODEL")
+		if model == "" {
+			model = defaultModel
+		}
+		cfg.Model = model
+	}
+	if cfg.MaxTokens == 0 {
+		cfg.MaxTokens = viper.GetInt("ANTHROPIC_MAX_TOKENS")
+		if cfg.MaxTokens == 0 {
+			cfg.MaxTokens = defaultMaxTokens
+		}
+	}
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = defaultAPIVersion
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 2 * time.Minute
+	}
+
+	logging.LogDebugf("Initialized Anthropic client (model=%s, base=%s, timeout=%s)",
+		cfg.Model, baseURL, cfg.Timeout)
+
+	return &Client{
+		model:      cfg.Model,
+		maxTokens:  cfg.MaxTokens,
+		apiKey:     cfg.APIKey,
+		apiVersion: cfg.APIVersion,
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Chat sends a non-streaming request to the Messages API.
+func (c *Client) Chat(ctx context.Context, request llm.ChatRequest) (*llm.ChatResponse, error) {
+	if request.Model == "" {
+		request.Model = c.model
+	}
+
+	params, err := c.buildMessagesRequest(request, false)
+	if err != nil {
+		return nil, err
+	}
+	logMessagesRequest(params)
+
+	resp, err := c.do(ctx, http.MethodPost, "/messages", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "LLM chat completion failed")
+	}
+	defer resp.Body.Close()
+
+	var result messagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.Wrap(err, "failed to decode Anthropic response")
+	}
+	if len(result.Content) == 0 && result.StopReason == "" {
+		return nil, errors.New("LLM returned an empty response")
+	}
+
+	return &llm.ChatResponse{
+		ID:      result.ID,
+		Model:   result.Model,
+		Message: convertFromContentBlocks(result.Content),
+		Usage:   convertUsage(result.Usage),
+	}, nil
+}
+
+// ChatStream starts a streaming request and returns incremental chunks.
+func (c *Client) ChatStream(ctx context.Context, request llm.ChatRequest) (<-chan llm.StreamChunk, error) {
+	if request.Model == "" {
+		request.Model = c.model
+	}
+
+	params, err := c.buildMessagesRequest(request, true)
+	if err != nil {
+		return nil, err
+	}
+	logMessagesRequest(params)
+
+	resp, err := c.do(ctx, http.MethodPost, "/messages", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "LLM streaming request failed")
+	}
+
+	chunkChan := make(chan llm.StreamChunk, 10)
+
+	go func() {
+		defer close(chunkChan)
+		defer resp.Body.Close()
+
+		acc := newStreamAccumulator()
+		reader := newEventReader(resp.Body)
+
+		for {
+			event, data, err := reader.next()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				logging.LogErrorf(err, "LLM streaming error")
+				chunkChan <- llm.StreamChunk{
+					Error: errors.Wrap(err, "LLM streaming error"),
+					Done:  true,
+				}
+				return
+			}
+
+			chunk, done, err := acc.apply(event, data)
+			if err != nil {
+				logging.LogErrorf(err, "LLM streaming error")
+				chunkChan <- llm.StreamChunk{
+					Error: err,
+					Done:  true,
+				}
+				return
+			}
+			if chunk != nil {
+				chunkChan <- *chunk
+			}
+			if done {
+				return
+			}
+		}
+
+		// A stream without message_stop was cut off, e.g. by a dropped connection or a proxy
+		// timeout; the accumulated message is incomplete
+		truncated := fmt.Errorf("%w: stream ended before message_stop", llm.ErrRequestFailed)
+		logging.LogErrorf(truncated, "LLM streaming error")
+		chunkChan <- llm.StreamChunk{
+			ID:    acc.id,
+			Model: acc.model,
+			Error: truncated,
+			Done:  true,
+		}
+	}()
+
+	return chunkChan, nil
+}
+
+// ListModels lists the models visible to the configured API key.
+func (c *Client) ListModels(ctx context.Context) ([]llm.Model, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list Anthropic models")
+	}
+	defer resp.Body.Close()
+
+	var result modelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.Wrap(err, "failed to decode Anthropic models")
+	}
+	if len(result.Data) == 0 {
+		return nil, errors.New("LLM returned no models")
+	}
+
+	models := make([]llm.Model, len(result.Data))
+	for i, m := range result.Data {
+		name := m.DisplayName
+		if name == "" {
+			name = m.ID
+		}
+		models[i] = llm.Model{
+			ID:          m.ID,
+			Name:        name,
+			Description: m.Type,
+			ModifiedAt:  m.CreatedAt,
+		}
+	}
+	return models, nil
+}
+
+// Tokenizer returns a heuristic tokenizer: Anthropic does not publish the tokenizer of its
+// current models, which produce somewhat more tokens per character than OpenAI's.
+func (c *Client) Tokenizer(model string) llm.Tokenizer {
+	return llm.HeuristicTokenizer{CharsPerToken: 3.5}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode request")
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("anthropic-version", c.apiVersion)
+	if c.apiKey != "" {
+		req.Header.Set("x-api-key", c.apiKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrConnectionFailed, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, decodeAPIError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) buildMessagesRequest(req llm.ChatRequest, stream bool) (messagesRequest, error) {
+	system, messages, err := convertMessages(req.Messages)
+	if err != nil {
+		return messagesRequest{}, err
+	}
+
+	maxTokens := c.maxTokens
+	if req.MaxTokens != nil && *req.MaxTokens > 0 {
+		maxTokens = *req.MaxTokens
+	}
+
+	params := messagesRequest{
+		Model:         req.Model,
+		System:        system,
+		Messages:      messages,
+		MaxTokens:     maxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        stream,
+	}
+	if len(req.Tools) > 0 {
+		params.Tools = convertTools(req.Tools)
+	}
+	return params, nil
+}
+
+// convertMessages maps llm messages onto the Messages API shape: system prompts are
+// lifted to the top-level field, tool results become tool_result blocks in a user turn,
+// and consecutive messages of the same role are merged since the API requires alternation.
+func convertMessages(messages []llm.Message) (string, []message, error) {
+	var systemParts []string
+	result := make([]message, 0, len(messages))
+
+	appendBlocks := func(role string, blocks ...contentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, blocks...)
+			return
+		}
+		result = append(result, message{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case llm.RoleSystem:
+			if msg.Content != "" {
+				systemParts = append(systemParts, msg.Content)
+			}
+		case llm.RoleAssistant:
+			blocks := make([]contentBlock, 0, 1+len(msg.ToolCalls))
+			if msg.Content != "" {
+				blocks = append(blocks, contentBlock{Type: blockTypeText, Text: msg.Content})
+			}
+			for i, tc := range msg.ToolCalls {
+				id := tc.ID
+				if id == "" {
+					id = fmt.Sprintf("toolu_%d", i)
+				}
+				input := json.RawMessage("{}")
+				if strings.TrimSpace(tc.Function.Arguments) != "" {
+					if !json.Valid([]byte(tc.Function.Arguments)) {
+						return "", nil, errors.Errorf("tool call %s has invalid JSON arguments", id)
+					}
+					input = json.RawMessage(tc.Function.Arguments)
+				}
+				blocks = append(blocks, contentBlock{
+					Type:  blockTypeToolUse,
+					ID:    id,
+					Name:  tc.Function.Name,
+					Input: input,
+				})
+			}
+			appendBlocks(llm.RoleAssistant, blocks...)
+		case llm.RoleTool:
+			if msg.ToolCallID == "" {
+				return "", nil, errors.New("tool messages require a tool_call_id")
+			}
+			block := contentBlock{
+				Type:      blockTypeToolResult,
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			}
+			if strings.HasPrefix(msg.Content, "Error: ") {
+				block.IsError = true
+			}
+			appendBlocks(llm.RoleUser, block)
+		default:
+			if msg.Content != "" {
+				appendBlocks(llm.RoleUser, contentBlock{Type: blockTypeText, Text: msg.Content})
+			}
+		}
+	}
+
+	return strings.Join(systemParts, "\n\n"), result, nil
+}
+
+func convertTools(tools []llm.Tool) []tool {
+	result := make([]tool, len(tools))
+	for i, t := range tools {
+		schema := t.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		result[i] = tool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: schema,
+		}
+	}
+	return result
+}
+
+func convertFromContentBlocks(blocks []contentBlock) llm.Message {
+	msg := llm.Message{Role: llm.RoleAssistant}
+	var text strings.Builder
+	for _, block := range blocks {
+		switch block.Type {
+		case blockTypeText:
+			text.WriteString(block.Text)
+		case blockTypeToolUse:
+			args := string(block.Input)
+			if args == "" {
+				args = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{
+				ID:   block.ID,
+				Type: llm.ToolTypeFunction,
+				Function: llm.ToolCallFunction{
+					Name:      block.Name,
+					Arguments: args,
+				},
+				Index: len(msg.ToolCalls),
+			})
+		}
+	}
+	msg.Content = text.String()
+	return msg
+}
+
+func convertUsage(u usage) llm.Usage {
+	return llm.Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
+
+func decodeAPIError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var apiErr errorResponse
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Message != "" {
+		return fmt.Errorf("%w: status %d: %s: %s", llm.ErrRequestFailed, resp.StatusCode, apiErr.Error.Type, apiErr.Error.Message)
+	}
+	return fmt.Errorf("%w: status %d: %s", llm.ErrRequestFailed, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func normalizeBaseURL(raw string) string {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		trimmed = defaultAPIBaseURL
+	}
+	trimmed = strings.TrimRight(trimmed, "/")
+	if !strings.HasSuffix(trimmed, "/v1") {
+		trimmed += "/v1"
+	}
+	return trimmed
+}
+
+func logMessagesRequest(params messagesRequest) {
+	if !viper.GetBool("VERBOSE") {
+		return
+	}
+	payload, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		logging.LogDebugf("Anthropic messages params (marshal error: %v): %+v", err, params)
+		return
+	}
+	logging.LogDebugf("Anthropic messages params:\n%s", string(payload))
+}
+
+// eventReader parses a text/event-stream body into (event, data) pairs.
+type eventReader struct {
+	scanner *bufio.Scanner
+}
+
+func newEventReader(r io.Reader) *eventReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	return &eventReader{scanner: scanner}
+}
+
+func (r *eventReader) next() (string, []byte, error) {
+	var event string
+	var data bytes.Buffer
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if event == "" && data.Len() == 0 {
+				continue
+			}
+			return event, data.Bytes(), nil
+		}
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	if event != "" || data.Len() > 0 {
+		return event, data.Bytes(), nil
+	}
+	return "", nil, io.EOF
+}
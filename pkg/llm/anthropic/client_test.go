@@ -0,0 +1,205 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+)
+
+func TestChat_MapsMessagesAndToolUse(t *testing.T) {
+	var captured messagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, defaultAPIVersion, r.Header.Get("anthropic-version"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&captured))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-test",
+			"content": [
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "weather__forecast", "input": {"city": "Berlin"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 10, "output_tokens": 5}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{APIKey: "test-key", BaseURL: server.URL, Model: "claude-test"})
+	resp, err := client.Chat(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "be helpful"},
+			{Role: llm.RoleUser, Content: "weather?"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{
+				ID:       "toolu_0",
+				Type:     llm.ToolTypeFunction,
+				Function: llm.ToolCallFunction{Name: "weather__forecast", Arguments: `{"city":"Paris"}`},
+			}}},
+			{Role: llm.RoleTool, ToolCallID: "toolu_0", Content: "sunny"},
+			{Role: llm.RoleUser, Content: "and Berlin?"},
+		},
+		Tools: []llm.Tool{{
+			Type: llm.ToolTypeFunction,
+			Function: llm.ToolFunction{
+				Name:       "weather__forecast",
+				Parameters: map[string]interface{}{"type": "object"},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	// System prompt lifted to the top-level field
+	assert.Equal(t, "be helpful", captured.System)
+	assert.Equal(t, defaultMaxTokens, captured.MaxTokens)
+	require.Len(t, captured.Tools, 1)
+	assert.Equal(t, "weather__forecast", captured.Tools[0].Name)
+
+	// user, assistant(tool_use), user(tool_result + text)
+	require.Len(t, captured.Messages, 3)
+	assert.Equal(t, blockTypeToolUse, captured.Messages[1].Content[0].Type)
+	assert.JSONEq(t, `{"city":"Paris"}`, string(captured.Messages[1].Content[0].Input))
+	assert.Equal(t, llm.RoleUser, captured.Messages[2].Role)
+	require.Len(t, captured.Messages[2].Content, 2)
+	assert.Equal(t, blockTypeToolResult, captured.Messages[2].Content[0].Type)
+	assert.Equal(t, "toolu_0", captured.Messages[2].Content[0].ToolUseID)
+	assert.Equal(t, "sunny", captured.Messages[2].Content[0].Content)
+
+	assert.Equal(t, "Let me check.", resp.Message.Content)
+	require.Len(t, resp.Message.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", resp.Message.ToolCalls[0].ID)
+	assert.Equal(t, "weather__forecast", resp.Message.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Berlin"}`, resp.Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, 15, resp.Usage.TotalTokens)
+}
+
+func TestChatStream_AccumulatesTextAndToolUse(t *testing.T) {
+	events := []struct{ name, data string }{
+		{"message_start", `{"type":"message_start","message":{"id":"msg_2","model":"claude-test","usage":{"input_tokens":7,"output_tokens":1}}}`},
+		{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`},
+		{"ping", `{"type":"ping"}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+		{"content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_9","name":"fs__read","input":{}}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"/tmp\"}"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":1}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":12}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req messagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, e.data)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, Model: "claude-test"})
+	stream, err := client.ChatStream(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	var content, toolName, toolArgs string
+	var final *llm.StreamChunk
+	for chunk := range stream {
+		require.NoError(t, chunk.Error)
+		content += chunk.Delta.Content
+		for _, tc := range chunk.Delta.ToolCalls {
+			assert.Equal(t, 0, tc.Index)
+			toolName += tc.Function.Name
+			toolArgs += tc.Function.Arguments
+		}
+		if chunk.Done {
+			c := chunk
+			final = &c
+		}
+	}
+
+	assert.Equal(t, "Hello", content)
+	assert.Equal(t, "fs__read", toolName)
+	assert.JSONEq(t, `{"path":"/tmp"}`, toolArgs)
+	require.NotNil(t, final)
+	require.NotNil(t, final.Message)
+	assert.Equal(t, "Hello", final.Message.Content)
+	require.Len(t, final.Message.ToolCalls, 1)
+	assert.Equal(t, "fs__read", final.Message.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"path":"/tmp"}`, final.Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, 19, final.Usage.TotalTokens)
+}
+
+func TestChatStream_TruncatedStreamFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_3\",\"model\":\"claude-test\"}}\n\n")
+		_, _ = fmt.Fprint(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n")
+		_, _ = fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n")
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, Model: "claude-test"})
+	stream, err := client.ChatStream(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	var final *llm.StreamChunk
+	for chunk := range stream {
+		if chunk.Done {
+			c := chunk
+			final = &c
+		}
+	}
+	require.NotNil(t, final)
+	assert.ErrorIs(t, final.Error, llm.ErrRequestFailed)
+	assert.Nil(t, final.Message)
+}
+
+func TestChat_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, Model: "claude-test"})
+	_, err := client.Chat(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "hi"}},
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, llm.ErrRequestFailed)
+}
+
+func TestListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		_, _ = w.Write([]byte(`{"data":[{"id":"claude-test","type":"model","display_name":"Claude Test","created_at":"2025-01-01T00:00:00Z"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL})
+	models, err := client.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, "claude-test", models[0].ID)
+	assert.Equal(t, "Claude Test", models[0].Name)
+}
@@ -0,0 +1,165 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+)
+
+// streamAccumulator rebuilds the assistant message from Messages API stream events.
+type streamAccumulator struct {
+	id           string
+	model        string
+	inputTokens  int
+	outputTokens int
+	toolCalls    int
+	blocks       []*blockState
+	byIndex      map[int]*blockState
+}
+
+type blockState struct {
+	toolIndex int
+	blockType string
+	id        string
+	name      string
+	text      strings.Builder
+	input     strings.Builder
+}
+
+func newStreamAccumulator() *streamAccumulator {
+	return &streamAccumulator{byIndex: make(map[int]*blockState)}
+}
+
+// apply consumes one server-sent event and returns the chunk to forward (if any) and
+// whether the stream has terminated.
+func (a *streamAccumulator) apply(event string, data []byte) (*llm.StreamChunk, bool, error) {
+	switch event {
+	case "message_start":
+		var payload streamMessageStart
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, false, errors.Wrap(err, "failed to decode message_start")
+		}
+		a.id = payload.Message.ID
+		a.model = payload.Message.Model
+		a.inputTokens = payload.Message.Usage.InputTokens
+		a.outputTokens = payload.Message.Usage.OutputTokens
+		return &llm.StreamChunk{
+			ID:    a.id,
+			Model: a.model,
+			Delta: llm.Delta{Role: llm.RoleAssistant},
+		}, false, nil
+
+	case "content_block_start":
+		var payload streamContentBlockStart
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, false, errors.Wrap(err, "failed to decode content_block_start")
+		}
+		block := &blockState{
+			blockType: payload.ContentBlock.Type,
+			id:        payload.ContentBlock.ID,
+			name:      payload.ContentBlock.Name,
+		}
+		block.text.WriteString(payload.ContentBlock.Text)
+		a.blocks = append(a.blocks, block)
+		a.byIndex[payload.Index] = block
+		if block.blockType != blockTypeToolUse {
+			return nil, false, nil
+		}
+		block.toolIndex = a.toolCalls
+		a.toolCalls++
+		return &llm.StreamChunk{
+			ID:    a.id,
+			Model: a.model,
+			Delta: llm.Delta{ToolCalls: []llm.ToolCall{{
+				ID:       block.id,
+				Type:     llm.ToolTypeFunction,
+				Function: llm.ToolCallFunction{Name: block.name},
+				Index:    block.toolIndex,
+			}}},
+		}, false, nil
+
+	case "content_block_delta":
+		var payload streamContentBlockDelta
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, false, errors.Wrap(err, "failed to decode content_block_delta")
+		}
+		block, ok := a.byIndex[payload.Index]
+		if !ok {
+			return nil, false, errors.Errorf("content_block_delta for unknown block index %d", payload.Index)
+		}
+		switch payload.Delta.Type {
+		case "text_delta":
+			block.text.WriteString(payload.Delta.Text)
+			return &llm.StreamChunk{
+				ID:    a.id,
+				Model: a.model,
+				Delta: llm.Delta{Content: payload.Delta.Text},
+			}, false, nil
+		case "input_json_delta":
+			block.input.WriteString(payload.Delta.PartialJSON)
+			if payload.Delta.PartialJSON == "" {
+				return nil, false, nil
+			}
+			return &llm.StreamChunk{
+				ID:    a.id,
+				Model: a.model,
+				Delta: llm.Delta{ToolCalls: []llm.ToolCall{{
+					Type:     llm.ToolTypeFunction,
+					Function: llm.ToolCallFunction{Arguments: payload.Delta.PartialJSON},
+					Index:    block.toolIndex,
+				}}},
+			}, false, nil
+		}
+		return nil, false, nil
+
+	case "message_delta":
+		var payload streamMessageDelta
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, false, errors.Wrap(err, "failed to decode message_delta")
+		}
+		if payload.Usage.OutputTokens > 0 {
+			a.outputTokens = payload.Usage.OutputTokens
+		}
+		return nil, false, nil
+
+	case "message_stop":
+		msg := a.message()
+		return &llm.StreamChunk{
+			ID:      a.id,
+			Model:   a.model,
+			Message: &msg,
+			Usage:   a.usage(),
+			Done:    true,
+		}, true, nil
+
+	case "error":
+		var payload errorResponse
+		if err := json.Unmarshal(data, &payload); err != nil || payload.Error.Message == "" {
+			return nil, true, fmt.Errorf("%w: stream error: %s", llm.ErrRequestFailed, string(data))
+		}
+		return nil, true, fmt.Errorf("%w: %s: %s", llm.ErrRequestFailed, payload.Error.Type, payload.Error.Message)
+	}
+
+	// ping and unknown events are ignored
+	return nil, false, nil
+}
+
+func (a *streamAccumulator) message() llm.Message {
+	blocks := make([]contentBlock, 0, len(a.blocks))
+	for _, b := range a.blocks {
+		block := contentBlock{Type: b.blockType, ID: b.id, Name: b.name, Text: b.text.String()}
+		if b.blockType == blockTypeToolUse {
+			block.Input = json.RawMessage(b.input.String())
+		}
+		blocks = append(blocks, block)
+	}
+	return convertFromContentBlocks(blocks)
+}
+
+func (a *streamAccumulator) usage() llm.Usage {
+	return convertUsage(usage{InputTokens: a.inputTokens, OutputTokens: a.outputTokens})
+}
@@ -0,0 +1,162 @@
+// Package bpe implements byte pair encoding tokenizers compatible with OpenAI's tiktoken
+// encodings, for counting tokens offline.
+package bpe
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// whitespace are the characters matched by \s in Go regular expressions
+const whitespace = "\t\n\f\r "
+
+// Encoding is a byte pair encoding: a pre-tokenization pattern and the merge ranks of
+// byte sequences. It implements llm.Tokenizer.
+type Encoding struct {
+	name    string
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// NewEncoding creates an encoding from merge ranks and a pre-tokenization pattern. The
+// ranks must contain every single byte so that any text can be encoded.
+//
+// Go regular expressions have no lookahead, so tiktoken's \s+(?!\S) alternative has to be
+// left out of the pattern; Encoding splits whitespace runs the same way itself.
+func NewEncoding(name string, ranks map[string]int, pattern string) (*Encoding, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid pattern of encoding %s", name)
+	}
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, errors.Errorf("encoding %s has no rank for byte %#x", name, b)
+		}
+	}
+	return &Encoding{name: name, ranks: ranks, pattern: re}, nil
+}
+
+// ParseRanks reads merge ranks in the tiktoken file format: one base64 encoded byte
+// sequence and its rank per line
+func ParseRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, errors.Errorf("malformed rank line %q", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, errors.Wrapf(err, "malformed token %q", token)
+		}
+		value, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, errors.Wrapf(err, "malformed rank %q", rank)
+		}
+		ranks[string(decoded)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read ranks")
+	}
+	return ranks, nil
+}
+
+// Name returns the name of the encoding
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// CountTokens returns the number of tokens of text
+func (e *Encoding) CountTokens(text string) int {
+	count := 0
+	for _, piece := range e.split(text) {
+		if _, ok := e.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += len(e.merge(piece)) - 1
+	}
+	return count
+}
+
+// Encode returns the token IDs of text
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range e.split(text) {
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		bounds := e.merge(piece)
+		for i := 0; i < len(bounds)-1; i++ {
+			tokens = append(tokens, e.ranks[piece[bounds[i]:bounds[i+1]]])
+		}
+	}
+	return tokens
+}
+
+// split pre-tokenizes text with the encoding's pattern. A whitespace run followed by other
+// text gives up its last character to the next piece, as \s+(?!\S) does in tiktoken.
+func (e *Encoding) split(text string) []string {
+	var pieces []string
+	for pos := 0; pos < len(text); {
+		loc := e.pattern.FindStringIndex(text[pos:])
+		if loc == nil {
+			pieces = append(pieces, text[pos:])
+			break
+		}
+		if loc[0] > 0 {
+			// Not covered by the pattern
+			pieces = append(pieces, text[pos:pos+loc[0]])
+			pos += loc[0]
+			continue
+		}
+		if loc[1] == 0 {
+			// Empty match: consume one character to make progress
+			loc[1] = 1
+		}
+
+		piece := text[pos : pos+loc[1]]
+		if pos+loc[1] < len(text) && len(piece) > 1 &&
+			strings.Trim(piece, whitespace) == "" && !strings.ContainsAny(piece, "\r\n") {
+			piece = piece[:len(piece)-1]
+		}
+		pieces = append(pieces, piece)
+		pos += len(piece)
+	}
+	return pieces
+}
+
+// merge applies the byte pair merges to a piece and returns the token boundaries
+func (e *Encoding) merge(piece string) []int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		minRank, minIndex := math.MaxInt, -1
+		for i := 0; i < len(bounds)-2; i++ {
+			if rank, ok := e.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < minRank {
+				minRank, minIndex = rank, i
+			}
+		}
+		if minIndex < 0 {
+			break
+		}
+		bounds = append(bounds[:minIndex+1], bounds[minIndex+2:]...)
+	}
+	return bounds
+}
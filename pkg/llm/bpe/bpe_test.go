@@ -0,0 +1,82 @@
+package bpe
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEncoding(t *testing.T) *Encoding {
+	var vocab strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&vocab, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	fmt.Fprintf(&vocab, "%s 256\n", base64.StdEncoding.EncodeToString([]byte("ab")))
+	fmt.Fprintf(&vocab, "%s 257\n", base64.StdEncoding.EncodeToString([]byte("abc")))
+	fmt.Fprintf(&vocab, "%s 258\n", base64.StdEncoding.EncodeToString([]byte(" ab")))
+
+	ranks, err := ParseRanks(strings.NewReader(vocab.String()))
+	require.NoError(t, err)
+	encoding, err := NewEncoding("test", ranks, patterns[CL100kBase])
+	require.NoError(t, err)
+	return encoding
+}
+
+func TestEncoding_Encode(t *testing.T) {
+	encoding := testEncoding(t)
+
+	assert.Equal(t, []int{257, 256}, encoding.Encode("abcab"))
+	assert.Equal(t, []int{257, 258}, encoding.Encode("abc ab"))
+	assert.Equal(t, 3, encoding.CountTokens("abcx"+"\n"))
+	assert.Equal(t, 0, encoding.CountTokens(""))
+}
+
+func TestEncoding_SplitsWhitespaceLikeTiktoken(t *testing.T) {
+	encoding := testEncoding(t)
+
+	assert.Equal(t,
+		[]string{"hello", " ", " world", "\n\n", "foo", "'s", " ", "123", "   "},
+		encoding.split("hello  world\n\nfoo's 123   "),
+	)
+}
+
+func TestNewEncoding_RequiresAllBytes(t *testing.T) {
+	_, err := NewEncoding("broken", map[string]int{"a": 0}, patterns[CL100kBase])
+	assert.Error(t, err)
+}
+
+// Token IDs as returned by tiktoken
+func TestGet_MatchesTiktoken(t *testing.T) {
+	cases := map[string]map[string][]int{
+		CL100kBase: {
+			"hello world":                  {15339, 1917},
+			"tiktoken is great!":           {83, 1609, 5963, 374, 2294, 0},
+			"2 + 2 = 4":                    {17, 489, 220, 17, 284, 220, 19},
+			"antidisestablishmentarianism": {519, 85342, 34500, 479, 8997, 2191},
+		},
+		O200kBase: {
+			"hello world":                  {24912, 2375},
+			"tiktoken is great!":           {83, 8251, 2488, 382, 2212, 0},
+			"2 + 2 = 4":                    {17, 659, 220, 17, 314, 220, 19},
+			"antidisestablishmentarianism": {493, 129901, 376, 160388, 21203, 2367},
+		},
+	}
+	for name, texts := range cases {
+		t.Run(name, func(t *testing.T) {
+			encoding, err := Get(name)
+			if errors.Is(err, ErrVocabularyNotFound) {
+				t.Skipf("vocabulary of %s not embedded, run make vocab", name)
+			}
+			require.NoError(t, err)
+			for text, tokens := range texts {
+				assert.Equal(t, tokens, encoding.Encode(text), text)
+				assert.Equal(t, len(tokens), encoding.CountTokens(text), text)
+			}
+		})
+	}
+}
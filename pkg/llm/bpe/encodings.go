@@ -0,0 +1,103 @@
+package bpe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the supported encodings
+const (
+	// CL100kBase is used by GPT-4, GPT-3.5 and the text-embedding-3 models
+	CL100kBase = "cl100k_base"
+	// O200kBase is used by GPT-4o, GPT-4.1, GPT-5 and the o-series models
+	O200kBase = "o200k_base"
+)
+
+// ErrVocabularyNotFound is returned when the vocabulary of an encoding is not embedded
+var ErrVocabularyNotFound = errors.New("BPE vocabulary not embedded")
+
+// Vocabulary is the published rank file of an encoding
+type Vocabulary struct {
+	URL string
+	// SHA256 pins the file; embedded files with another checksum are rejected
+	SHA256 string
+}
+
+// Vocabularies are the rank files of the supported encodings, with the checksums tiktoken
+// verifies them with
+var Vocabularies = map[string]Vocabulary{
+	CL100kBase: {
+		URL:    "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		SHA256: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	},
+	O200kBase: {
+		URL:    "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		SHA256: "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+	},
+}
+
+// vocabFS holds the tiktoken rank files as vocab/<encoding>.tiktoken. They are downloaded
+// and verified by go generate (make vocab) before building.
+//
+//go:generate go run ./internal/fetchvocab vocab
+//go:embed vocab
+var vocabFS embed.FS
+
+// patterns are the pre-tokenization patterns of the encodings, without the \s+(?!\S)
+// alternative (see NewEncoding)
+var patterns = map[string]string{
+	CL100kBase: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`,
+	O200kBase: `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`,
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = make(map[string]*Encoding)
+)
+
+// Get returns an encoding by name, loading its embedded vocabulary on first use
+func Get(name string) (*Encoding, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if encoding, ok := encodings[name]; ok {
+		return encoding, nil
+	}
+
+	pattern, ok := patterns[name]
+	if !ok {
+		return nil, errors.Errorf("unknown encoding %s", name)
+	}
+
+	data, err := vocabFS.ReadFile("vocab/" + name + ".tiktoken")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errors.Wrapf(ErrVocabularyNotFound, "encoding %s", name)
+		}
+		return nil, errors.Wrapf(err, "failed to read vocabulary of encoding %s", name)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != Vocabularies[name].SHA256 {
+		return nil, errors.Errorf("vocabulary of encoding %s does not match its checksum", name)
+	}
+
+	ranks, err := ParseRanks(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse vocabulary of encoding %s", name)
+	}
+	encoding, err := NewEncoding(name, ranks, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	encodings[name] = encoding
+	return encoding, nil
+}
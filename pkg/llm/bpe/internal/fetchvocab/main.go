@@ -0,0 +1,77 @@
+// Command fetchvocab downloads the tiktoken rank files of the encodings in bpe.Vocabularies
+// into a directory and verifies their checksums. Files that are already present and valid
+// are kept, so it only needs network access once.
+//
+// Usage: go run ./internal/fetchvocab <dir>
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm/bpe"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: fetchvocab <dir>")
+		os.Exit(2)
+	}
+	dir := os.Args[1]
+
+	names := make([]string, 0, len(bpe.Vocabularies))
+	for name := range bpe.Vocabularies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	for _, name := range names {
+		if err := fetch(client, filepath.Join(dir, name+".tiktoken"), bpe.Vocabularies[name]); err != nil {
+			fmt.Fprintf(os.Stderr, "fetchvocab: %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// fetch downloads the vocabulary to path unless a file with the pinned checksum exists
+func fetch(client *http.Client, path string, vocab bpe.Vocabulary) error {
+	if data, err := os.ReadFile(path); err == nil && checksum(data) == vocab.SHA256 {
+		return nil
+	}
+
+	resp, err := client.Get(vocab.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", vocab.URL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if sum := checksum(data); sum != vocab.SHA256 {
+		return fmt.Errorf("checksum of %s is %s, expected %s", vocab.URL, sum, vocab.SHA256)
+	}
+
+	// Written under a temporary name so that an interrupted run leaves no partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
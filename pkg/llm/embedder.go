@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"math"
+)
+
+// Embedder turns texts into embedding vectors for semantic similarity. LLM clients that
+// serve an embeddings API implement it next to Client.
+type Embedder interface {
+	// Embed returns one vector per text, in the order of texts. An empty model means the
+	// embedder's default embedding model.
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// EmbedderFor returns the client as an Embedder, or nil when it cannot compute embeddings
+func EmbedderFor(client Client) Embedder {
+	if embedder, ok := client.(Embedder); ok {
+		return embedder
+	}
+	return nil
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, or 0 when their
+// lengths differ or one of them is zero
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
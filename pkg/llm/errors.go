@@ -9,5 +9,7 @@ var (
 
 	// ErrRequestFailed indicates the LLM request failed
 	ErrRequestFailed = errors.New("LLM request failed")
-)
 
+	// ErrEmbeddingsUnsupported indicates the LLM client cannot compute embeddings
+	ErrEmbeddingsUnsupported = errors.New("LLM client does not support embeddings")
+)
@@ -17,18 +17,24 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm/bpe"
 	"github.com/d4l-data4life/go-svc/pkg/logging"
 )
 
 const (
 	defaultAPIBaseURL = "https://api.openai.com/v1"
 	defaultModel      = "gpt-4o-mini"
+	// defaultEmbeddingModel is used when neither Config nor OPENAI_EMBEDDING_MODEL set one
+	defaultEmbeddingModel = "text-embedding-3-small"
+	// maxEmbeddingInputs is the maximum number of inputs of one embeddings request
+	maxEmbeddingInputs = 2048
 )
 
 // Client implements the llm.Client interface using the official OpenAI Go SDK.
 type Client struct {
-	model  string
-	openai *openai.Client
+	model          string
+	embeddingModel string
+	openai         *openai.Client
 }
 
 // Config defines the settings for the OpenAI client wrapper.
@@ -36,7 +42,9 @@ type Config struct {
 	APIKey  string
 	BaseURL string
 	Model   string
-	Timeout time.Duration
+	// EmbeddingModel is the default model for Embed
+	EmbeddingModel string
+	Timeout        time.Duration
 }
 
 // NewClient builds a new llm.Client backed by OpenAI's official SDK.
@@ -55,6 +63,12 @@ func NewClient(cfg Config) *Client {
 		}
 		cfg.Model = model
 	}
+	if cfg.EmbeddingModel == "" {
+		cfg.EmbeddingModel = viper.GetString("OPENAI_EMBEDDING_MODEL")
+		if cfg.EmbeddingModel == "" {
+			cfg.EmbeddingModel = defaultEmbeddingModel
+		}
+	}
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 2 * time.Minute
 	}
@@ -74,8 +88,9 @@ func NewClient(cfg Config) *Client {
 		cfg.Model, baseURL, cfg.Timeout)
 
 	return &Client{
-		model:  cfg.Model,
-		openai: &openaiClient,
+		model:          cfg.Model,
+		embeddingModel: cfg.EmbeddingModel,
+		openai:         &openaiClient,
 	}
 }
 
@@ -197,6 +212,81 @@ func (c *Client) ListModels(ctx context.Context) ([]llm.Model, error) {
 	return models, nil
 }
 
+// Embed computes embeddings through the /embeddings endpoint, which Ollama serves as well.
+// Large batches are split into several requests.
+func (c *Client) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if model == "" {
+		model = c.embeddingModel
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingInputs {
+		batch := texts[start:min(start+maxEmbeddingInputs, len(texts))]
+
+		resp, err := c.openai.Embeddings.New(ctx, openai.EmbeddingNewParams{
+			Model:          model,
+			Input:          openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: batch},
+			EncodingFormat: openai.EmbeddingNewParamsEncodingFormatFloat,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "LLM embeddings request failed")
+		}
+		if resp == nil || len(resp.Data) != len(batch) {
+			return nil, errors.New("LLM returned an incomplete embeddings response")
+		}
+
+		embeddings := make([][]float32, len(batch))
+		for _, data := range resp.Data {
+			if data.Index < 0 || int(data.Index) >= len(batch) {
+				return nil, errors.Errorf("LLM returned an embedding for unknown input %d", data.Index)
+			}
+			vector := make([]float32, len(data.Embedding))
+			for i, v := range data.Embedding {
+				vector[i] = float32(v)
+			}
+			embeddings[data.Index] = vector
+		}
+		vectors = append(vectors, embeddings...)
+	}
+	return vectors, nil
+}
+
+// Tokenizer returns the BPE encoding of OpenAI models, or the heuristic tokenizer for
+// models of other providers served through the OpenAI-compatible API.
+func (c *Client) Tokenizer(model string) llm.Tokenizer {
+	if model == "" {
+		model = c.model
+	}
+	name := encodingForModel(model)
+	if name == "" {
+		return llm.HeuristicTokenizer{}
+	}
+	encoding, err := bpe.Get(name)
+	if err != nil {
+		logging.LogDebugf("Using heuristic tokenizer for model %s: %v", model, err)
+		return llm.HeuristicTokenizer{}
+	}
+	return encoding
+}
+
+// encodingForModel maps an OpenAI model to its encoding; unknown models have none
+func encodingForModel(model string) string {
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(model, "gpt-4o"), strings.HasPrefix(model, "gpt-4.1"),
+		strings.HasPrefix(model, "gpt-4.5"), strings.HasPrefix(model, "gpt-5"),
+		strings.HasPrefix(model, "o1"), strings.HasPrefix(model, "o3"), strings.HasPrefix(model, "o4"):
+		return bpe.O200kBase
+	case strings.HasPrefix(model, "gpt-4"), strings.HasPrefix(model, "gpt-3.5"),
+		strings.HasPrefix(model, "text-embedding-"):
+		return bpe.CL100kBase
+	}
+	return ""
+}
+
 func (c *Client) buildChatParams(req llm.ChatRequest) (openai.ChatCompletionNewParams, error) {
 	messages, err := convertMessages(req.Messages)
 	if err != nil {
@@ -323,9 +413,32 @@ func convertFromAPIMessage(msg openai.ChatCompletionMessage) llm.Message {
 
 func convertChunkDelta(delta openai.ChatCompletionChunkChoiceDelta) llm.Delta {
 	return llm.Delta{
-		Role:    delta.Role,
-		Content: delta.Content,
+		Role:      delta.Role,
+		Content:   delta.Content,
+		ToolCalls: convertChunkToolCalls(delta.ToolCalls),
+	}
+}
+
+// convertChunkToolCalls maps partial tool calls from a stream chunk. The ID and name
+// usually only arrive on the first fragment; later fragments carry argument pieces and
+// are correlated through Index.
+func convertChunkToolCalls(toolCalls []openai.ChatCompletionChunkChoiceDeltaToolCall) []llm.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
 	}
+	result := make([]llm.ToolCall, len(toolCalls))
+	for i, tc := range toolCalls {
+		result[i] = llm.ToolCall{
+			ID:   tc.ID,
+			Type: llm.ToolTypeFunction,
+			Function: llm.ToolCallFunction{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+			Index: int(tc.Index),
+		}
+	}
+	return result
 }
 
 func convertAPIToolCalls(toolCalls []openai.ChatCompletionMessageToolCall) []llm.ToolCall {
@@ -0,0 +1,58 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbed_UsesEmbeddingsEndpoint(t *testing.T) {
+	var captured struct {
+		Model          string   `json:"model"`
+		Input          []string `json:"input"`
+		EncodingFormat string   `json:"encoding_format"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&captured))
+
+		// Out of order, as the index decides the position
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"object": "list",
+			"model": "nomic-embed-text",
+			"data": [
+				{"object": "embedding", "index": 1, "embedding": [0, 1]},
+				{"object": "embedding", "index": 0, "embedding": [1, 0.5]}
+			],
+			"usage": {"prompt_tokens": 4, "total_tokens": 4}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, Model: "llama3.2", EmbeddingModel: "nomic-embed-text"})
+	vectors, err := client.Embed(context.Background(), "", []string{"first", "second"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "nomic-embed-text", captured.Model)
+	assert.Equal(t, []string{"first", "second"}, captured.Input)
+	assert.Equal(t, "float", captured.EncodingFormat)
+	assert.Equal(t, [][]float32{{1, 0.5}, {0, 1}}, vectors)
+}
+
+func TestEmbed_RejectsIncompleteResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object": "list", "model": "m", "data": [{"object": "embedding", "index": 0, "embedding": [1]}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL})
+	_, err := client.Embed(context.Background(), "m", []string{"first", "second"})
+	assert.Error(t, err)
+}
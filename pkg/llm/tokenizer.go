@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// messageTokenOverhead approximates the tokens of a message's role and framing
+	messageTokenOverhead = 4
+	// toolCallTokenOverhead approximates the tokens framing a tool call or tool definition
+	toolCallTokenOverhead = 3
+)
+
+// Tokenizer counts the tokens of text as a model would see them
+type Tokenizer interface {
+	// Name identifies the tokenizer, e.g. "cl100k_base" or "heuristic"
+	Name() string
+
+	// CountTokens returns the number of tokens of text
+	CountTokens(text string) int
+}
+
+// TokenizerProvider is implemented by clients that know which tokenizer their models use
+type TokenizerProvider interface {
+	// Tokenizer returns the tokenizer of a model; an empty model means the client's default
+	Tokenizer(model string) Tokenizer
+}
+
+// TokenizerFor returns the tokenizer the client reports for the model, or the heuristic
+// tokenizer when the client does not provide one
+func TokenizerFor(client Client, model string) Tokenizer {
+	if provider, ok := client.(TokenizerProvider); ok {
+		if tokenizer := provider.Tokenizer(model); tokenizer != nil {
+			return tokenizer
+		}
+	}
+	return HeuristicTokenizer{}
+}
+
+// HeuristicTokenizer estimates tokens from the text length. It is used for models whose
+// tokenizer is not available.
+type HeuristicTokenizer struct {
+	// CharsPerToken is the average number of bytes per token (default 4)
+	CharsPerToken float64
+}
+
+// Name implements Tokenizer
+func (t HeuristicTokenizer) Name() string {
+	return "heuristic"
+}
+
+// CountTokens implements Tokenizer
+func (t HeuristicTokenizer) CountTokens(text string) int {
+	charsPerToken := t.CharsPerToken
+	if charsPerToken <= 0 {
+		charsPerToken = 4
+	}
+	return int(float64(len(text)) / charsPerToken)
+}
+
+// CountMessageTokens counts the tokens of messages including their tool calls and a small
+// per-message overhead. A nil tokenizer uses the heuristic.
+func CountMessageTokens(tokenizer Tokenizer, messages []Message) int {
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+
+	total := 0
+	for _, msg := range messages {
+		total += messageTokenOverhead + tokenizer.CountTokens(msg.Content)
+		for _, tc := range msg.ToolCalls {
+			total += toolCallTokenOverhead +
+				tokenizer.CountTokens(tc.Function.Name) +
+				tokenizer.CountTokens(tc.Function.Arguments)
+		}
+	}
+	return total
+}
+
+// CountToolTokens counts the tokens of tool definitions as they are sent to the model.
+// A nil tokenizer uses the heuristic.
+func CountToolTokens(tokenizer Tokenizer, tools []Tool) int {
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+
+	total := 0
+	for _, tool := range tools {
+		schema, err := json.Marshal(tool.Function.Parameters)
+		if err != nil {
+			schema = []byte(fmt.Sprint(tool.Function.Parameters))
+		}
+		total += toolCallTokenOverhead +
+			tokenizer.CountTokens(tool.Function.Name) +
+			tokenizer.CountTokens(tool.Function.Description) +
+			tokenizer.CountTokens(string(schema))
+	}
+	return total
+}
@@ -60,8 +60,11 @@ type Message struct {
 
 // Delta represents incremental content in a stream
 type Delta struct {
-	Role      string     `json:"role,omitempty"`
-	Content   string     `json:"content,omitempty"`
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+	// ToolCalls carries partial tool calls. Fragments of the same call share an Index;
+	// ID and Function.Name are typically only set on the first fragment and
+	// Function.Arguments holds the next piece of the JSON argument string.
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 }
 
@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pkg/errors"
+)
+
+// Elicitation actions defined by the MCP specification
+const (
+	ElicitationActionAccept  = "accept"
+	ElicitationActionDecline = "decline"
+	ElicitationActionCancel  = "cancel"
+)
+
+// ElicitationRequest describes an elicitation/create request from an MCP server
+type ElicitationRequest struct {
+	UserID         uuid.UUID
+	ConversationID uuid.UUID
+	ServerName     string
+	Message        string
+	// RequestedSchema is the JSON schema of the requested input
+	RequestedSchema any
+}
+
+// ElicitationHandler obtains the user's answer to an elicitation request
+type ElicitationHandler func(ctx context.Context, req ElicitationRequest) (*mcp.ElicitResult, error)
+
+// SetElicitationHandler routes elicitation requests from MCP servers to handler. The
+// capability is advertised to sessions created afterwards.
+func (m *Manager) SetElicitationHandler(handler ElicitationHandler) {
+	m.elicitationMu.Lock()
+	defer m.elicitationMu.Unlock()
+	m.elicitationHandler = handler
+}
+
+func (m *Manager) getElicitationHandler() ElicitationHandler {
+	m.elicitationMu.RLock()
+	defer m.elicitationMu.RUnlock()
+	return m.elicitationHandler
+}
+
+func (m *Manager) handleElicit(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+	if req == nil || req.Params == nil {
+		return nil, errors.New("missing elicitation parameters")
+	}
+	handler := m.getElicitationHandler()
+	session := m.findSessionByClientSession(req.GetSession())
+	if handler == nil || session == nil {
+		// Not tied to a conversation (e.g. a short-lived listing client): nobody can answer
+		return &mcp.ElicitResult{Action: ElicitationActionCancel}, nil
+	}
+
+	return handler(ctx, ElicitationRequest{
+		UserID:          session.UserID,
+		ConversationID:  session.ConversationID,
+		ServerName:      session.ServerName,
+		Message:         req.Params.Message,
+		RequestedSchema: req.Params.RequestedSchema,
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
 
 	"github.com/d4l-data4life/go-svc/pkg/logging"
 )
@@ -43,14 +44,20 @@ func WithReconnectPolicy(attempts int, delay time.Duration) Option {
 // Manager manages MCP client sessions for conversations
 type Manager struct {
 	serverConfigs      []config.MCPServerConfig
+	userServers        UserServerStore
+	oauth              OAuthProvider
+	challenges         *cache.Cache            // key: userID:serverName -> WWW-Authenticate header
+	exchangedTokens    *cache.Cache            // key: exchange request -> exchanged access token
 	sessions           map[string]*SessionInfo // key: conversationID:serverName
 	sessionIndex       map[*mcp.ClientSession]*SessionInfo
 	mu                 sync.RWMutex
 	sessionTimeout     time.Duration
 	userToolsCache     *cache.Cache // key: userID:server -> []*mcp.Tool
 	userResourcesCache *cache.Cache // key: userID:server -> []*mcp.Resource
+	userPromptsCache   *cache.Cache // key: userID:server -> []*mcp.Prompt
 	userCacheTTL       time.Duration
-	serverCapsCache    *cache.Cache // key: serverName -> *mcp.ServerCapabilities
+	serverCapsCache    *cache.Cache // key: serverName|url -> *mcp.ServerCapabilities
+	userServerConfigs  *cache.Cache // key: userID -> []config.MCPServerConfig
 	serverLocks        map[string]*sync.Mutex
 
 	userServerLocks map[string]*sync.Mutex // key: userID:serverName
@@ -59,6 +66,19 @@ type Manager struct {
 	clientVersion        string
 	maxReconnectAttempts int
 	reconnectDelay       time.Duration
+
+	samplingClient   llm.Client
+	samplingApprover SamplingApprover
+
+	elicitationMu      sync.RWMutex
+	elicitationHandler ElicitationHandler
+
+	rootsMu   sync.RWMutex
+	userRoots map[string][]config.RootConfig // key: userID:serverName
+
+	subscriptionsMu  sync.RWMutex
+	subscriptions    map[uuid.UUID]map[string]map[string]bool // conversation -> server -> resource URIs
+	resourceWatchers map[*resourceWatcher]struct{}
 }
 
 // SessionInfo holds information about an active MCP session
@@ -73,6 +93,8 @@ type SessionInfo struct {
 	LastAccessed     time.Time
 	mu               sync.RWMutex
 	reconnectTracker *reconnectTracker
+	mcpClient        *mcp.Client
+	roots            []*mcp.Root // roots currently advertised by mcpClient
 }
 
 // NewMCPManager creates a new MCP manager
@@ -84,10 +106,17 @@ func NewMCPManager(serverConfigs []config.MCPServerConfig, opts ...Option) *Mana
 		sessionTimeout:       30 * time.Minute,
 		userToolsCache:       cache.New(30*time.Minute, 10*time.Minute),
 		userResourcesCache:   cache.New(30*time.Minute, 10*time.Minute),
+		userPromptsCache:     cache.New(30*time.Minute, 10*time.Minute),
 		userCacheTTL:         30 * time.Minute,
 		serverCapsCache:      cache.New(10*time.Minute, 5*time.Minute),
+		userServerConfigs:    cache.New(userServerConfigsTTL, 5*time.Minute),
+		exchangedTokens:      cache.New(5*time.Minute, 10*time.Minute),
+		challenges:           cache.New(time.Hour, 10*time.Minute),
 		serverLocks:          make(map[string]*sync.Mutex),
 		userServerLocks:      make(map[string]*sync.Mutex),
+		userRoots:            make(map[string][]config.RootConfig),
+		subscriptions:        make(map[uuid.UUID]map[string]map[string]bool),
+		resourceWatchers:     make(map[*resourceWatcher]struct{}),
 		clientName:           "go-mcp-host",
 		clientVersion:        config.Version,
 		maxReconnectAttempts: 0,
@@ -108,7 +137,7 @@ func (m *Manager) GetConfiguredServers() []config.MCPServerConfig {
 	return m.serverConfigs
 }
 
-// ListAllToolsForUser returns all tools for all enabled servers, scoped by user (short-lived clients + cache)
+// ListAllToolsForUser returns all tools for all enabled servers of the user, scoped by user (short-lived clients + cache)
 func (m *Manager) ListAllToolsForUser(ctx context.Context, userID uuid.UUID, bearerToken string) ([]ToolWithServer, error) {
 	var (
 		results []ToolWithServer
@@ -116,11 +145,7 @@ func (m *Manager) ListAllToolsForUser(ctx context.Context, userID uuid.UUID, bea
 		wg      sync.WaitGroup
 	)
 
-	for _, server := range m.serverConfigs {
-		if !server.Enabled {
-			continue
-		}
-
+	for _, server := range m.ServersForUser(ctx, userID) {
 		server := server
 		wg.Add(1)
 		go func() {
@@ -168,7 +193,7 @@ func (m *Manager) ListAllToolsForUser(ctx context.Context, userID uuid.UUID, bea
 	return results, nil
 }
 
-// ListAllResourcesForUser returns all resources for all enabled servers, scoped by user (short-lived clients + cache)
+// ListAllResourcesForUser returns all resources for all enabled servers of the user, scoped by user (short-lived clients + cache)
 func (m *Manager) ListAllResourcesForUser(ctx context.Context, userID uuid.UUID, bearerToken string) ([]ResourceWithServer, error) {
 	var (
 		results []ResourceWithServer
@@ -176,11 +201,7 @@ func (m *Manager) ListAllResourcesForUser(ctx context.Context, userID uuid.UUID,
 		wg      sync.WaitGroup
 	)
 
-	for _, server := range m.serverConfigs {
-		if !server.Enabled {
-			continue
-		}
-
+	for _, server := range m.ServersForUser(ctx, userID) {
 		server := server
 		wg.Add(1)
 		go func() {
@@ -234,7 +255,24 @@ func (m *Manager) ProbeServer(
 	serverCfg config.MCPServerConfig,
 	bearerToken string,
 ) (*mcp.ServerCapabilities, error) {
-	if caps, found := m.serverCapsCache.Get(serverCfg.Name); found {
+	return m.ProbeServerForUser(ctx, uuid.Nil, serverCfg, bearerToken)
+}
+
+// ProbeServerForUser performs a short-lived initialize as the user to detect capabilities.
+// Servers the user authorized with OAuth are probed with the user's access token.
+func (m *Manager) ProbeServerForUser(
+	ctx context.Context,
+	userID uuid.UUID,
+	serverCfg config.MCPServerConfig,
+	bearerToken string,
+) (*mcp.ServerCapabilities, error) {
+	auth := m.oauthBindingFor(userID, serverCfg)
+	cacheKey := capsCacheKey(serverCfg)
+	if auth != nil {
+		cacheKey = m.getUserKey(userID, cacheKey)
+	}
+
+	if caps, found := m.serverCapsCache.Get(cacheKey); found {
 		if c, ok := caps.(*mcp.ServerCapabilities); ok {
 			logging.LogDebugf("Using cached capabilities for server %s", serverCfg.Name)
 			return c, nil
@@ -245,14 +283,15 @@ func (m *Manager) ProbeServer(
 	lock.Lock()
 	defer lock.Unlock()
 
-	if caps, found := m.serverCapsCache.Get(serverCfg.Name); found {
+	if caps, found := m.serverCapsCache.Get(cacheKey); found {
 		if c, ok := caps.(*mcp.ServerCapabilities); ok {
 			logging.LogDebugf("Using cached capabilities for server %s", serverCfg.Name)
 			return c, nil
 		}
 	}
 
-	session, initResult, err := m.newInitializedClient(ctx, serverCfg, bearerToken, nil)
+	client := m.newClient(serverCfg, m.rootsFor(serverCfg, userID, uuid.Nil))
+	session, initResult, err := m.newInitializedClient(ctx, client, serverCfg, bearerToken, nil, auth)
 	if err != nil {
 		return nil, err
 	}
@@ -263,7 +302,7 @@ func (m *Manager) ProbeServer(
 	}
 
 	capsCopy := *initResult.Capabilities
-	m.serverCapsCache.Set(serverCfg.Name, &capsCopy, cache.DefaultExpiration)
+	m.serverCapsCache.Set(cacheKey, &capsCopy, cache.DefaultExpiration)
 	return &capsCopy, nil
 }
 
@@ -272,7 +311,8 @@ func (m *Manager) fetchToolsForUser(ctx context.Context, userID uuid.UUID, serve
 	lock.Lock()
 	defer lock.Unlock()
 
-	session, _, err := m.newInitializedClient(ctx, serverCfg, bearerToken, nil)
+	client := m.newClient(serverCfg, m.rootsFor(serverCfg, userID, uuid.Nil))
+	session, _, err := m.newInitializedClient(ctx, client, serverCfg, bearerToken, nil, m.oauthBindingFor(userID, serverCfg))
 	if err != nil {
 		return nil, err
 	}
@@ -295,7 +335,8 @@ func (m *Manager) fetchResourcesForUser(
 	lock.Lock()
 	defer lock.Unlock()
 
-	session, _, err := m.newInitializedClient(ctx, serverCfg, bearerToken, nil)
+	client := m.newClient(serverCfg, m.rootsFor(serverCfg, userID, uuid.Nil))
+	session, _, err := m.newInitializedClient(ctx, client, serverCfg, bearerToken, nil, m.oauthBindingFor(userID, serverCfg))
 	if err != nil {
 		return nil, err
 	}
@@ -310,16 +351,17 @@ func (m *Manager) fetchResourcesForUser(
 
 func (m *Manager) newInitializedClient(
 	ctx context.Context,
+	client *mcp.Client,
 	serverCfg config.MCPServerConfig,
 	bearerToken string,
 	tracker *reconnectTracker,
+	auth *oauthBinding,
 ) (*mcp.ClientSession, *mcp.InitializeResult, error) {
-	trans, err := m.createTransport(serverCfg, bearerToken, tracker)
+	trans, err := m.createTransport(serverCfg, bearerToken, tracker, auth)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	client := m.newClient()
 	session, err := client.Connect(ctx, trans, nil)
 	if err != nil {
 		return nil, nil, err
@@ -342,7 +384,7 @@ func (m *Manager) GetOrCreateSession(
 	bearerToken string,
 	userID uuid.UUID,
 ) (*SessionInfo, error) {
-	if serverConfig.Type == "http" && serverConfig.ForwardBearer {
+	if usesUserBearer(serverConfig) {
 		sessionKey := m.getSessionKey(conversationID, serverConfig.Name)
 		incomingHash := hashToken(bearerToken)
 
@@ -396,7 +438,7 @@ func (m *Manager) GetOrCreateSession(
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if serverConfig.Type != "http" || !serverConfig.ForwardBearer {
+	if !usesUserBearer(serverConfig) {
 		if session, exists := m.sessions[sessionKey]; exists {
 			session.mu.Lock()
 			session.LastAccessed = time.Now()
@@ -417,7 +459,9 @@ func (m *Manager) GetOrCreateSession(
 		tracker = newReconnectTracker(m, conversationID, serverConfig.Name)
 	}
 
-	clientSession, initResult, err := m.newInitializedClient(ctx, serverConfig, bearerToken, tracker)
+	roots := m.rootsFor(serverConfig, userID, conversationID)
+	client := m.newClient(serverConfig, roots)
+	clientSession, initResult, err := m.newInitializedClient(ctx, client, serverConfig, bearerToken, tracker, m.oauthBindingFor(userID, serverConfig))
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create MCP client for server %s", serverConfig.Name)
 	}
@@ -433,6 +477,8 @@ func (m *Manager) GetOrCreateSession(
 		BearerTokenHash:  tokenHash,
 		LastAccessed:     time.Now(),
 		reconnectTracker: tracker,
+		mcpClient:        client,
+		roots:            roots,
 	}
 
 	caps := initResult.Capabilities
@@ -442,6 +488,12 @@ func (m *Manager) GetOrCreateSession(
 	if caps.Resources != nil {
 		go m.refreshResources(context.Background(), session)
 	}
+	if caps.Prompts != nil {
+		go m.refreshPrompts(context.Background(), session)
+	}
+	if caps.Resources != nil && caps.Resources.Subscribe {
+		go m.resubscribe(context.Background(), session)
+	}
 
 	m.sessions[sessionKey] = session
 	m.sessionIndex[session.Client] = session
@@ -495,8 +547,11 @@ func (m *Manager) CloseSession(conversationID uuid.UUID, serverName string) erro
 	return nil
 }
 
-// CloseAllSessionsForConversation closes all sessions for a conversation
+// CloseAllSessionsForConversation closes all sessions for a conversation and drops its
+// resource subscriptions
 func (m *Manager) CloseAllSessionsForConversation(conversationID uuid.UUID) error {
+	m.dropSubscriptions(conversationID)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -683,6 +738,14 @@ func (m *Manager) handleResourceUpdated(ctx context.Context, req *mcp.ResourceUp
 		return
 	}
 	go m.refreshResources(context.Background(), session)
+
+	if req.Params != nil {
+		m.notifyResourceUpdated(ResourceUpdate{
+			ConversationID: session.ConversationID,
+			ServerName:     session.ServerName,
+			URI:            req.Params.URI,
+		})
+	}
 }
 
 func (m *Manager) findSessionByClientSession(session mcp.Session) *SessionInfo {
@@ -696,17 +759,29 @@ func (m *Manager) findSessionByClientSession(session mcp.Session) *SessionInfo {
 	return m.sessionIndex[clientSession]
 }
 
-func (m *Manager) newClient() *mcp.Client {
+func (m *Manager) newClient(serverCfg config.MCPServerConfig, roots []*mcp.Root) *mcp.Client {
 	impl := &mcp.Implementation{
 		Name:    m.clientName,
 		Version: m.clientVersion,
 	}
 
-	return mcp.NewClient(impl, &mcp.ClientOptions{
+	opts := &mcp.ClientOptions{
 		ToolListChangedHandler:     m.handleToolListChanged,
 		ResourceListChangedHandler: m.handleResourceListChanged,
 		ResourceUpdatedHandler:     m.handleResourceUpdated,
-	})
+		PromptListChangedHandler:   m.handlePromptListChanged,
+	}
+	// The sampling capability is only advertised to servers that opted in
+	if serverCfg.SamplingEnabled() && m.samplingClient != nil {
+		opts.CreateMessageHandler = m.handleCreateMessage
+	}
+	if m.getElicitationHandler() != nil {
+		opts.ElicitationHandler = m.handleElicit
+	}
+
+	client := mcp.NewClient(impl, opts)
+	client.AddRoots(roots...)
+	return client
 }
 
 func (m *Manager) cleanupLoop() {
@@ -784,8 +859,10 @@ func (m *Manager) GetCacheStats() map[string]interface{} {
 	return map[string]interface{}{
 		"tools_cache_items":     m.userToolsCache.ItemCount(),
 		"resources_cache_items": m.userResourcesCache.ItemCount(),
+		"prompts_cache_items":   m.userPromptsCache.ItemCount(),
 		"tools_cache_keys":      m.userToolsCache.Items(),
 		"resources_cache_keys":  m.userResourcesCache.Items(),
+		"prompts_cache_keys":    m.userPromptsCache.Items(),
 	}
 }
 
@@ -805,6 +882,7 @@ func (m *Manager) createTransport(
 	serverCfg config.MCPServerConfig,
 	bearerToken string,
 	tracker *reconnectTracker,
+	auth *oauthBinding,
 ) (mcp.Transport, error) {
 	switch serverCfg.Type {
 	case "stdio":
@@ -825,10 +903,14 @@ func (m *Manager) createTransport(
 			mode = config.HTTPServerModeBatch
 		}
 		headers := cloneHeaders(serverCfg.Headers)
-		if serverCfg.ForwardBearer && bearerToken != "" {
+		switch {
+		case serverCfg.TokenExchange != nil:
+			// Exchanged per request, so that sessions outlive the exchanged tokens
+			auth = m.tokenExchangeBinding(serverCfg, bearerToken)
+		case serverCfg.ForwardBearer && bearerToken != "":
 			headers["Authorization"] = "Bearer " + bearerToken
 		}
-		httpClient := m.newHTTPClient(headers, tracker)
+		httpClient := m.newHTTPClient(m.userServerTransport(serverCfg.Name), headers, tracker, auth)
 
 		switch mode {
 		case config.HTTPServerModeBatch:
@@ -877,9 +959,12 @@ func cloneHeaders(headers map[string]string) map[string]string {
 	return cloned
 }
 
-func (m *Manager) newHTTPClient(headers map[string]string, tracker *reconnectTracker) *http.Client {
-	needsInstrumentation := len(headers) > 0 || tracker != nil
+func (m *Manager) newHTTPClient(base http.RoundTripper, headers map[string]string, tracker *reconnectTracker, auth *oauthBinding) *http.Client {
+	needsInstrumentation := len(headers) > 0 || tracker != nil || auth != nil
 	if !needsInstrumentation {
+		if base != nil {
+			return &http.Client{Transport: base}
+		}
 		return http.DefaultClient
 	}
 
@@ -894,7 +979,6 @@ func (m *Manager) newHTTPClient(headers map[string]string, tracker *reconnectTra
 		headerValues.Add(k, v)
 	}
 
-	base := http.DefaultTransport
 	if base == nil {
 		base = http.DefaultTransport
 	}
@@ -904,6 +988,7 @@ func (m *Manager) newHTTPClient(headers map[string]string, tracker *reconnectTra
 			base:    base,
 			headers: headerValues,
 			tracker: tracker,
+			auth:    auth,
 		},
 	}
 }
@@ -912,15 +997,22 @@ type instrumentedRoundTripper struct {
 	base    http.RoundTripper
 	headers http.Header
 	tracker *reconnectTracker
+	auth    *oauthBinding
 }
 
 func (rt *instrumentedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	r := req
-	if len(rt.headers) > 0 {
+	if len(rt.headers) > 0 || rt.auth != nil {
 		r = req.Clone(req.Context())
 		if r.Header == nil {
 			r.Header = make(http.Header)
 		}
+		// OAuth access tokens take precedence over a configured Authorization header
+		if rt.auth != nil {
+			if err := rt.auth.authorize(r); err != nil {
+				return nil, errors.Wrap(err, "failed to authorize MCP request")
+			}
+		}
 		for k, values := range rt.headers {
 			if r.Header.Get(k) != "" {
 				continue
@@ -932,6 +1024,9 @@ func (rt *instrumentedRoundTripper) RoundTrip(req *http.Request) (*http.Response
 	}
 
 	resp, err := rt.base.RoundTrip(r)
+	if err == nil && rt.auth != nil {
+		rt.auth.observe(resp)
+	}
 	if err != nil && rt.tracker != nil && r.Method == http.MethodGet {
 		go rt.tracker.handleListenFailure(err.Error())
 	}
@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// OAuthProvider supplies access tokens that users obtained for HTTP MCP servers through
+// the MCP authorization flow
+type OAuthProvider interface {
+	// AccessToken returns a valid access token of the user for the server, refreshing it
+	// when needed, or "" when the user has not authorized the server
+	AccessToken(ctx context.Context, userID uuid.UUID, server config.MCPServerConfig) (string, error)
+}
+
+// OAuthServerTransport is implemented by OAuth providers that make requests on behalf of an
+// MCP server, such as discovery and token requests; the manager makes them use the
+// restricted transport of user-registered servers (see UserServerTransport)
+type OAuthServerTransport interface {
+	SetServerTransport(transport func(serverName string) http.RoundTripper)
+}
+
+// WithOAuth injects the users' OAuth access tokens into requests to HTTP servers and
+// records the authorization challenges of servers that reject a request
+func WithOAuth(provider OAuthProvider) Option {
+	return func(m *Manager) {
+		m.oauth = provider
+		if t, ok := provider.(OAuthServerTransport); ok {
+			t.SetServerTransport(m.userServerTransport)
+		}
+	}
+}
+
+// OAuth returns the OAuth provider, or nil when there is none
+func (m *Manager) OAuth() OAuthProvider {
+	return m.oauth
+}
+
+// AuthorizationChallenge returns the WWW-Authenticate header of the last request of the
+// user that the server rejected with 401, if the user has not authorized it since
+func (m *Manager) AuthorizationChallenge(userID uuid.UUID, serverName string) (string, bool) {
+	challenge, found := m.challenges.Get(m.getUserKey(userID, serverName))
+	if !found {
+		return "", false
+	}
+	header, ok := challenge.(string)
+	return header, ok
+}
+
+// AuthorizationChanged forgets the server's challenge for the user and drops the user's
+// cached lists and sessions with it, so that they are re-created with the new token
+func (m *Manager) AuthorizationChanged(userID uuid.UUID, server config.MCPServerConfig) {
+	m.challenges.Delete(m.getUserKey(userID, server.Name))
+	m.InvalidateUserServer(userID, server)
+}
+
+// oauthBinding supplies the access tokens of a transport: the user's tokens obtained with
+// the authorization flow, or exchanged tokens (see tokenExchangeBinding)
+type oauthBinding struct {
+	token       func(ctx context.Context) (string, error)
+	onChallenge func(header string)
+}
+
+// oauthBindingFor returns the OAuth binding of a transport, or nil when requests of the
+// user to the server are not authorized with OAuth
+func (m *Manager) oauthBindingFor(userID uuid.UUID, server config.MCPServerConfig) *oauthBinding {
+	if m.oauth == nil || userID == uuid.Nil || server.Type != "http" || usesUserBearer(server) {
+		return nil
+	}
+	key := m.getUserKey(userID, server.Name)
+	return &oauthBinding{
+		token: func(ctx context.Context) (string, error) {
+			return m.oauth.AccessToken(ctx, userID, server)
+		},
+		onChallenge: func(header string) {
+			m.challenges.SetDefault(key, header)
+			logging.LogDebugf("MCP server %s requires authorization for user %s", server.Name, userID)
+		},
+	}
+}
+
+// authorize sets the bearer token of the binding on the request
+func (b *oauthBinding) authorize(req *http.Request) error {
+	token, err := b.token(req.Context())
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// observe records the challenge of a rejected request
+func (b *oauthBinding) observe(resp *http.Response) {
+	if b.onChallenge == nil || resp.StatusCode != http.StatusUnauthorized {
+		return
+	}
+	if header := resp.Header.Get("WWW-Authenticate"); header != "" {
+		b.onChallenge(header)
+	}
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+)
+
+// staticOAuth returns a fixed access token for one user
+type staticOAuth struct {
+	userID uuid.UUID
+	token  string
+}
+
+func (s staticOAuth) AccessToken(_ context.Context, userID uuid.UUID, _ config.MCPServerConfig) (string, error) {
+	if userID != s.userID {
+		return "", nil
+	}
+	return s.token, nil
+}
+
+func TestOAuth_InjectsTokenAndRecordsChallenge(t *testing.T) {
+	const challenge = `Bearer resource_metadata="https://notes.example.com/.well-known/oauth-protected-resource"`
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			w.Header().Set("WWW-Authenticate", challenge)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	serverCfg := config.MCPServerConfig{
+		Name:    "notes",
+		Type:    "http",
+		Mode:    config.HTTPServerModeBatch,
+		URL:     httpServer.URL,
+		Enabled: true,
+		Headers: map[string]string{"Authorization": "Bearer static"},
+	}
+	authorized := uuid.New()
+	m := NewMCPManager([]config.MCPServerConfig{serverCfg}, WithOAuth(staticOAuth{userID: authorized, token: "user-token"}))
+	ctx := context.Background()
+
+	// The user's token replaces the configured Authorization header
+	_, err := m.ProbeServerForUser(ctx, authorized, serverCfg, "")
+	require.NoError(t, err)
+	_, found := m.AuthorizationChallenge(authorized, "notes")
+	assert.False(t, found)
+
+	// Users without a token are rejected and the challenge is kept for the authorization flow
+	other := uuid.New()
+	_, err = m.ProbeServerForUser(ctx, other, serverCfg, "")
+	require.Error(t, err)
+	header, found := m.AuthorizationChallenge(other, "notes")
+	require.True(t, found)
+	assert.Equal(t, challenge, header)
+
+	m.AuthorizationChanged(other, serverCfg)
+	_, found = m.AuthorizationChallenge(other, "notes")
+	assert.False(t, found)
+}
+
+// transportOAuth records the server transport the manager sets
+type transportOAuth struct {
+	staticOAuth
+	transport func(serverName string) http.RoundTripper
+}
+
+func (s *transportOAuth) SetServerTransport(transport func(serverName string) http.RoundTripper) {
+	s.transport = transport
+}
+
+// guardedUserServers restricts user servers to a transport
+type guardedUserServers struct {
+	staticUserServers
+	transport http.RoundTripper
+}
+
+func (s guardedUserServers) Transport() http.RoundTripper {
+	return s.transport
+}
+
+func TestWithOAuth_UsesUserServerTransport(t *testing.T) {
+	guarded := &http.Transport{}
+	provider := &transportOAuth{}
+	NewMCPManager(
+		[]config.MCPServerConfig{{Name: "files", Type: "http", URL: "https://files.example.com/mcp", Enabled: true}},
+		WithOAuth(provider),
+		WithUserServers(guardedUserServers{transport: guarded}),
+	)
+
+	require.NotNil(t, provider.transport)
+	assert.Same(t, guarded, provider.transport("notes"))
+	assert.Nil(t, provider.transport("files"))
+}
@@ -0,0 +1,159 @@
+package manager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pkg/errors"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// PromptWithServer associates a prompt with its server
+type PromptWithServer struct {
+	Prompt     *mcp.Prompt
+	ServerName string
+}
+
+// ListAllPromptsForUser returns all prompts for all enabled servers of the user, scoped by user (short-lived clients + cache)
+func (m *Manager) ListAllPromptsForUser(ctx context.Context, userID uuid.UUID, bearerToken string) ([]PromptWithServer, error) {
+	var (
+		results []PromptWithServer
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+
+	for _, server := range m.ServersForUser(ctx, userID) {
+		server := server
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			key := m.getUserKey(userID, server.Name)
+
+			prompts, found := m.userPromptsCache.Get(key)
+			promptList, ok := prompts.([]*mcp.Prompt)
+			if found && ok {
+				logging.LogDebugf("Using cached prompts for user %s server %s: %d prompts", userID, server.Name, len(promptList))
+			} else {
+				logging.LogDebugf("Fetching fresh prompts for user %s server %s", userID, server.Name)
+				fetched, err := m.fetchPromptsForUser(ctx, userID, server, bearerToken)
+				if err != nil {
+					logging.LogWarningf(err, "Failed to fetch prompts for server %s", server.Name)
+					return
+				}
+				m.userPromptsCache.Set(key, fetched, m.userCacheTTL)
+				logging.LogDebugf("Cached prompts for user %s server %s: %d prompts", userID, server.Name, len(fetched))
+				promptList = fetched
+			}
+
+			mu.Lock()
+			for _, p := range promptList {
+				if p == nil {
+					continue
+				}
+				results = append(results, PromptWithServer{Prompt: p, ServerName: server.Name})
+			}
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return results, nil
+}
+
+// GetPrompt renders a prompt of a server with the given arguments (short-lived client)
+func (m *Manager) GetPrompt(
+	ctx context.Context,
+	userID uuid.UUID,
+	bearerToken string,
+	serverName, promptName string,
+	arguments map[string]string,
+) (*mcp.GetPromptResult, error) {
+	serverCfg, ok := m.GetServerConfigForUser(ctx, userID, serverName)
+	if !ok {
+		return nil, errors.Errorf("unknown MCP server %s", serverName)
+	}
+
+	client := m.newClient(serverCfg, m.rootsFor(serverCfg, userID, uuid.Nil))
+	session, _, err := m.newInitializedClient(ctx, client, serverCfg, bearerToken, nil, m.oauthBindingFor(userID, serverCfg))
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result, err := session.GetPrompt(ctx, &mcp.GetPromptParams{
+		Name:      promptName,
+		Arguments: arguments,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get prompt %s from server %s", promptName, serverName)
+	}
+	return result, nil
+}
+
+func (m *Manager) fetchPromptsForUser(
+	ctx context.Context,
+	userID uuid.UUID,
+	serverCfg config.MCPServerConfig,
+	bearerToken string,
+) ([]*mcp.Prompt, error) {
+	lock := m.getUserServerLock(userID, serverCfg.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	client := m.newClient(serverCfg, m.rootsFor(serverCfg, userID, uuid.Nil))
+	session, initResult, err := m.newInitializedClient(ctx, client, serverCfg, bearerToken, nil, m.oauthBindingFor(userID, serverCfg))
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	// Servers without the prompts capability would reject prompts/list
+	if initResult.Capabilities == nil || initResult.Capabilities.Prompts == nil {
+		return []*mcp.Prompt{}, nil
+	}
+
+	result, err := session.ListPrompts(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Prompts, nil
+}
+
+func (m *Manager) refreshPrompts(ctx context.Context, session *SessionInfo) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	logging.LogDebugf("Refreshing prompts for server %s", session.ServerName)
+	result, err := session.Client.ListPrompts(ctx, nil)
+	if err != nil {
+		logging.LogErrorf(err, "Failed to refresh prompts for server %s", session.ServerName)
+		return
+	}
+
+	var prompts []*mcp.Prompt
+	if result != nil {
+		prompts = result.Prompts
+	}
+	if session.UserID != uuid.Nil {
+		m.userPromptsCache.Set(m.getUserKey(session.UserID, session.ServerName), prompts, m.userCacheTTL)
+	}
+	logging.LogDebugf("Refreshed prompts for server %s: %d prompts", session.ServerName, len(prompts))
+}
+
+func (m *Manager) handlePromptListChanged(ctx context.Context, req *mcp.PromptListChangedRequest) {
+	if req == nil {
+		return
+	}
+	session := m.findSessionByClientSession(req.GetSession())
+	if session == nil {
+		return
+	}
+	go m.refreshPrompts(context.Background(), session)
+}
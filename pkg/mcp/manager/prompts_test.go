@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+)
+
+func TestPrompts_ListCachedPerUserAndGet(t *testing.T) {
+	var httpRequests int32
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddPrompt(&mcp.Prompt{
+		Name:      "summarize",
+		Arguments: []*mcp.PromptArgument{{Name: "topic", Required: true}},
+	}, func(_ context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return &mcp.GetPromptResult{Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: "Summarize " + req.Params.Arguments["topic"]}},
+		}}, nil
+	})
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&httpRequests, 1)
+		handler.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	m := NewMCPManager([]config.MCPServerConfig{{
+		Name:    "docs",
+		Type:    "http",
+		Mode:    config.HTTPServerModeBatch,
+		URL:     httpServer.URL,
+		Enabled: true,
+	}})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	prompts, err := m.ListAllPromptsForUser(ctx, userID, "")
+	require.NoError(t, err)
+	require.Len(t, prompts, 1)
+	assert.Equal(t, "summarize", prompts[0].Prompt.Name)
+	assert.Equal(t, "docs", prompts[0].ServerName)
+
+	// The second listing is served from the per-user cache
+	requests := atomic.LoadInt32(&httpRequests)
+	prompts, err = m.ListAllPromptsForUser(ctx, userID, "")
+	require.NoError(t, err)
+	require.Len(t, prompts, 1)
+	assert.Equal(t, requests, atomic.LoadInt32(&httpRequests))
+
+	result, err := m.GetPrompt(ctx, userID, "", "docs", "summarize", map[string]string{"topic": "MCP"})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, "Summarize MCP", result.Messages[0].Content.(*mcp.TextContent).Text)
+
+	_, err = m.GetPrompt(ctx, userID, "", "unknown", "summarize", nil)
+	assert.Error(t, err)
+}
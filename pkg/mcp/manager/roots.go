@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// SetUserRoots replaces the runtime roots of a user for one server. They are advertised in
+// addition to the server's configured roots and may use the same placeholders. Active
+// sessions of the user are updated and notify their server with roots/list_changed.
+func (m *Manager) SetUserRoots(userID uuid.UUID, serverName string, roots []config.RootConfig) {
+	key := m.getUserKey(userID, serverName)
+
+	m.rootsMu.Lock()
+	if len(roots) == 0 {
+		delete(m.userRoots, key)
+	} else {
+		m.userRoots[key] = append([]config.RootConfig(nil), roots...)
+	}
+	m.rootsMu.Unlock()
+
+	m.mu.RLock()
+	var affected []*SessionInfo
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.ServerName == serverName {
+			affected = append(affected, session)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, session := range affected {
+		m.updateSessionRoots(session)
+	}
+}
+
+// rootsFor expands the configured and runtime roots of a server for one user and conversation.
+// Roots that need an ID that is not known (uuid.Nil) are left out.
+func (m *Manager) rootsFor(serverCfg config.MCPServerConfig, userID, conversationID uuid.UUID) []*mcp.Root {
+	var userValue, conversationValue string
+	if userID != uuid.Nil {
+		userValue = userID.String()
+	}
+	if conversationID != uuid.Nil {
+		conversationValue = conversationID.String()
+	}
+
+	templates := append([]config.RootConfig(nil), serverCfg.Roots...)
+	if userID != uuid.Nil {
+		m.rootsMu.RLock()
+		templates = append(templates, m.userRoots[m.getUserKey(userID, serverCfg.Name)]...)
+		m.rootsMu.RUnlock()
+	}
+
+	var roots []*mcp.Root
+	seen := make(map[string]bool)
+	for _, template := range templates {
+		root, ok := template.Expand(userValue, conversationValue)
+		if !ok || root.URI == "" || seen[root.URI] {
+			continue
+		}
+		seen[root.URI] = true
+		roots = append(roots, &mcp.Root{URI: root.URI, Name: root.Name})
+	}
+	return roots
+}
+
+// updateSessionRoots brings the roots of a session's client in line with the current
+// configuration. The client sends roots/list_changed for every change.
+func (m *Manager) updateSessionRoots(session *SessionInfo) {
+	roots := m.rootsFor(session.ServerConfig, session.UserID, session.ConversationID)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.mcpClient == nil {
+		return
+	}
+
+	current := make(map[string]*mcp.Root, len(session.roots))
+	for _, root := range session.roots {
+		current[root.URI] = root
+	}
+
+	var added []*mcp.Root
+	for _, root := range roots {
+		if existing, ok := current[root.URI]; !ok || existing.Name != root.Name {
+			added = append(added, root)
+		}
+		delete(current, root.URI)
+	}
+	removed := make([]string, 0, len(current))
+	for uri := range current {
+		removed = append(removed, uri)
+	}
+
+	if len(removed) > 0 {
+		session.mcpClient.RemoveRoots(removed...)
+	}
+	if len(added) > 0 {
+		session.mcpClient.AddRoots(added...)
+	}
+	session.roots = roots
+
+	if len(added) > 0 || len(removed) > 0 {
+		logging.LogDebugf("Updated roots for session %s server %s: %d added, %d removed",
+			session.SessionID, session.ServerName, len(added), len(removed))
+	}
+}
@@ -0,0 +1,78 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+)
+
+func TestRoots_TemplatedPerSessionAndUpdatedAtRuntime(t *testing.T) {
+	ctx := context.Background()
+	userID, conversationID := uuid.New(), uuid.New()
+	serverCfg := config.MCPServerConfig{
+		Name: "files",
+		Roots: []config.RootConfig{
+			{URI: "file:///shared", Name: "Shared"},
+			{URI: "file:///home/{userId}", Name: "Home"},
+			{URI: "file:///tmp/{conversationId}"},
+		},
+	}
+	m := NewMCPManager([]config.MCPServerConfig{serverCfg})
+
+	// Short-lived clients without a conversation only get the roots they can resolve
+	roots := m.rootsFor(serverCfg, userID, uuid.Nil)
+	require.Len(t, roots, 2)
+	assert.Equal(t, "file:///home/"+userID.String(), roots[1].URI)
+
+	changed := make(chan struct{}, 4)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, &mcp.ServerOptions{
+		RootsListChangedHandler: func(context.Context, *mcp.RootsListChangedRequest) { changed <- struct{}{} },
+	})
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+
+	roots = m.rootsFor(serverCfg, userID, conversationID)
+	client := m.newClient(serverCfg, roots)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = clientSession.Close() })
+
+	m.sessions[m.getSessionKey(conversationID, serverCfg.Name)] = &SessionInfo{
+		Client:         clientSession,
+		ConversationID: conversationID,
+		UserID:         userID,
+		ServerName:     serverCfg.Name,
+		ServerConfig:   serverCfg,
+		mcpClient:      client,
+		roots:          roots,
+	}
+
+	listed, err := serverSession.ListRoots(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, listed.Roots, 3)
+	assert.Equal(t, "file:///tmp/"+conversationID.String(), listed.Roots[2].URI)
+
+	m.SetUserRoots(userID, serverCfg.Name, []config.RootConfig{{URI: "file:///projects/{userId}", Name: "Projects"}})
+
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server was not notified about changed roots")
+	}
+	listed, err = serverSession.ListRoots(ctx, nil)
+	require.NoError(t, err)
+	uris := make([]string, 0, len(listed.Roots))
+	for _, root := range listed.Roots {
+		uris = append(uris, root.URI)
+	}
+	assert.Contains(t, uris, "file:///projects/"+userID.String())
+	assert.Len(t, uris, 4)
+}
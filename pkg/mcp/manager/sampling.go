@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pkg/errors"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// defaultSamplingMaxTokens caps sampling completions when the server config sets no limit
+const defaultSamplingMaxTokens = 1024
+
+var (
+	// ErrSamplingDisabled is returned to servers that request sampling without being allowed to
+	ErrSamplingDisabled = errors.New("sampling is not enabled for this server")
+
+	// ErrSamplingDenied is returned to servers when the sampling approver rejects a request
+	ErrSamplingDenied = errors.New("sampling request denied")
+)
+
+// SamplingRequest describes a sampling/createMessage request from an MCP server
+type SamplingRequest struct {
+	UserID         uuid.UUID
+	ConversationID uuid.UUID
+	ServerName     string
+	Params         *mcp.CreateMessageParams
+}
+
+// SamplingApprover decides whether a sampling request may be sent to the LLM.
+// Returning an error denies the request.
+type SamplingApprover func(ctx context.Context, req SamplingRequest) error
+
+// WithSampling routes sampling requests of servers that opt in (MCPServerConfig.Sampling)
+// to the given LLM client. approver may be nil to allow all requests.
+func WithSampling(client llm.Client, approver SamplingApprover) Option {
+	return func(m *Manager) {
+		m.samplingClient = client
+		m.samplingApprover = approver
+	}
+}
+
+func (m *Manager) handleCreateMessage(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	if req == nil || req.Params == nil {
+		return nil, errors.New("missing sampling parameters")
+	}
+	session := m.findSessionByClientSession(req.GetSession())
+	if session == nil || !session.ServerConfig.SamplingEnabled() || m.samplingClient == nil {
+		return nil, ErrSamplingDisabled
+	}
+
+	if m.samplingApprover != nil {
+		err := m.samplingApprover(ctx, SamplingRequest{
+			UserID:         session.UserID,
+			ConversationID: session.ConversationID,
+			ServerName:     session.ServerName,
+			Params:         req.Params,
+		})
+		if err != nil {
+			logging.LogDebugf("Sampling denied: server=%s user=%s: %v", session.ServerName, session.UserID, err)
+			return nil, errors.Wrap(ErrSamplingDenied, err.Error())
+		}
+	}
+
+	chatRequest := buildSamplingChatRequest(*session.ServerConfig.Sampling, req.Params)
+
+	logging.LogDebugf("Sampling for server %s: model=%s messages=%d max_tokens=%d",
+		session.ServerName, chatRequest.Model, len(chatRequest.Messages), *chatRequest.MaxTokens)
+
+	response, err := m.samplingClient.Chat(ctx, chatRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "sampling completion failed")
+	}
+
+	return &mcp.CreateMessageResult{
+		Content:    &mcp.TextContent{Text: response.Message.Content},
+		Model:      response.Model,
+		Role:       "assistant",
+		StopReason: "endTurn",
+	}, nil
+}
+
+// buildSamplingChatRequest converts sampling parameters into an LLM request, applying the
+// server's model mapping and token limit
+func buildSamplingChatRequest(cfg config.SamplingConfig, params *mcp.CreateMessageParams) llm.ChatRequest {
+	messages := make([]llm.Message, 0, len(params.Messages)+1)
+	if params.SystemPrompt != "" {
+		messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: params.SystemPrompt})
+	}
+	for _, msg := range params.Messages {
+		if msg == nil {
+			continue
+		}
+		messages = append(messages, llm.Message{
+			Role:    string(msg.Role),
+			Content: llm.ConvertMCPContentToString([]mcp.Content{msg.Content}),
+		})
+	}
+
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultSamplingMaxTokens
+	}
+	if params.MaxTokens > 0 && params.MaxTokens < int64(maxTokens) {
+		maxTokens = int(params.MaxTokens)
+	}
+
+	request := llm.ChatRequest{
+		Model:     selectSamplingModel(cfg, params.ModelPreferences),
+		Messages:  messages,
+		MaxTokens: &maxTokens,
+		Stop:      params.StopSequences,
+	}
+	if params.Temperature > 0 {
+		temperature := params.Temperature
+		request.Temperature = &temperature
+	}
+	return request
+}
+
+// selectSamplingModel maps the server's model hints to a host model. Hints are evaluated
+// in order; for each hint the longest configured key contained in it wins.
+func selectSamplingModel(cfg config.SamplingConfig, prefs *mcp.ModelPreferences) string {
+	if prefs != nil && len(cfg.Models) > 0 {
+		keys := make([]string, 0, len(cfg.Models))
+		for key := range cfg.Models {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) > len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+
+		for _, hint := range prefs.Hints {
+			if hint == nil || hint.Name == "" {
+				continue
+			}
+			for _, key := range keys {
+				if strings.Contains(hint.Name, key) {
+					return cfg.Models[key]
+				}
+			}
+		}
+	}
+	return cfg.DefaultModel
+}
@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+)
+
+type fakeLLM struct {
+	requests []llm.ChatRequest
+}
+
+func (f *fakeLLM) Chat(_ context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	f.requests = append(f.requests, req)
+	return &llm.ChatResponse{
+		Model:   req.Model,
+		Message: llm.Message{Role: llm.RoleAssistant, Content: "summary"},
+	}, nil
+}
+
+func (f *fakeLLM) ChatStream(context.Context, llm.ChatRequest) (<-chan llm.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeLLM) ListModels(context.Context) ([]llm.Model, error) {
+	return nil, nil
+}
+
+// connectSamplingSession connects a manager client to an in-memory MCP server and
+// registers it as a session so that server-initiated requests can be routed.
+func connectSamplingSession(t *testing.T, m *Manager, serverCfg config.MCPServerConfig) *mcp.ServerSession {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+
+	clientSession, err := m.newClient(serverCfg, nil).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = clientSession.Close() })
+
+	m.sessionIndex[clientSession] = &SessionInfo{
+		Client:       clientSession,
+		UserID:       uuid.New(),
+		ServerName:   serverCfg.Name,
+		ServerConfig: serverCfg,
+	}
+	return serverSession
+}
+
+func TestSampling_RoutesToLLMWithModelMappingAndTokenCap(t *testing.T) {
+	client := &fakeLLM{}
+	m := NewMCPManager(nil, WithSampling(client, nil))
+	serverSession := connectSamplingSession(t, m, config.MCPServerConfig{
+		Name: "notes",
+		Sampling: &config.SamplingConfig{
+			Enabled:   true,
+			Models:    map[string]string{"claude": "claude-sonnet-4-5", "claude-3-haiku": "claude-haiku-4-5"},
+			MaxTokens: 200,
+		},
+	})
+
+	result, err := serverSession.CreateMessage(context.Background(), &mcp.CreateMessageParams{
+		SystemPrompt: "Summarize",
+		Messages:     []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: "long text"}}},
+		MaxTokens:    1000,
+		ModelPreferences: &mcp.ModelPreferences{
+			Hints: []*mcp.ModelHint{{Name: "gpt-4o"}, {Name: "claude-3-haiku-20240307"}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "summary", result.Content.(*mcp.TextContent).Text)
+	assert.Equal(t, "claude-haiku-4-5", result.Model)
+	require.Len(t, client.requests, 1)
+	req := client.requests[0]
+	assert.Equal(t, 200, *req.MaxTokens)
+	require.Len(t, req.Messages, 2)
+	assert.Equal(t, llm.RoleSystem, req.Messages[0].Role)
+	assert.Equal(t, "long text", req.Messages[1].Content)
+}
+
+func TestSampling_RequiresOptInAndApproval(t *testing.T) {
+	client := &fakeLLM{}
+	m := NewMCPManager(nil, WithSampling(client, func(context.Context, SamplingRequest) error {
+		return errors.New("user has no sampling quota")
+	}))
+
+	// Not advertised without opt-in
+	serverSession := connectSamplingSession(t, m, config.MCPServerConfig{Name: "plain"})
+	_, err := serverSession.CreateMessage(context.Background(), &mcp.CreateMessageParams{MaxTokens: 10})
+	assert.Error(t, err)
+
+	serverSession = connectSamplingSession(t, m, config.MCPServerConfig{
+		Name:     "notes",
+		Sampling: &config.SamplingConfig{Enabled: true},
+	})
+	_, err = serverSession.CreateMessage(context.Background(), &mcp.CreateMessageParams{
+		Messages:  []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: "hi"}}},
+		MaxTokens: 10,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no sampling quota")
+	assert.Empty(t, client.requests)
+}
@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pkg/errors"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// ErrSubscriptionsNotSupported is returned when a server does not offer resources/subscribe
+var ErrSubscriptionsNotSupported = errors.New("server does not support resource subscriptions")
+
+// ResourceSubscription is a resource URI a conversation is subscribed to
+type ResourceSubscription struct {
+	ServerName string `json:"server"`
+	URI        string `json:"uri"`
+}
+
+// ResourceUpdate reports that a subscribed resource changed
+type ResourceUpdate struct {
+	ConversationID uuid.UUID
+	ServerName     string
+	URI            string
+}
+
+// resourceWatcher receives the resource updates of one conversation
+type resourceWatcher struct {
+	conversationID uuid.UUID
+	fn             func(ResourceUpdate)
+}
+
+// SubscribeResource subscribes a conversation to updates of a resource. A session with the
+// server is created if needed. The subscription is kept by the manager and renewed whenever
+// the conversation's session with the server is re-created.
+func (m *Manager) SubscribeResource(
+	ctx context.Context,
+	conversationID, userID uuid.UUID,
+	bearerToken, serverName, uri string,
+) error {
+	serverCfg, ok := m.GetServerConfigForUser(ctx, userID, serverName)
+	if !ok {
+		return errors.Errorf("unknown MCP server %s", serverName)
+	}
+
+	session, err := m.GetOrCreateSession(ctx, conversationID, serverCfg, bearerToken, userID)
+	if err != nil {
+		return err
+	}
+	if !supportsSubscriptions(session.Client) {
+		return ErrSubscriptionsNotSupported
+	}
+
+	if err := session.Client.Subscribe(ctx, &mcp.SubscribeParams{URI: uri}); err != nil {
+		return errors.Wrapf(err, "failed to subscribe to %s on server %s", uri, serverName)
+	}
+
+	m.subscriptionsMu.Lock()
+	servers, ok := m.subscriptions[conversationID]
+	if !ok {
+		servers = make(map[string]map[string]bool)
+		m.subscriptions[conversationID] = servers
+	}
+	if servers[serverName] == nil {
+		servers[serverName] = make(map[string]bool)
+	}
+	servers[serverName][uri] = true
+	m.subscriptionsMu.Unlock()
+
+	logging.LogDebugf("Subscribed conversation %s to %s on server %s", conversationID, uri, serverName)
+	return nil
+}
+
+// UnsubscribeResource removes a subscription of a conversation
+func (m *Manager) UnsubscribeResource(ctx context.Context, conversationID uuid.UUID, serverName, uri string) error {
+	m.subscriptionsMu.Lock()
+	delete(m.subscriptions[conversationID][serverName], uri)
+	if len(m.subscriptions[conversationID][serverName]) == 0 {
+		delete(m.subscriptions[conversationID], serverName)
+	}
+	if len(m.subscriptions[conversationID]) == 0 {
+		delete(m.subscriptions, conversationID)
+	}
+	m.subscriptionsMu.Unlock()
+
+	session, exists := m.GetSession(conversationID, serverName)
+	if !exists {
+		return nil
+	}
+	if err := session.Client.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: uri}); err != nil {
+		return errors.Wrapf(err, "failed to unsubscribe from %s on server %s", uri, serverName)
+	}
+	return nil
+}
+
+// ListResourceSubscriptions returns the subscriptions of a conversation
+func (m *Manager) ListResourceSubscriptions(conversationID uuid.UUID) []ResourceSubscription {
+	m.subscriptionsMu.RLock()
+	defer m.subscriptionsMu.RUnlock()
+
+	subscriptions := []ResourceSubscription{}
+	for serverName, uris := range m.subscriptions[conversationID] {
+		for uri := range uris {
+			subscriptions = append(subscriptions, ResourceSubscription{ServerName: serverName, URI: uri})
+		}
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].ServerName != subscriptions[j].ServerName {
+			return subscriptions[i].ServerName < subscriptions[j].ServerName
+		}
+		return subscriptions[i].URI < subscriptions[j].URI
+	})
+	return subscriptions
+}
+
+// WatchResourceUpdates calls fn for every update of a resource the conversation is
+// subscribed to, until the returned function is called
+func (m *Manager) WatchResourceUpdates(conversationID uuid.UUID, fn func(ResourceUpdate)) func() {
+	watcher := &resourceWatcher{conversationID: conversationID, fn: fn}
+
+	m.subscriptionsMu.Lock()
+	m.resourceWatchers[watcher] = struct{}{}
+	m.subscriptionsMu.Unlock()
+
+	return func() {
+		m.subscriptionsMu.Lock()
+		delete(m.resourceWatchers, watcher)
+		m.subscriptionsMu.Unlock()
+	}
+}
+
+// notifyResourceUpdated fans an update out to the watchers of the conversation
+func (m *Manager) notifyResourceUpdated(update ResourceUpdate) {
+	m.subscriptionsMu.RLock()
+	var watchers []*resourceWatcher
+	for watcher := range m.resourceWatchers {
+		if watcher.conversationID == update.ConversationID {
+			watchers = append(watchers, watcher)
+		}
+	}
+	m.subscriptionsMu.RUnlock()
+
+	for _, watcher := range watchers {
+		watcher.fn(update)
+	}
+}
+
+// resubscribe renews the stored subscriptions of a newly created session
+func (m *Manager) resubscribe(ctx context.Context, session *SessionInfo) {
+	m.subscriptionsMu.RLock()
+	uris := make([]string, 0, len(m.subscriptions[session.ConversationID][session.ServerName]))
+	for uri := range m.subscriptions[session.ConversationID][session.ServerName] {
+		uris = append(uris, uri)
+	}
+	m.subscriptionsMu.RUnlock()
+
+	if len(uris) == 0 || !supportsSubscriptions(session.Client) {
+		return
+	}
+
+	for _, uri := range uris {
+		if err := session.Client.Subscribe(ctx, &mcp.SubscribeParams{URI: uri}); err != nil {
+			logging.LogWarningf(err, "Failed to renew subscription to %s on server %s", uri, session.ServerName)
+		}
+	}
+	logging.LogDebugf("Renewed %d resource subscriptions: conversation=%s server=%s", len(uris), session.ConversationID, session.ServerName)
+}
+
+// dropSubscriptions forgets all subscriptions of a conversation
+func (m *Manager) dropSubscriptions(conversationID uuid.UUID) {
+	m.subscriptionsMu.Lock()
+	delete(m.subscriptions, conversationID)
+	m.subscriptionsMu.Unlock()
+}
+
+func supportsSubscriptions(client *mcp.ClientSession) bool {
+	initResult := client.InitializeResult()
+	return initResult != nil && initResult.Capabilities != nil &&
+		initResult.Capabilities.Resources != nil && initResult.Capabilities.Resources.Subscribe
+}
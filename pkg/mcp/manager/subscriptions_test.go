@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+)
+
+func TestSubscriptions_FanOutAndRenewOnNewSession(t *testing.T) {
+	subscribed := make(chan string, 4)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, &mcp.ServerOptions{
+		HasResources: true,
+		SubscribeHandler: func(_ context.Context, req *mcp.SubscribeRequest) error {
+			subscribed <- req.Params.URI
+			return nil
+		},
+		UnsubscribeHandler: func(context.Context, *mcp.UnsubscribeRequest) error { return nil },
+	})
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	m := NewMCPManager([]config.MCPServerConfig{{
+		Name:    "notes",
+		Type:    "http",
+		Mode:    config.HTTPServerModeBatch,
+		URL:     httpServer.URL,
+		Enabled: true,
+	}})
+	ctx := context.Background()
+	conversationID, userID := uuid.New(), uuid.New()
+
+	updates := make(chan ResourceUpdate, 1)
+	stop := m.WatchResourceUpdates(conversationID, func(update ResourceUpdate) { updates <- update })
+	defer stop()
+
+	require.NoError(t, m.SubscribeResource(ctx, conversationID, userID, "", "notes", "note://1"))
+	assert.Equal(t, "note://1", <-subscribed)
+	assert.Equal(t, []ResourceSubscription{{ServerName: "notes", URI: "note://1"}}, m.ListResourceSubscriptions(conversationID))
+
+	require.NoError(t, server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: "note://1"}))
+	select {
+	case update := <-updates:
+		assert.Equal(t, ResourceUpdate{ConversationID: conversationID, ServerName: "notes", URI: "note://1"}, update)
+	case <-time.After(2 * time.Second):
+		t.Fatal("resource update was not delivered")
+	}
+
+	// A re-created session renews the stored subscription
+	require.NoError(t, m.CloseSession(conversationID, "notes"))
+	serverCfg, _ := m.GetServerConfig("notes")
+	_, err := m.GetOrCreateSession(ctx, conversationID, serverCfg, "", userID)
+	require.NoError(t, err)
+	select {
+	case uri := <-subscribed:
+		assert.Equal(t, "note://1", uri)
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription was not renewed")
+	}
+
+	require.NoError(t, m.CloseAllSessionsForConversation(conversationID))
+	assert.Empty(t, m.ListResourceSubscriptions(conversationID))
+}
@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// exchangedTokenMargin renews exchanged tokens before they expire
+	exchangedTokenMargin = 30 * time.Second
+)
+
+// ErrNoSubjectToken indicates a request to a token exchange server without a user token
+var ErrNoSubjectToken = errors.New("token exchange requires the user's bearer token")
+
+var tokenExchangeClient = &http.Client{Timeout: 30 * time.Second}
+
+// tokenExchangeResponse is the successful response of an RFC 8693 token exchange
+type tokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// usesUserBearer reports whether requests to the server carry the user's bearer token,
+// either as is or exchanged. Sessions with such servers are bound to the token.
+func usesUserBearer(server config.MCPServerConfig) bool {
+	return server.Type == "http" && (server.ForwardBearer || server.TokenExchange != nil)
+}
+
+// tokenExchangeBinding authorizes requests to the server with tokens exchanged for the
+// user's bearer token
+func (m *Manager) tokenExchangeBinding(server config.MCPServerConfig, bearerToken string) *oauthBinding {
+	return &oauthBinding{
+		token: func(ctx context.Context) (string, error) {
+			return m.exchangeToken(ctx, server, bearerToken)
+		},
+	}
+}
+
+// exchangeToken returns a token for the server in exchange for the user's token, cached
+// until shortly before it expires
+func (m *Manager) exchangeToken(ctx context.Context, server config.MCPServerConfig, subjectToken string) (string, error) {
+	if subjectToken == "" {
+		return "", ErrNoSubjectToken
+	}
+	exchange := server.TokenExchange
+	form := url.Values{
+		"grant_type":           {grantTypeTokenExchange},
+		"subject_token":        {subjectToken},
+		"subject_token_type":   {valueOrDefault(exchange.SubjectTokenType, config.TokenTypeAccessToken)},
+		"requested_token_type": {valueOrDefault(exchange.RequestedTokenType, config.TokenTypeAccessToken)},
+	}
+	if exchange.Audience != "" {
+		form.Set("audience", exchange.Audience)
+	}
+	if exchange.Resource != "" {
+		form.Set("resource", exchange.Resource)
+	} else if exchange.Audience == "" {
+		form.Set("resource", server.URL)
+	}
+	if len(exchange.Scopes) > 0 {
+		form.Set("scope", strings.Join(exchange.Scopes, " "))
+	}
+
+	// The subject token is only part of the key as a hash
+	cacheKey := strings.Join([]string{
+		exchange.TokenURL, exchange.ClientID, form.Get("audience"), form.Get("resource"),
+		form.Get("scope"), form.Get("requested_token_type"), hashToken(subjectToken),
+	}, "|")
+	if token, found := m.exchangedTokens.Get(cacheKey); found {
+		if accessToken, ok := token.(string); ok {
+			return accessToken, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exchange.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create token exchange request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if exchange.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(exchange.ClientID), url.QueryEscape(exchange.ClientSecret))
+	}
+
+	resp, err := tokenExchangeClient.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "token exchange for MCP server %s failed", server.Name)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", errors.Errorf("token exchange for MCP server %s failed: status %d: %s", server.Name, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result tokenExchangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", errors.Wrap(err, "failed to decode token exchange response")
+	}
+	if result.AccessToken == "" {
+		return "", errors.Errorf("token exchange for MCP server %s returned no token", server.Name)
+	}
+
+	switch ttl := time.Duration(result.ExpiresIn)*time.Second - exchangedTokenMargin; {
+	case result.ExpiresIn <= 0:
+		m.exchangedTokens.SetDefault(cacheKey, result.AccessToken)
+	case ttl > 0:
+		m.exchangedTokens.Set(cacheKey, result.AccessToken, ttl)
+	}
+	logging.LogDebugf("Exchanged token for MCP server %s: type=%s expiresIn=%d", server.Name, result.IssuedTokenType, result.ExpiresIn)
+	return result.AccessToken, nil
+}
+
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
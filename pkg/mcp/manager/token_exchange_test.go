@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+)
+
+func TestTokenExchange_ExchangesAndCachesPerUserToken(t *testing.T) {
+	var exchanges int32
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, grantTypeTokenExchange, r.PostForm.Get("grant_type"))
+		assert.Equal(t, "notes-api", r.PostForm.Get("audience"))
+		assert.Empty(t, r.PostForm.Get("resource"))
+		assert.Equal(t, config.TokenTypeAccessToken, r.PostForm.Get("subject_token_type"))
+		clientID, clientSecret, _ := r.BasicAuth()
+		assert.Equal(t, "host", clientID)
+		assert.Equal(t, "secret", clientSecret)
+
+		atomic.AddInt32(&exchanges, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"exchanged-%s","token_type":"Bearer","expires_in":300}`, r.PostForm.Get("subject_token"))
+	}))
+	defer sts.Close()
+
+	var (
+		mu             sync.Mutex
+		authorizations []string
+	)
+	lastAuthorization := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return authorizations[len(authorizations)-1]
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	serverCfg := config.MCPServerConfig{
+		Name:          "notes",
+		Type:          "http",
+		Mode:          config.HTTPServerModeBatch,
+		URL:           httpServer.URL,
+		ForwardBearer: true,
+		Enabled:       true,
+		TokenExchange: &config.TokenExchangeConfig{
+			TokenURL:     sts.URL,
+			ClientID:     "host",
+			ClientSecret: "secret",
+			Audience:     "notes-api",
+		},
+	}
+	m := NewMCPManager([]config.MCPServerConfig{serverCfg})
+	ctx := context.Background()
+	conversationID := uuid.New()
+	userID := uuid.New()
+	defer func() { _ = m.CloseAllSessionsForConversation(conversationID) }()
+
+	session, err := m.GetOrCreateSession(ctx, conversationID, serverCfg, "user-token", userID)
+	require.NoError(t, err)
+	_, err = session.Client.ListTools(ctx, nil)
+	require.NoError(t, err)
+
+	// The user's token is never forwarded, and one exchange serves all requests
+	mu.Lock()
+	require.NotEmpty(t, authorizations)
+	for _, authorization := range authorizations {
+		assert.Equal(t, "Bearer exchanged-user-token", authorization)
+	}
+	mu.Unlock()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&exchanges))
+
+	// The same user token reuses the session, a new one recreates it with a new exchange
+	reused, err := m.GetOrCreateSession(ctx, conversationID, serverCfg, "user-token", userID)
+	require.NoError(t, err)
+	assert.Same(t, session, reused)
+
+	recreated, err := m.GetOrCreateSession(ctx, conversationID, serverCfg, "renewed-token", userID)
+	require.NoError(t, err)
+	assert.NotSame(t, session, recreated)
+	assert.Equal(t, "Bearer exchanged-renewed-token", lastAuthorization())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&exchanges))
+
+	// Without a user token there is nothing to exchange
+	_, err = m.ProbeServer(ctx, serverCfg, "")
+	assert.ErrorContains(t, err, ErrNoSubjectToken.Error())
+}
@@ -0,0 +1,174 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// userServerConfigsTTL is how long the servers of a user are cached. Changes made on this
+// instance invalidate them immediately, changes made on other replicas after the TTL.
+const userServerConfigsTTL = time.Minute
+
+// UserServerStore provides the MCP servers registered by individual users
+type UserServerStore interface {
+	// ListUserServers returns the enabled servers of the user
+	ListUserServers(ctx context.Context, userID uuid.UUID) ([]config.MCPServerConfig, error)
+}
+
+// UserServerTransport is implemented by user server stores that restrict where user servers
+// may connect; the manager sends all requests to user servers through the transport
+type UserServerTransport interface {
+	Transport() http.RoundTripper
+}
+
+// WithUserServers merges the servers of store with the configured ones for each user
+func WithUserServers(store UserServerStore) Option {
+	return func(m *Manager) {
+		m.userServers = store
+	}
+}
+
+// UserServers returns the store of user-registered servers, or nil when there is none
+func (m *Manager) UserServers() UserServerStore {
+	return m.userServers
+}
+
+// ServersForUser returns the enabled configured servers followed by the enabled servers the
+// user registered. User servers named like a configured server are ignored.
+func (m *Manager) ServersForUser(ctx context.Context, userID uuid.UUID) []config.MCPServerConfig {
+	servers := make([]config.MCPServerConfig, 0, len(m.serverConfigs))
+	for _, server := range m.serverConfigs {
+		if server.Enabled {
+			servers = append(servers, server)
+		}
+	}
+	if m.userServers == nil || userID == uuid.Nil {
+		return servers
+	}
+
+	userServers, err := m.listUserServers(ctx, userID)
+	if err != nil {
+		logging.LogWarningf(err, "Failed to load MCP servers of user %s", userID)
+		return servers
+	}
+	for _, server := range userServers {
+		if m.IsConfiguredServer(server.Name) {
+			logging.LogWarningf(nil, "Ignoring MCP server %s of user %s: the name is taken by a configured server", server.Name, userID)
+			continue
+		}
+		if server.Enabled {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// GetServerConfigForUser returns the enabled server entry by name, looking at the user's
+// servers when no configured server matches
+func (m *Manager) GetServerConfigForUser(ctx context.Context, userID uuid.UUID, serverName string) (config.MCPServerConfig, bool) {
+	if server, ok := m.GetServerConfig(serverName); ok {
+		return server, true
+	}
+	if m.userServers == nil || userID == uuid.Nil || m.IsConfiguredServer(serverName) {
+		return config.MCPServerConfig{}, false
+	}
+
+	userServers, err := m.listUserServers(ctx, userID)
+	if err != nil {
+		logging.LogWarningf(err, "Failed to load MCP servers of user %s", userID)
+		return config.MCPServerConfig{}, false
+	}
+	for _, server := range userServers {
+		if server.Enabled && server.Name == serverName {
+			return server, true
+		}
+	}
+	return config.MCPServerConfig{}, false
+}
+
+// listUserServers returns the servers of the user from the store, cached for
+// userServerConfigsTTL since they are looked up for every tool call and listing
+func (m *Manager) listUserServers(ctx context.Context, userID uuid.UUID) ([]config.MCPServerConfig, error) {
+	key := userID.String()
+	if cached, found := m.userServerConfigs.Get(key); found {
+		if servers, ok := cached.([]config.MCPServerConfig); ok {
+			return servers, nil
+		}
+	}
+	servers, err := m.userServers.ListUserServers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	m.userServerConfigs.SetDefault(key, servers)
+	return servers, nil
+}
+
+// userServerTransport returns the transport for a server registered by a user, or nil for
+// configured servers and stores without a restricted transport
+func (m *Manager) userServerTransport(serverName string) http.RoundTripper {
+	if m.userServers == nil || m.IsConfiguredServer(serverName) {
+		return nil
+	}
+	if t, ok := m.userServers.(UserServerTransport); ok {
+		return t.Transport()
+	}
+	return nil
+}
+
+// IsConfiguredServer reports whether a configured server, enabled or not, has the name
+func (m *Manager) IsConfiguredServer(serverName string) bool {
+	for _, server := range m.serverConfigs {
+		if server.Name == serverName {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidateUserServer drops the cached server list of the user and the cached tools,
+// resources, prompts and capabilities of a user's server, and closes the user's sessions
+// with it. Call it after the server was added, changed or removed, with its previous
+// configuration.
+func (m *Manager) InvalidateUserServer(userID uuid.UUID, server config.MCPServerConfig) {
+	m.userServerConfigs.Delete(userID.String())
+	key := m.getUserKey(userID, server.Name)
+	m.userToolsCache.Delete(key)
+	m.userResourcesCache.Delete(key)
+	m.userPromptsCache.Delete(key)
+	m.serverCapsCache.Delete(capsCacheKey(server))
+	m.serverCapsCache.Delete(m.getUserKey(userID, capsCacheKey(server)))
+
+	m.mu.Lock()
+	var closed []*SessionInfo
+	for sessionKey, session := range m.sessions {
+		if session.UserID == userID && session.ServerName == server.Name {
+			delete(m.sessions, sessionKey)
+			delete(m.sessionIndex, session.Client)
+			closed = append(closed, session)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, session := range closed {
+		if session.reconnectTracker != nil {
+			session.reconnectTracker.markClosed()
+		}
+		if err := session.Client.Close(); err != nil {
+			logging.LogWarningf(err, "Failed to close MCP session with server %s", server.Name)
+		}
+	}
+	logging.LogDebugf("Invalidated MCP server %s of user %s: sessions=%d", server.Name, userID, len(closed))
+}
+
+// capsCacheKey identifies a server in the capabilities cache. Servers of different users
+// may share a name, so the endpoint is part of the key.
+func capsCacheKey(server config.MCPServerConfig) string {
+	return server.Name + "|" + server.URL
+}
@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+)
+
+// staticUserServers returns fixed servers for one user
+type staticUserServers struct {
+	userID  uuid.UUID
+	servers []config.MCPServerConfig
+}
+
+func (s staticUserServers) ListUserServers(_ context.Context, userID uuid.UUID) ([]config.MCPServerConfig, error) {
+	if userID != s.userID {
+		return nil, nil
+	}
+	return s.servers, nil
+}
+
+func TestServersForUser_MergesUserServers(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	m := NewMCPManager(
+		[]config.MCPServerConfig{
+			{Name: "files", Type: "stdio", Enabled: true},
+			{Name: "legacy", Type: "stdio", Enabled: false},
+		},
+		WithUserServers(staticUserServers{userID: userID, servers: []config.MCPServerConfig{
+			{Name: "notes", Type: "http", URL: "https://notes.example.com/mcp", Enabled: true},
+			{Name: "files", Type: "http", URL: "https://evil.example.com/mcp", Enabled: true},
+			{Name: "legacy", Type: "http", URL: "https://legacy.example.com/mcp", Enabled: true},
+		}}),
+	)
+
+	names := func(servers []config.MCPServerConfig) []string {
+		var result []string
+		for _, s := range servers {
+			result = append(result, s.Name)
+		}
+		return result
+	}
+
+	// Configured names, enabled or not, cannot be taken over by user servers
+	assert.Equal(t, []string{"files", "notes"}, names(m.ServersForUser(ctx, userID)))
+	assert.Equal(t, []string{"files"}, names(m.ServersForUser(ctx, uuid.New())))
+
+	notes, ok := m.GetServerConfigForUser(ctx, userID, "notes")
+	assert.True(t, ok)
+	assert.Equal(t, "https://notes.example.com/mcp", notes.URL)
+	_, ok = m.GetServerConfigForUser(ctx, uuid.New(), "notes")
+	assert.False(t, ok)
+	_, ok = m.GetServerConfigForUser(ctx, userID, "legacy")
+	assert.False(t, ok)
+	files, _ := m.GetServerConfigForUser(ctx, userID, "files")
+	assert.Equal(t, "stdio", files.Type)
+}
+
+// countingUserServers counts the loads of the wrapped store
+type countingUserServers struct {
+	staticUserServers
+	loads int
+}
+
+func (s *countingUserServers) ListUserServers(ctx context.Context, userID uuid.UUID) ([]config.MCPServerConfig, error) {
+	s.loads++
+	return s.staticUserServers.ListUserServers(ctx, userID)
+}
+
+func TestServersForUser_CachesUntilInvalidated(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	notes := config.MCPServerConfig{Name: "notes", Type: "http", URL: "https://notes.example.com/mcp", Enabled: true}
+	store := &countingUserServers{staticUserServers: staticUserServers{userID: userID, servers: []config.MCPServerConfig{notes}}}
+	m := NewMCPManager(nil, WithUserServers(store))
+
+	assert.Len(t, m.ServersForUser(ctx, userID), 1)
+	_, ok := m.GetServerConfigForUser(ctx, userID, "notes")
+	assert.True(t, ok)
+	assert.Equal(t, 1, store.loads)
+
+	store.servers = nil
+	m.InvalidateUserServer(userID, notes)
+	assert.Empty(t, m.ServersForUser(ctx, userID))
+	assert.Equal(t, 2, store.loads)
+}
@@ -0,0 +1,569 @@
+// Package oauth implements the MCP authorization flow for HTTP MCP servers: protected
+// resource and authorization server discovery, dynamic client registration and the
+// authorization code flow with PKCE. Tokens are stored per user and server.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+var (
+	// ErrUnknownState indicates a callback without a matching pending authorization,
+	// e.g. because it expired or was already completed
+	ErrUnknownState = errors.New("unknown or expired OAuth state")
+	// ErrStateMismatch indicates a callback in another browser than the one that started
+	// the authorization, e.g. a victim lured into completing an attacker's flow
+	ErrStateMismatch = errors.New("OAuth callback does not belong to this browser")
+	// ErrNotHTTPServer indicates an authorization request for a server without HTTP transport
+	ErrNotHTTPServer = errors.New("OAuth is only supported for HTTP MCP servers")
+)
+
+const (
+	// pendingTTL bounds the time between starting an authorization and its callback
+	pendingTTL = 10 * time.Minute
+	// nonceCookiePrefix names the cookies that bind authorizations to the browser
+	nonceCookiePrefix = "mcp_oauth_"
+	// requestTimeout bounds discovery, registration and token requests
+	requestTimeout = 30 * time.Second
+)
+
+// Authorizer runs the MCP authorization flow and provides the resulting access tokens to
+// the manager as a manager.OAuthProvider
+type Authorizer struct {
+	db          *gorm.DB
+	box         *auth.SecretBox
+	clientName  string
+	redirectURL string
+	successURL  string
+
+	// serverTransport returns the transport for the requests of a server's flows (nil = default)
+	serverTransport func(serverName string) http.RoundTripper
+
+	mu     sync.Mutex
+	tokens map[string]*cachedToken // key: userID:serverName
+	locks  map[string]*sync.Mutex  // serializes refreshes per user and server
+}
+
+// Authorization is a started authorization flow
+type Authorization struct {
+	// URL is the authorization URL the user must open
+	URL string
+	// State identifies the flow in the callback
+	State string
+	// Nonce must be set as cookie NonceCookieName(State) in the browser that opens URL;
+	// the callback is only accepted with it
+	Nonce string
+}
+
+// NonceCookieName returns the name of the cookie holding the nonce of a flow
+func NonceCookieName(state string) string {
+	if len(state) > 16 {
+		state = state[:16]
+	}
+	return nonceCookiePrefix + state
+}
+
+// flow is the client configuration of an authorization discovered for a server
+type flow struct {
+	oauth    oauth2.Config
+	resource string
+	issuer   string
+}
+
+// authCodeURL returns the authorization URL with the PKCE challenge of verifier
+func (f *flow) authCodeURL(state, verifier string) string {
+	return f.oauth.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("resource", f.resource),
+	)
+}
+
+// cachedToken is a decrypted token and the server endpoint it was issued for
+type cachedToken struct {
+	token     *oauth2.Token
+	serverURL string
+}
+
+// New creates an authorizer that stores tokens sealed by box. redirectURL must point to
+// the callback endpoint; the browser is sent to successURL after a completed flow.
+func New(db *gorm.DB, box *auth.SecretBox, settings config.OAuthSettings) *Authorizer {
+	return &Authorizer{
+		db:          db,
+		box:         box,
+		clientName:  "go-mcp-host",
+		redirectURL: settings.RedirectURL,
+		successURL:  settings.SuccessURL,
+		tokens:      make(map[string]*cachedToken),
+		locks:       make(map[string]*sync.Mutex),
+	}
+}
+
+// SetServerTransport makes the requests of an MCP server's flows use the transport returned
+// for the server, or the default transport for nil. The manager sets it so that the flows of
+// user-registered servers cannot reach internal addresses either (see manager.WithOAuth).
+func (a *Authorizer) SetServerTransport(transport func(serverName string) http.RoundTripper) {
+	a.serverTransport = transport
+}
+
+// SuccessURL returns where the browser is sent after a completed flow ("" = nowhere)
+func (a *Authorizer) SuccessURL() string {
+	return a.successURL
+}
+
+// RedirectURL returns the callback URL the authorization server redirects to
+func (a *Authorizer) RedirectURL() string {
+	return a.redirectURL
+}
+
+// Start discovers the authorization server of the MCP server, registers a client when none
+// is configured, and stores the pending authorization. challenge is the WWW-Authenticate
+// header the server rejected the user's last request with, if any.
+func (a *Authorizer) Start(ctx context.Context, userID uuid.UUID, server config.MCPServerConfig, challenge string) (*Authorization, error) {
+	f, err := a.prepare(ctx, server, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	row := models.MCPOAuthPending{
+		StateHash:  hashValue(state),
+		NonceHash:  hashValue(nonce),
+		UserID:     userID,
+		ServerName: server.Name,
+		ServerURL:  server.URL,
+		Issuer:     f.issuer,
+		Resource:   f.resource,
+		AuthURL:    f.oauth.Endpoint.AuthURL,
+		TokenURL:   f.oauth.Endpoint.TokenURL,
+		ClientID:   f.oauth.ClientID,
+		Scopes:     strings.Join(f.oauth.Scopes, " "),
+		ExpiresAt:  now.Add(pendingTTL),
+	}
+	aad := pendingAAD(&row)
+	if row.EncryptedVerifier, err = a.box.Seal([]byte(verifier), aad); err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt PKCE verifier")
+	}
+	if f.oauth.ClientSecret != "" {
+		if row.EncryptedClientSecret, err = a.box.Seal([]byte(f.oauth.ClientSecret), aad); err != nil {
+			return nil, errors.Wrap(err, "failed to encrypt OAuth client secret")
+		}
+	}
+	err = a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Abandoned authorizations are no longer needed
+		if err := tx.Where("expires_at < ?", now).Delete(&models.MCPOAuthPending{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&row).Error
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store pending OAuth authorization")
+	}
+
+	logging.LogDebugf("Started OAuth authorization of user %s for MCP server %s with issuer %s", userID, server.Name, f.issuer)
+	return &Authorization{URL: f.authCodeURL(state, verifier), State: state, Nonce: nonce}, nil
+}
+
+// prepare discovers the authorization server of the MCP server and registers a client when
+// none is configured
+func (a *Authorizer) prepare(ctx context.Context, server config.MCPServerConfig, challenge string) (*flow, error) {
+	if server.Type != "http" {
+		return nil, ErrNotHTTPServer
+	}
+
+	client := a.httpClient(server.Name)
+	resource, metadata, err := a.discover(ctx, client, server.URL, challenge)
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata.CodeChallengeMethodsSupported) > 0 && !slices.Contains(metadata.CodeChallengeMethodsSupported, "S256") {
+		return nil, errors.Errorf("authorization server %s does not support PKCE with S256", metadata.Issuer)
+	}
+
+	scopes := requestedScopes(server, resource, challenge)
+	f := &flow{
+		oauth: oauth2.Config{
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  metadata.AuthorizationEndpoint,
+				TokenURL: metadata.TokenEndpoint,
+			},
+			RedirectURL: a.redirectURL,
+			Scopes:      scopes,
+		},
+		resource: server.URL,
+		issuer:   metadata.Issuer,
+	}
+	if server.OAuth != nil && server.OAuth.ClientID != "" {
+		f.oauth.ClientID = server.OAuth.ClientID
+		f.oauth.ClientSecret = server.OAuth.ClientSecret
+	} else {
+		registration, err := a.register(ctx, client, metadata, scopes)
+		if err != nil {
+			return nil, err
+		}
+		f.oauth.ClientID = registration.ClientID
+		f.oauth.ClientSecret = registration.ClientSecret
+	}
+	if f.oauth.ClientSecret == "" {
+		f.oauth.Endpoint.AuthStyle = oauth2.AuthStyleInParams
+	}
+	if resource != nil && resource.Resource != "" {
+		f.resource = resource.Resource
+	}
+	return f, nil
+}
+
+// Complete exchanges the authorization code of a callback for tokens and stores them.
+// nonce is the value of the flow's cookie in the browser that called back. It returns the
+// user and the name of the server the authorization was started for.
+func (a *Authorizer) Complete(ctx context.Context, state, code, nonce string) (uuid.UUID, string, error) {
+	stateHash := hashValue(state)
+	var pending models.MCPOAuthPending
+	err := a.db.WithContext(ctx).Where("state_hash = ? AND expires_at > ?", stateHash, time.Now()).First(&pending).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, "", ErrUnknownState
+	}
+	if err != nil {
+		return uuid.Nil, "", errors.Wrap(err, "failed to load pending OAuth authorization")
+	}
+	if subtle.ConstantTimeCompare([]byte(hashValue(nonce)), []byte(pending.NonceHash)) != 1 {
+		return uuid.Nil, "", ErrStateMismatch
+	}
+	// Each authorization completes once, even with concurrent callbacks
+	result := a.db.WithContext(ctx).Where("state_hash = ?", stateHash).Delete(&models.MCPOAuthPending{})
+	if result.Error != nil {
+		return uuid.Nil, "", errors.Wrap(result.Error, "failed to consume pending OAuth authorization")
+	}
+	if result.RowsAffected == 0 {
+		return uuid.Nil, "", ErrUnknownState
+	}
+
+	verifier, err := a.box.Open(pending.EncryptedVerifier, pendingAAD(&pending))
+	if err != nil {
+		return uuid.Nil, "", errors.Wrap(err, "failed to decrypt PKCE verifier")
+	}
+	row := models.MCPOAuthToken{
+		UserID:     pending.UserID,
+		ServerName: pending.ServerName,
+		ServerURL:  pending.ServerURL,
+		Issuer:     pending.Issuer,
+		Resource:   pending.Resource,
+		AuthURL:    pending.AuthURL,
+		TokenURL:   pending.TokenURL,
+		ClientID:   pending.ClientID,
+		Scopes:     pending.Scopes,
+	}
+	// The client secret is sealed again for the token row
+	if len(pending.EncryptedClientSecret) > 0 {
+		secret, err := a.box.Open(pending.EncryptedClientSecret, pendingAAD(&pending))
+		if err != nil {
+			return uuid.Nil, "", errors.Wrap(err, "failed to decrypt OAuth client secret")
+		}
+		if row.EncryptedClientSecret, err = a.box.Seal(secret, tokenAAD(&row)); err != nil {
+			return uuid.Nil, "", errors.Wrap(err, "failed to encrypt OAuth client secret")
+		}
+	}
+	oauthConfig, err := a.oauthConfig(&row)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	token, err := oauthConfig.Exchange(a.clientContext(ctx, pending.ServerName), code,
+		oauth2.VerifierOption(string(verifier)),
+		oauth2.SetAuthURLParam("resource", pending.Resource),
+	)
+	if err != nil {
+		return uuid.Nil, "", errors.Wrapf(err, "failed to exchange authorization code of MCP server %s", pending.ServerName)
+	}
+	if err := a.setToken(&row, token); err != nil {
+		return uuid.Nil, "", err
+	}
+
+	err = a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND server_name = ?", row.UserID, row.ServerName).
+			Delete(&models.MCPOAuthToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&row).Error
+	})
+	if err != nil {
+		return uuid.Nil, "", errors.Wrap(err, "failed to store OAuth token")
+	}
+	a.cacheToken(row.UserID, row.ServerName, &cachedToken{token: token, serverURL: row.ServerURL})
+
+	logging.LogDebugf("Completed OAuth authorization of user %s for MCP server %s", pending.UserID, pending.ServerName)
+	return pending.UserID, pending.ServerName, nil
+}
+
+// AccessToken returns a valid access token of the user for the server, refreshing it when
+// it expired, or "" when the user has not authorized the server at its current URL
+func (a *Authorizer) AccessToken(ctx context.Context, userID uuid.UUID, server config.MCPServerConfig) (string, error) {
+	key := userID.String() + ":" + server.Name
+	if cached := a.cachedToken(key); cached != nil && cached.token.Valid() {
+		if cached.serverURL != server.URL {
+			return "", nil
+		}
+		return cached.token.AccessToken, nil
+	}
+
+	lock := a.refreshLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Another request may have refreshed the token meanwhile
+	if cached := a.cachedToken(key); cached != nil && cached.token.Valid() && cached.serverURL == server.URL {
+		return cached.token.AccessToken, nil
+	}
+
+	var row models.MCPOAuthToken
+	err := a.db.WithContext(ctx).Where("user_id = ? AND server_name = ?", userID, server.Name).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load OAuth token")
+	}
+	// Tokens are never sent to another endpoint than the one they were issued for
+	if row.ServerURL != server.URL {
+		return "", nil
+	}
+
+	stored, err := a.storedToken(&row)
+	if err != nil {
+		return "", err
+	}
+	if !stored.Valid() && stored.RefreshToken == "" {
+		// Expired without a way to refresh: the user has to authorize again
+		return "", nil
+	}
+	oauthConfig, err := a.oauthConfig(&row)
+	if err != nil {
+		return "", err
+	}
+	token, err := oauthConfig.TokenSource(a.clientContext(ctx, server.Name), stored).Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil && retrieveErr.Response.StatusCode < 500 {
+			// The grant was revoked or expired: the user has to authorize again
+			logging.LogWarningf(err, "Discarding OAuth token of user %s for MCP server %s", userID, server.Name)
+			a.forget(key)
+			if err := a.db.WithContext(ctx).Delete(&row).Error; err != nil {
+				return "", errors.Wrap(err, "failed to delete OAuth token")
+			}
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to refresh OAuth token for MCP server %s", server.Name)
+	}
+
+	if token.AccessToken != stored.AccessToken {
+		if err := a.setToken(&row, token); err != nil {
+			return "", err
+		}
+		if err := a.db.WithContext(ctx).Save(&row).Error; err != nil {
+			return "", errors.Wrap(err, "failed to store refreshed OAuth token")
+		}
+		logging.LogDebugf("Refreshed OAuth token of user %s for MCP server %s", userID, server.Name)
+	}
+	a.cacheToken(userID, server.Name, &cachedToken{token: token, serverURL: row.ServerURL})
+	return token.AccessToken, nil
+}
+
+// Authorized reports whether the user has a token for the server
+func (a *Authorizer) Authorized(ctx context.Context, userID uuid.UUID, serverName string) (bool, error) {
+	var count int64
+	err := a.db.WithContext(ctx).Model(&models.MCPOAuthToken{}).
+		Where("user_id = ? AND server_name = ?", userID, serverName).
+		Count(&count).Error
+	return count > 0, errors.Wrap(err, "failed to check OAuth token")
+}
+
+// Disconnect deletes the user's token for the server
+func (a *Authorizer) Disconnect(ctx context.Context, userID uuid.UUID, serverName string) error {
+	a.forget(userID.String() + ":" + serverName)
+	err := a.db.WithContext(ctx).
+		Where("user_id = ? AND server_name = ?", userID, serverName).
+		Delete(&models.MCPOAuthToken{}).Error
+	return errors.Wrap(err, "failed to delete OAuth token")
+}
+
+// oauthConfig rebuilds the client configuration of a stored token
+func (a *Authorizer) oauthConfig(row *models.MCPOAuthToken) (*oauth2.Config, error) {
+	oauthConfig := &oauth2.Config{
+		ClientID: row.ClientID,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   row.AuthURL,
+			TokenURL:  row.TokenURL,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		RedirectURL: a.redirectURL,
+		Scopes:      strings.Fields(row.Scopes),
+	}
+	if len(row.EncryptedClientSecret) > 0 {
+		secret, err := a.box.Open(row.EncryptedClientSecret, tokenAAD(row))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt OAuth client secret")
+		}
+		oauthConfig.ClientSecret = string(secret)
+		oauthConfig.Endpoint.AuthStyle = oauth2.AuthStyleAutoDetect
+	}
+	return oauthConfig, nil
+}
+
+// storedToken decrypts the tokens of a stored row
+func (a *Authorizer) storedToken(row *models.MCPOAuthToken) (*oauth2.Token, error) {
+	token := &oauth2.Token{TokenType: row.TokenType}
+	if row.Expiry != nil {
+		token.Expiry = *row.Expiry
+	}
+	if len(row.EncryptedAccessToken) > 0 {
+		accessToken, err := a.box.Open(row.EncryptedAccessToken, tokenAAD(row))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt OAuth access token")
+		}
+		token.AccessToken = string(accessToken)
+	}
+	if len(row.EncryptedRefreshToken) > 0 {
+		refreshToken, err := a.box.Open(row.EncryptedRefreshToken, tokenAAD(row))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt OAuth refresh token")
+		}
+		token.RefreshToken = string(refreshToken)
+	}
+	return token, nil
+}
+
+// setToken encrypts token into the row. A refresh response without a refresh token keeps
+// the stored one.
+func (a *Authorizer) setToken(row *models.MCPOAuthToken, token *oauth2.Token) error {
+	accessToken, err := a.box.Seal([]byte(token.AccessToken), tokenAAD(row))
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt OAuth access token")
+	}
+	row.EncryptedAccessToken = accessToken
+	if token.RefreshToken != "" {
+		refreshToken, err := a.box.Seal([]byte(token.RefreshToken), tokenAAD(row))
+		if err != nil {
+			return errors.Wrap(err, "failed to encrypt OAuth refresh token")
+		}
+		row.EncryptedRefreshToken = refreshToken
+	}
+	row.TokenType = token.Type()
+	row.Expiry = nil
+	if !token.Expiry.IsZero() {
+		expiry := token.Expiry
+		row.Expiry = &expiry
+	}
+	return nil
+}
+
+func (a *Authorizer) cachedToken(key string) *cachedToken {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.tokens[key]
+}
+
+func (a *Authorizer) cacheToken(userID uuid.UUID, serverName string, token *cachedToken) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens[userID.String()+":"+serverName] = token
+}
+
+func (a *Authorizer) forget(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.tokens, key)
+}
+
+func (a *Authorizer) refreshLock(key string) *sync.Mutex {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	lock, ok := a.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		a.locks[key] = lock
+	}
+	return lock
+}
+
+// httpClient returns the HTTP client for the requests of an MCP server's flows
+func (a *Authorizer) httpClient(serverName string) *http.Client {
+	client := &http.Client{Timeout: requestTimeout}
+	if a.serverTransport != nil {
+		client.Transport = a.serverTransport(serverName)
+	}
+	return client
+}
+
+// clientContext makes the oauth2 package use the HTTP client of an MCP server's flows
+func (a *Authorizer) clientContext(ctx context.Context, serverName string) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, a.httpClient(serverName))
+}
+
+// requestedScopes returns the configured scopes, or else the scopes of the challenge, or
+// else the scopes the server announces in its resource metadata
+func requestedScopes(server config.MCPServerConfig, resource *ProtectedResourceMetadata, challenge string) []string {
+	if server.OAuth != nil && len(server.OAuth.Scopes) > 0 {
+		return server.OAuth.Scopes
+	}
+	if scope := ChallengeParam(challenge, "scope"); scope != "" {
+		return strings.Fields(scope)
+	}
+	if resource != nil {
+		return resource.ScopesSupported
+	}
+	return nil
+}
+
+// pendingAAD binds the secrets of a pending authorization to its row
+func pendingAAD(row *models.MCPOAuthPending) []byte {
+	return []byte("mcp_oauth_pending|" + row.StateHash + "|" + row.UserID.String())
+}
+
+// tokenAAD binds the secrets of a stored token to the user and server it was issued for
+func tokenAAD(row *models.MCPOAuthToken) []byte {
+	return []byte("mcp_oauth_tokens|" + row.UserID.String() + "|" + row.ServerName)
+}
+
+// randomToken returns a random state or nonce
+func randomToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate OAuth state")
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashValue returns the hex SHA-256 states and nonces are stored as
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,262 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/registry"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+	"github.com/d4l-data4life/go-svc/pkg/db"
+)
+
+func TestChallengeParam(t *testing.T) {
+	challenge := `Bearer error="invalid_token", scope="notes:read notes:write", resource_metadata="https://a.example.com/.well-known/oauth-protected-resource"`
+	assert.Equal(t, "https://a.example.com/.well-known/oauth-protected-resource", ChallengeParam(challenge, "resource_metadata"))
+	assert.Equal(t, "notes:read notes:write", ChallengeParam(challenge, "scope"))
+	assert.Equal(t, "invalid_token", ChallengeParam(challenge, "error"))
+	assert.Equal(t, "", ChallengeParam(challenge, "realm"))
+	assert.Equal(t, "", ChallengeParam(`Bearer myscope="x"`, "scope"))
+	assert.Equal(t, "x", ChallengeParam(`Bearer scope=x, realm="y"`, "scope"))
+}
+
+func TestStart_DiscoversAndRegistersClient(t *testing.T) {
+	var registration clientRegistration
+	mux := http.NewServeMux()
+	httpServer := httptest.NewTLSServer(mux)
+	defer httpServer.Close()
+	issuer := httpServer.URL + "/tenant"
+
+	mux.HandleFunc("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(ProtectedResourceMetadata{
+			Resource:             httpServer.URL + "/mcp",
+			AuthorizationServers: []string{issuer},
+			ScopesSupported:      []string{"notes"},
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/tenant", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(ServerMetadata{
+			Issuer:                        issuer,
+			AuthorizationEndpoint:         issuer + "/authorize",
+			TokenEndpoint:                 issuer + "/token",
+			RegistrationEndpoint:          issuer + "/register",
+			CodeChallengeMethodsSupported: []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/tenant/register", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&registration))
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(clientRegistration{ClientID: "registered-client"})
+	})
+
+	box, err := auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+	a := New(nil, box, config.OAuthSettings{RedirectURL: "https://host.example.com/api/v1/oauth/callback"})
+	a.SetServerTransport(testTransport(httpServer))
+	server := config.MCPServerConfig{Name: "notes", Type: "http", URL: httpServer.URL + "/mcp"}
+
+	f, err := a.prepare(context.Background(), server, `Bearer error="invalid_token"`)
+	require.NoError(t, err)
+	authURL := f.authCodeURL("state", "verifier-verifier-verifier-verifier-verifier")
+
+	assert.Equal(t, []string{"https://host.example.com/api/v1/oauth/callback"}, registration.RedirectURIs)
+	assert.Equal(t, "none", registration.TokenEndpointAuthMethod)
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, issuer+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	query := u.Query()
+	assert.Equal(t, "registered-client", query.Get("client_id"))
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotEmpty(t, query.Get("code_challenge"))
+	assert.Equal(t, httpServer.URL+"/mcp", query.Get("resource"))
+	assert.Equal(t, "notes", query.Get("scope"))
+	assert.Equal(t, "state", query.Get("state"))
+}
+
+func TestStart_UsesConfiguredClient(t *testing.T) {
+	var registrations int
+	mux := http.NewServeMux()
+	httpServer := httptest.NewTLSServer(mux)
+	defer httpServer.Close()
+
+	// Without resource metadata the server's origin is the authorization server
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(ServerMetadata{
+			Issuer:                httpServer.URL,
+			AuthorizationEndpoint: httpServer.URL + "/oauth/authorize",
+			TokenEndpoint:         httpServer.URL + "/oauth/token",
+			RegistrationEndpoint:  httpServer.URL + "/oauth/register",
+		})
+	})
+	mux.HandleFunc("/oauth/register", func(w http.ResponseWriter, _ *http.Request) {
+		registrations++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	box, err := auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+	a := New(nil, box, config.OAuthSettings{RedirectURL: "https://host.example.com/api/v1/oauth/callback"})
+	a.SetServerTransport(testTransport(httpServer))
+	server := config.MCPServerConfig{
+		Name:  "notes",
+		Type:  "http",
+		URL:   httpServer.URL + "/mcp",
+		OAuth: &config.OAuthConfig{ClientID: "static-client", Scopes: []string{"a", "b"}},
+	}
+
+	f, err := a.prepare(context.Background(), server, `Bearer scope="ignored"`)
+	require.NoError(t, err)
+	assert.Zero(t, registrations)
+
+	u, err := url.Parse(f.authCodeURL("state", "verifier-verifier-verifier-verifier-verifier"))
+	require.NoError(t, err)
+	assert.Equal(t, "/oauth/authorize", u.Path)
+	assert.Equal(t, "static-client", u.Query().Get("client_id"))
+	assert.Equal(t, "a b", u.Query().Get("scope"))
+
+	_, err = a.prepare(context.Background(), config.MCPServerConfig{Name: "files", Type: "stdio"}, "")
+	assert.ErrorIs(t, err, ErrNotHTTPServer)
+}
+
+func TestComplete_ChecksStateAndNonce(t *testing.T) {
+	models.InitializeTestDB(t)
+	defer db.Close()
+	box, err := auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+	a := New(db.Get(), box, config.OAuthSettings{})
+
+	user := models.User{ID: uuid.New()}
+	require.NoError(t, db.Get().Create(&user).Error)
+	pending := models.MCPOAuthPending{
+		StateHash:  hashValue("state"),
+		NonceHash:  hashValue("nonce"),
+		UserID:     user.ID,
+		ServerName: "notes",
+		ServerURL:  "https://notes.example.com/mcp",
+		AuthURL:    "https://notes.example.com/authorize",
+		TokenURL:   "https://notes.example.com/token",
+		ClientID:   "client",
+		ExpiresAt:  time.Now().Add(pendingTTL),
+	}
+	pending.EncryptedVerifier, err = box.Seal([]byte("verifier"), pendingAAD(&pending))
+	require.NoError(t, err)
+	require.NoError(t, db.Get().Create(&pending).Error)
+
+	_, _, err = a.Complete(context.Background(), "unknown", "code", "nonce")
+	assert.ErrorIs(t, err, ErrUnknownState)
+
+	// Another browser's callback does not consume the authorization
+	_, _, err = a.Complete(context.Background(), "state", "code", "other-nonce")
+	assert.ErrorIs(t, err, ErrStateMismatch)
+	var count int64
+	require.NoError(t, db.Get().Model(&models.MCPOAuthPending{}).Where("state_hash = ?", hashValue("state")).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDiscover_ValidatesResource(t *testing.T) {
+	mux := http.NewServeMux()
+	httpServer := httptest.NewTLSServer(mux)
+	defer httpServer.Close()
+	other := httptest.NewServer(http.NotFoundHandler())
+	defer other.Close()
+
+	mux.HandleFunc("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(ProtectedResourceMetadata{
+			Resource:             "https://other.example.com/mcp",
+			AuthorizationServers: []string{httpServer.URL},
+		})
+	})
+
+	box, err := auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+	a := New(nil, box, config.OAuthSettings{RedirectURL: "https://host.example.com/api/v1/oauth/callback"})
+
+	_, _, err = a.discover(context.Background(), httpServer.Client(), httpServer.URL+"/mcp", "")
+	assert.ErrorIs(t, err, ErrResourceMismatch)
+
+	_, _, err = a.discover(context.Background(), httpServer.Client(), httpServer.URL+"/mcp",
+		`Bearer resource_metadata="`+other.URL+`/.well-known/oauth-protected-resource"`)
+	assert.ErrorContains(t, err, "not on the server's origin")
+}
+
+func TestDiscover_ValidatesAuthorizationServer(t *testing.T) {
+	var metadata ServerMetadata
+	mux := http.NewServeMux()
+	httpServer := httptest.NewTLSServer(mux)
+	defer httpServer.Close()
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(metadata)
+	})
+
+	box, err := auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+	a := New(nil, box, config.OAuthSettings{RedirectURL: "https://host.example.com/api/v1/oauth/callback"})
+	discover := func() error {
+		_, _, err := a.discover(context.Background(), httpServer.Client(), httpServer.URL+"/mcp", "")
+		return err
+	}
+
+	// Metadata of another issuer is not used
+	metadata = ServerMetadata{
+		Issuer:                "https://evil.example.com",
+		AuthorizationEndpoint: "https://evil.example.com/authorize",
+		TokenEndpoint:         "https://evil.example.com/token",
+	}
+	assert.ErrorIs(t, discover(), ErrIssuerMismatch)
+
+	// Endpoints must use https
+	metadata = ServerMetadata{
+		Issuer:                httpServer.URL,
+		AuthorizationEndpoint: httpServer.URL + "/authorize",
+		TokenEndpoint:         "http://169.254.169.254/token",
+	}
+	assert.ErrorIs(t, discover(), ErrInsecureEndpoint)
+
+	metadata.TokenEndpoint = httpServer.URL + "/token"
+	assert.NoError(t, discover())
+
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	_, _, err = a.discover(context.Background(), plain.Client(), plain.URL+"/mcp", "")
+	assert.ErrorIs(t, err, ErrInsecureEndpoint)
+}
+
+func TestStart_UsesServerTransport(t *testing.T) {
+	var requests int
+	httpServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer httpServer.Close()
+
+	guard, err := registry.NewAddressGuard(nil)
+	require.NoError(t, err)
+	box, err := auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+	a := New(nil, box, config.OAuthSettings{RedirectURL: "https://host.example.com/api/v1/oauth/callback"})
+	a.SetServerTransport(func(string) http.RoundTripper { return guard.Transport() })
+
+	// The guard keeps the flow of a user server from reaching the loopback address
+	_, err = a.prepare(context.Background(), config.MCPServerConfig{Name: "notes", Type: "http", URL: httpServer.URL + "/mcp"}, "")
+	assert.ErrorIs(t, err, registry.ErrAddressNotAllowed)
+	assert.Zero(t, requests)
+}
+
+// testTransport returns a server transport that trusts the certificate of a TLS test server
+func testTransport(server *httptest.Server) func(string) http.RoundTripper {
+	return func(string) http.RoundTripper {
+		return server.Client().Transport
+	}
+}
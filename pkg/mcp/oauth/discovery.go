@@ -0,0 +1,285 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNoMetadata indicates that no authorization server metadata was found
+	ErrNoMetadata = errors.New("no OAuth authorization server metadata found")
+	// ErrRegistrationUnsupported indicates that the authorization server does not offer
+	// dynamic client registration and no client is configured for the server
+	ErrRegistrationUnsupported = errors.New("authorization server does not support dynamic client registration")
+	// ErrResourceMismatch indicates protected resource metadata that is not the MCP server's,
+	// which must not be used for it (RFC 9728 §3.3)
+	ErrResourceMismatch = errors.New("protected resource metadata does not belong to the MCP server")
+	// ErrIssuerMismatch indicates authorization server metadata of another issuer than the
+	// one it was fetched for, which must not be used (RFC 8414 §3.3)
+	ErrIssuerMismatch = errors.New("authorization server metadata names another issuer")
+	// ErrInsecureEndpoint indicates an authorization server or endpoint without https
+	ErrInsecureEndpoint = errors.New("OAuth endpoint does not use https")
+)
+
+// ProtectedResourceMetadata is the OAuth protected resource metadata of an MCP server (RFC 9728)
+type ProtectedResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+	ScopesSupported      []string `json:"scopes_supported,omitempty"`
+}
+
+// ServerMetadata is the metadata of an OAuth authorization server (RFC 8414)
+type ServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// clientRegistration is the request and response of dynamic client registration (RFC 7591)
+type clientRegistration struct {
+	ClientName              string   `json:"client_name,omitempty"`
+	RedirectURIs            []string `json:"redirect_uris,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	ClientID                string   `json:"client_id,omitempty"`
+	ClientSecret            string   `json:"client_secret,omitempty"`
+}
+
+// ChallengeParam returns a parameter of a Bearer WWW-Authenticate challenge, such as
+// "resource_metadata" or "scope", or "" when it is absent
+func ChallengeParam(challenge, name string) string {
+	lower := strings.ToLower(challenge)
+	key := strings.ToLower(name) + "="
+	for i := 0; i < len(lower); {
+		idx := strings.Index(lower[i:], key)
+		if idx < 0 {
+			return ""
+		}
+		start := i + idx
+		i = start + len(key)
+		// The name must start a parameter, not end another one
+		if start > 0 && !strings.ContainsRune(" ,\t", rune(lower[start-1])) {
+			continue
+		}
+		value := challenge[i:]
+		if strings.HasPrefix(value, `"`) {
+			if end := strings.Index(value[1:], `"`); end >= 0 {
+				return value[1 : end+1]
+			}
+			return ""
+		}
+		if end := strings.IndexAny(value, " ,"); end >= 0 {
+			return value[:end]
+		}
+		return value
+	}
+	return ""
+}
+
+// resourceCandidate is a URL that may serve the protected resource metadata of a server,
+// with the resource identifier the URL was formed from
+type resourceCandidate struct {
+	url      string
+	resource string
+}
+
+// discover finds the protected resource metadata (nil when the server publishes none) and
+// the authorization server metadata of an MCP server. challenge is the WWW-Authenticate
+// header of a rejected request, if any; its resource_metadata URL must be on the server's
+// origin. The metadata's resource must be the server URL or the identifier of the
+// well-known URL it was found at. The authorization server and its endpoints must use https.
+func (a *Authorizer) discover(ctx context.Context, client *http.Client, serverURL, challenge string) (*ProtectedResourceMetadata, *ServerMetadata, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" {
+		return nil, nil, errors.Errorf("invalid MCP server URL %q", serverURL)
+	}
+	origin := u.Scheme + "://" + u.Host
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+
+	var candidates []resourceCandidate
+	if metadataURL := ChallengeParam(challenge, "resource_metadata"); metadataURL != "" {
+		m, err := url.Parse(metadataURL)
+		if err != nil || !strings.EqualFold(m.Scheme+"://"+m.Host, origin) {
+			return nil, nil, errors.Errorf("resource_metadata %q of MCP server %s is not on the server's origin", metadataURL, serverURL)
+		}
+		candidates = append(candidates, resourceCandidate{url: metadataURL, resource: serverURL})
+	}
+	if path != "" {
+		candidates = append(candidates, resourceCandidate{url: origin + "/.well-known/oauth-protected-resource" + path, resource: serverURL})
+	}
+	candidates = append(candidates, resourceCandidate{url: origin + "/.well-known/oauth-protected-resource", resource: origin})
+
+	var resource *ProtectedResourceMetadata
+	for _, candidate := range candidates {
+		var metadata ProtectedResourceMetadata
+		if getJSON(ctx, client, candidate.url, &metadata) != nil || len(metadata.AuthorizationServers) == 0 {
+			continue
+		}
+		if !sameResource(metadata.Resource, serverURL) && !sameResource(metadata.Resource, candidate.resource) {
+			return nil, nil, errors.Wrapf(ErrResourceMismatch, "%s names resource %q, expected %q", candidate.url, metadata.Resource, serverURL)
+		}
+		resource = &metadata
+		break
+	}
+
+	// Servers without resource metadata act as their own authorization server
+	issuer := origin
+	if resource != nil {
+		issuer = resource.AuthorizationServers[0]
+	}
+	server, err := serverMetadata(ctx, client, issuer)
+	if errors.Is(err, ErrNoMetadata) && resource == nil {
+		// Default endpoints of the 2025-03-26 MCP authorization spec
+		server = &ServerMetadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: origin + "/authorize",
+			TokenEndpoint:         origin + "/token",
+			RegistrationEndpoint:  origin + "/register",
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, endpoint := range []string{server.AuthorizationEndpoint, server.TokenEndpoint, server.RegistrationEndpoint} {
+		if endpoint != "" {
+			if err := requireHTTPS(endpoint); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return resource, server, nil
+}
+
+// requireHTTPS rejects URLs that do not use https
+func requireHTTPS(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Scheme, "https") || u.Host == "" {
+		return errors.Wrapf(ErrInsecureEndpoint, "%q", rawURL)
+	}
+	return nil
+}
+
+// sameResource reports whether two resource identifiers are equal, ignoring a trailing slash
+// and the case of scheme and host
+func sameResource(a, b string) bool {
+	ua, errA := url.Parse(strings.TrimSuffix(a, "/"))
+	ub, errB := url.Parse(strings.TrimSuffix(b, "/"))
+	if errA != nil || errB != nil || ua.Host == "" {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host) &&
+		ua.EscapedPath() == ub.EscapedPath() && ua.RawQuery == ub.RawQuery
+}
+
+// serverMetadata fetches the metadata of the authorization server identified by issuer,
+// trying OAuth and OpenID Connect discovery. Metadata naming another issuer is rejected.
+func serverMetadata(ctx context.Context, client *http.Client, issuer string) (*ServerMetadata, error) {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("invalid authorization server %q", issuer)
+	}
+	if err := requireHTTPS(issuer); err != nil {
+		return nil, errors.Wrap(err, "authorization server")
+	}
+	origin := u.Scheme + "://" + u.Host
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+
+	candidates := []string{
+		origin + "/.well-known/oauth-authorization-server" + path,
+		origin + "/.well-known/openid-configuration" + path,
+	}
+	if path != "" {
+		candidates = append(candidates, origin+path+"/.well-known/openid-configuration")
+	}
+
+	for _, candidate := range candidates {
+		var metadata ServerMetadata
+		if getJSON(ctx, client, candidate, &metadata) != nil {
+			continue
+		}
+		if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" {
+			continue
+		}
+		if metadata.Issuer != issuer {
+			return nil, errors.Wrapf(ErrIssuerMismatch, "%s names issuer %q, expected %q", candidate, metadata.Issuer, issuer)
+		}
+		return &metadata, nil
+	}
+	return nil, errors.Wrapf(ErrNoMetadata, "issuer %s", issuer)
+}
+
+// register registers the host as a public client of the authorization server
+func (a *Authorizer) register(ctx context.Context, client *http.Client, server *ServerMetadata, scopes []string) (clientRegistration, error) {
+	if server.RegistrationEndpoint == "" {
+		return clientRegistration{}, errors.Wrapf(ErrRegistrationUnsupported, "issuer %s", server.Issuer)
+	}
+
+	body, err := json.Marshal(clientRegistration{
+		ClientName:              a.clientName,
+		RedirectURIs:            []string{a.redirectURL},
+		GrantTypes:              []string{"authorization_code", "refresh_token"},
+		ResponseTypes:           []string{"code"},
+		TokenEndpointAuthMethod: "none",
+		Scope:                   strings.Join(scopes, " "),
+	})
+	if err != nil {
+		return clientRegistration{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return clientRegistration{}, errors.Wrap(err, "failed to create client registration request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return clientRegistration{}, errors.Wrap(err, "client registration failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return clientRegistration{}, errors.Errorf("client registration failed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var registration clientRegistration
+	if err := json.NewDecoder(resp.Body).Decode(&registration); err != nil {
+		return clientRegistration{}, errors.Wrap(err, "failed to decode client registration")
+	}
+	if registration.ClientID == "" {
+		return clientRegistration{}, errors.New("client registration returned no client_id")
+	}
+	return registration, nil
+}
+
+// getJSON decodes the JSON document at rawURL into v
+func getJSON(ctx context.Context, client *http.Client, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("GET %s: status %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
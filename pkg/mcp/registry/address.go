@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAddressNotAllowed indicates a user server address in a loopback, private, link-local
+// or otherwise internal network
+var ErrAddressNotAllowed = errors.New("address of MCP server is not allowed")
+
+// deniedNetworks are internal ranges not covered by the net.IP predicates: "this network",
+// carrier-grade NAT (used by some cloud metadata services), IETF protocol assignments and
+// the benchmarking range
+var deniedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+)
+
+// AddressGuard keeps user-registered servers from reaching the host's internal network.
+// Loopback, private, link-local (including the cloud metadata address) and similar ranges
+// are denied unless listed in the allow list. Addresses are checked when the connection is
+// dialed, so DNS names that later resolve to internal addresses are rejected too.
+type AddressGuard struct {
+	allowed []*net.IPNet
+}
+
+// NewAddressGuard creates a guard that additionally allows the networks in CIDR notation
+func NewAddressGuard(allowedNetworks []string) (*AddressGuard, error) {
+	g := &AddressGuard{}
+	for _, cidr := range allowedNetworks {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid allowed network %q", cidr)
+		}
+		g.allowed = append(g.allowed, network)
+	}
+	return g, nil
+}
+
+// Allowed reports whether user servers may connect to the IP address
+func (g *AddressGuard) Allowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range deniedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL rejects server URLs whose host is or resolves to a denied address. Hosts that do
+// not resolve are accepted; the dial-time check still applies once they do.
+func (g *AddressGuard) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid MCP server URL %q", rawURL)
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !g.Allowed(ip) {
+			return errors.Wrapf(ErrAddressNotAllowed, "%s", ip)
+		}
+		return nil
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return errors.Wrapf(ErrAddressNotAllowed, "%s", host)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !g.Allowed(addr.IP) {
+			return errors.Wrapf(ErrAddressNotAllowed, "%s resolves to %s", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// Transport returns an HTTP transport that refuses to connect to denied addresses. It does
+// not use a proxy, which would connect on its behalf.
+func (g *AddressGuard) Transport() http.RoundTripper {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// control checks the resolved address of every connection before it is made
+func (g *AddressGuard) control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrapf(ErrAddressNotAllowed, "%s", address)
+	}
+	if !g.Allowed(net.ParseIP(host)) {
+		return errors.Wrapf(ErrAddressNotAllowed, "%s", host)
+	}
+	return nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,206 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// ErrServerNotFound indicates that the user has no server with the given ID
+var ErrServerNotFound = errors.New("MCP server not found")
+
+// Secrets are the credentials of a user's server. They are stored encrypted and never
+// returned by the API.
+type Secrets struct {
+	// Headers are sent with every request to the server
+	Headers map[string]string `json:"headers,omitempty"`
+	// Token is sent as "Authorization: Bearer <token>" unless Headers set Authorization
+	Token string `json:"token,omitempty"`
+}
+
+// Registry stores the MCP servers registered by users, with their secrets sealed by a
+// SecretBox. It provides them to the manager as a manager.UserServerStore, with a transport
+// that keeps them out of internal networks.
+type Registry struct {
+	db        *gorm.DB
+	box       *auth.SecretBox
+	guard     *AddressGuard
+	transport http.RoundTripper
+}
+
+// New creates a registry that encrypts secrets with box and restricts server addresses with
+// guard (nil = internal networks are denied without exceptions)
+func New(db *gorm.DB, box *auth.SecretBox, guard *AddressGuard) *Registry {
+	if guard == nil {
+		guard = &AddressGuard{}
+	}
+	return &Registry{db: db, box: box, guard: guard, transport: guard.Transport()}
+}
+
+// CheckURL reports ErrAddressNotAllowed for server URLs in denied networks
+func (r *Registry) CheckURL(ctx context.Context, rawURL string) error {
+	return r.guard.CheckURL(ctx, rawURL)
+}
+
+// Transport implements manager.UserServerTransport
+func (r *Registry) Transport() http.RoundTripper {
+	return r.transport
+}
+
+// ListUserServers returns the enabled servers of the user as server configurations.
+// Servers whose secrets cannot be decrypted are skipped.
+func (r *Registry) ListUserServers(ctx context.Context, userID uuid.UUID) ([]config.MCPServerConfig, error) {
+	var servers []models.UserMCPServer
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND enabled", userID).
+		Order("name").
+		Find(&servers).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to list user MCP servers")
+	}
+
+	configs := make([]config.MCPServerConfig, 0, len(servers))
+	for i := range servers {
+		cfg, err := r.ServerConfig(&servers[i])
+		if err != nil {
+			logging.LogWarningf(err, "Skipping MCP server %s of user %s", servers[i].Name, userID)
+			continue
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// ServerConfig converts a stored server into the configuration the manager connects with
+func (r *Registry) ServerConfig(server *models.UserMCPServer) (config.MCPServerConfig, error) {
+	secrets, err := r.Secrets(server)
+	if err != nil {
+		return config.MCPServerConfig{}, err
+	}
+
+	headers := make(map[string]string, len(secrets.Headers)+1)
+	for k, v := range secrets.Headers {
+		headers[k] = v
+	}
+	if secrets.Token != "" && !hasHeader(headers, "Authorization") {
+		headers["Authorization"] = "Bearer " + secrets.Token
+	}
+
+	return config.MCPServerConfig{
+		Name:            server.Name,
+		Type:            "http",
+		Mode:            server.Mode,
+		URL:             server.URL,
+		Headers:         headers,
+		Enabled:         server.Enabled,
+		Description:     server.Description,
+		RequireApproval: server.RequireApproval,
+	}, nil
+}
+
+// Secrets decrypts the secrets of a stored server
+func (r *Registry) Secrets(server *models.UserMCPServer) (Secrets, error) {
+	var secrets Secrets
+	if len(server.EncryptedSecrets) == 0 {
+		return secrets, nil
+	}
+	plaintext, err := r.box.Open(server.EncryptedSecrets, secretsAAD(server))
+	if err != nil {
+		return secrets, errors.Wrapf(err, "failed to decrypt secrets of MCP server %s", server.Name)
+	}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return secrets, errors.Wrapf(err, "failed to decode secrets of MCP server %s", server.Name)
+	}
+	return secrets, nil
+}
+
+// SetSecrets encrypts secrets into the stored server. New servers are assigned their ID
+// here, as the secrets are bound to it.
+func (r *Registry) SetSecrets(server *models.UserMCPServer, secrets Secrets) error {
+	if len(secrets.Headers) == 0 && secrets.Token == "" {
+		server.EncryptedSecrets = nil
+		return nil
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	if server.ID == uuid.Nil {
+		server.ID = uuid.New()
+	}
+	sealed, err := r.box.Seal(plaintext, secretsAAD(server))
+	if err != nil {
+		return errors.Wrapf(err, "failed to encrypt secrets of MCP server %s", server.Name)
+	}
+	server.EncryptedSecrets = sealed
+	return nil
+}
+
+// secretsAAD binds the secrets of a server to its row
+func secretsAAD(server *models.UserMCPServer) []byte {
+	return []byte("user_mcp_servers|" + server.ID.String() + "|" + server.UserID.String())
+}
+
+// List returns all servers of the user
+func (r *Registry) List(ctx context.Context, userID uuid.UUID) ([]models.UserMCPServer, error) {
+	var servers []models.UserMCPServer
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("name").Find(&servers).Error
+	return servers, errors.Wrap(err, "failed to list user MCP servers")
+}
+
+// Get returns a server of the user
+func (r *Registry) Get(ctx context.Context, userID, id uuid.UUID) (*models.UserMCPServer, error) {
+	var server models.UserMCPServer
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&server).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrServerNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user MCP server")
+	}
+	return &server, nil
+}
+
+// NameTaken reports whether the user has another server (not excludeID) with the name
+func (r *Registry) NameTaken(ctx context.Context, userID uuid.UUID, name string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.UserMCPServer{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, excludeID).
+		Count(&count).Error
+	return count > 0, errors.Wrap(err, "failed to check MCP server name")
+}
+
+// Save creates or updates a server
+func (r *Registry) Save(ctx context.Context, server *models.UserMCPServer) error {
+	return errors.Wrap(r.db.WithContext(ctx).Save(server).Error, "failed to save user MCP server")
+}
+
+// Delete removes a server of the user
+func (r *Registry) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserMCPServer{})
+	if result.Error != nil {
+		return errors.Wrap(result.Error, "failed to delete user MCP server")
+	}
+	if result.RowsAffected == 0 {
+		return ErrServerNotFound
+	}
+	return nil
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if http.CanonicalHeaderKey(k) == name {
+			return true
+		}
+	}
+	return false
+}
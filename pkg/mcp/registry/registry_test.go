@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+)
+
+func TestServerConfig_DecryptsSecrets(t *testing.T) {
+	box, err := auth.NewSecretBox("test-key")
+	require.NoError(t, err)
+	r := New(nil, box, nil)
+
+	server := &models.UserMCPServer{Name: "notes", URL: "https://notes.example.com/mcp", Enabled: true}
+	require.NoError(t, r.SetSecrets(server, Secrets{Headers: map[string]string{"X-Team": "a"}, Token: "secret"}))
+	assert.NotContains(t, string(server.EncryptedSecrets), "secret")
+
+	cfg, err := r.ServerConfig(server)
+	require.NoError(t, err)
+	assert.Equal(t, "http", cfg.Type)
+	assert.False(t, cfg.ForwardBearer)
+	assert.Equal(t, map[string]string{"X-Team": "a", "Authorization": "Bearer secret"}, cfg.Headers)
+
+	// An explicit Authorization header wins over the token
+	require.NoError(t, r.SetSecrets(server, Secrets{Headers: map[string]string{"authorization": "Basic abc"}, Token: "secret"}))
+	cfg, err = r.ServerConfig(server)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"authorization": "Basic abc"}, cfg.Headers)
+
+	// Secrets sealed with another key cannot be read
+	other, err := auth.NewSecretBox("other-key")
+	require.NoError(t, err)
+	_, err = New(nil, other, nil).ServerConfig(server)
+	assert.ErrorIs(t, err, auth.ErrSecretDecryption)
+
+	// Secrets copied to a server of another user cannot be read
+	copied := &models.UserMCPServer{ID: server.ID, UserID: uuid.New(), Name: "notes", URL: server.URL, Enabled: true, EncryptedSecrets: server.EncryptedSecrets}
+	_, err = r.ServerConfig(copied)
+	assert.ErrorIs(t, err, auth.ErrSecretDecryption)
+}
+
+func TestAddressGuard(t *testing.T) {
+	guard, err := NewAddressGuard(nil)
+	require.NoError(t, err)
+	for _, addr := range []string{"127.0.0.1", "10.0.0.1", "192.168.1.1", "169.254.169.254", "100.100.100.200", "0.0.0.0", "::1", "fd00:ec2::254", "fe80::1", "::ffff:127.0.0.1"} {
+		assert.False(t, guard.Allowed(net.ParseIP(addr)), addr)
+	}
+	assert.True(t, guard.Allowed(net.ParseIP("93.184.216.34")))
+
+	assert.ErrorIs(t, guard.CheckURL(context.Background(), "http://127.0.0.1:8080/mcp"), ErrAddressNotAllowed)
+	assert.ErrorIs(t, guard.CheckURL(context.Background(), "http://[::1]/mcp"), ErrAddressNotAllowed)
+	assert.ErrorIs(t, guard.CheckURL(context.Background(), "http://localhost/mcp"), ErrAddressNotAllowed)
+	assert.NoError(t, guard.CheckURL(context.Background(), "https://93.184.216.34/mcp"))
+
+	// Connections are checked when dialed, whatever the URL looked like before
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer httpServer.Close()
+	_, err = (&http.Client{Transport: guard.Transport()}).Get(httpServer.URL)
+	assert.ErrorIs(t, err, ErrAddressNotAllowed)
+
+	allowing, err := NewAddressGuard([]string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: allowing.Transport()}).Get(httpServer.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	_, err = NewAddressGuard([]string{"10.0.0.0"})
+	assert.Error(t, err)
+}
@@ -2,16 +2,24 @@ package mcphost
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"github.com/d4l-data4life/go-mcp-host/pkg/agent"
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
 	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/handlers"
 	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	llmanthropic "github.com/d4l-data4life/go-mcp-host/pkg/llm/anthropic"
 	llmopenai "github.com/d4l-data4life/go-mcp-host/pkg/llm/openai"
 	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/oauth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/registry"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
 )
 
 // Host is the main entry point for embedding MCP Host functionality in your application.
@@ -20,6 +28,7 @@ type Host struct {
 	agent      *agent.Agent
 	mcpManager *manager.Manager
 	llmClient  llm.Client
+	embedder   llm.Embedder
 	config     Config
 }
 
@@ -28,6 +37,10 @@ type Config struct {
 	// MCPServers is the list of MCP servers to connect to
 	MCPServers []config.MCPServerConfig
 
+	// LLMProvider selects the built-in LLM client: "openai" (default, also used for Ollama)
+	// or "anthropic" for the native Anthropic Messages API. Ignored when LLMClient is set.
+	LLMProvider string
+
 	// OpenAIBaseURL is the base URL of your OpenAI-compatible endpoint.
 	// Example: "https://api.openai.com/v1" or "http://localhost:11434/v1" for Ollama.
 	OpenAIBaseURL string
@@ -38,12 +51,52 @@ type Config struct {
 	// OpenAIModel is the default model to use for the LLM client.
 	OpenAIDefaultModel string
 
+	// OpenAIEmbeddingModel is the default model for embeddings (optional, defaults to
+	// OPENAI_EMBEDDING_MODEL or "text-embedding-3-small").
+	OpenAIEmbeddingModel string
+
+	// AnthropicBaseURL optionally overrides the base URL of the Anthropic Messages API.
+	AnthropicBaseURL string
+
+	// AnthropicAPIKey optionally overrides the Anthropic API key.
+	AnthropicAPIKey string
+
+	// AnthropicDefaultModel is the default model used when LLMProvider is "anthropic".
+	AnthropicDefaultModel string
+
 	// LLMClient allows providing a fully custom llm.Client implementation.
 	LLMClient llm.Client
 
+	// Embedder optionally overrides the embeddings backend. By default the LLM client is
+	// used when it implements llm.Embedder.
+	Embedder llm.Embedder
+
+	// SamplingApprover decides per request whether an MCP server may sample the LLM
+	// (optional; servers must also opt in via MCPServerConfig.Sampling).
+	SamplingApprover manager.SamplingApprover
+
 	// Database connection (required for conversation persistence)
 	DB *gorm.DB
 
+	// SecretsKey enables MCP servers registered by users (stored in DB) and encrypts their
+	// headers and tokens (optional; defaults to MCP_SECRETS_KEY, user servers are disabled
+	// without a key).
+	SecretsKey string
+
+	// UserServerAllowedNetworks are internal networks (CIDR notation) that user-registered
+	// servers may connect to; loopback, private and link-local addresses are denied otherwise
+	// (optional; defaults to MCP_USER_SERVER_ALLOWED_NETWORKS).
+	UserServerAllowedNetworks []string
+
+	// OAuthRedirectURL enables the OAuth flow for HTTP MCP servers; it must be the public URL
+	// of /api/v1/oauth/callback (optional; defaults to MCP_OAUTH_REDIRECT_URL, requires a
+	// SecretsKey to store the tokens).
+	OAuthRedirectURL string
+
+	// OAuthSuccessURL is where the browser is sent after a completed OAuth flow (optional;
+	// defaults to MCP_OAUTH_SUCCESS_URL).
+	OAuthSuccessURL string
+
 	// Agent configuration (optional, defaults will be used)
 	AgentConfig agent.Config
 }
@@ -66,15 +119,42 @@ type Config struct {
 //	    DB:          db,
 //	})
 func NewHost(ctx context.Context, cfg Config) (*Host, error) {
-	// Create MCP manager
-	mcpManager := manager.NewMCPManager(cfg.MCPServers)
 	openAIConfig := config.GetOpenAIConfig()
 
 	// Set agent defaults if not provided
 	agentConfig := cfg.AgentConfig
 
+	provider := cfg.LLMProvider
+	if provider == "" {
+		provider = config.GetLLMProvider()
+	}
+
 	// Create LLM client
 	llmClient := cfg.LLMClient
+	if llmClient == nil && provider == config.LLMProviderAnthropic {
+		anthropicConfig := config.GetAnthropicConfig()
+		baseURL := cfg.AnthropicBaseURL
+		if baseURL == "" {
+			baseURL = anthropicConfig.BaseURL
+		}
+		apiKey := cfg.AnthropicAPIKey
+		if apiKey == "" {
+			apiKey = anthropicConfig.APIKey
+		}
+		if agentConfig.DefaultModel == "" {
+			agentConfig.DefaultModel = cfg.AnthropicDefaultModel
+		}
+		if agentConfig.DefaultModel == "" {
+			agentConfig.DefaultModel = anthropicConfig.DefaultModel
+		}
+		llmClient = llmanthropic.NewClient(llmanthropic.Config{
+			APIKey:    apiKey,
+			BaseURL:   baseURL,
+			Model:     agentConfig.DefaultModel,
+			MaxTokens: anthropicConfig.MaxTokens,
+			Timeout:   parseTimeout(anthropicConfig.RequestTimeout),
+		})
+	}
 	if llmClient == nil {
 		baseURL := cfg.OpenAIBaseURL
 		if baseURL == "" {
@@ -88,19 +168,63 @@ func NewHost(ctx context.Context, cfg Config) (*Host, error) {
 			agentConfig.DefaultModel = cfg.OpenAIDefaultModel
 		}
 		llmClient = llmopenai.NewClient(llmopenai.Config{
-			APIKey:  apiKey,
-			BaseURL: baseURL,
-			Model:   agentConfig.DefaultModel,
+			APIKey:         apiKey,
+			BaseURL:        baseURL,
+			Model:          agentConfig.DefaultModel,
+			EmbeddingModel: cfg.OpenAIEmbeddingModel,
 		})
 	}
 
+	embedder := cfg.Embedder
+	if embedder == nil {
+		embedder = llm.EmbedderFor(llmClient)
+	}
+
+	// Create MCP manager; sampling requests are served by the same LLM client
+	managerOptions := []manager.Option{manager.WithSampling(llmClient, cfg.SamplingApprover)}
+	secretsKey := cfg.SecretsKey
+	if secretsKey == "" {
+		secretsKey = config.GetSecretsKey()
+	}
+	if secretsKey != "" && cfg.DB != nil {
+		box, err := auth.NewSecretBox(secretsKey)
+		if err != nil {
+			return nil, err
+		}
+		allowedNetworks := cfg.UserServerAllowedNetworks
+		if allowedNetworks == nil {
+			allowedNetworks = config.GetUserServerAllowedNetworks()
+		}
+		guard, err := registry.NewAddressGuard(allowedNetworks)
+		if err != nil {
+			return nil, err
+		}
+		managerOptions = append(managerOptions, manager.WithUserServers(registry.New(cfg.DB, box, guard)))
+
+		oauthSettings := config.GetOAuthSettings()
+		if cfg.OAuthRedirectURL != "" {
+			oauthSettings.RedirectURL = cfg.OAuthRedirectURL
+		}
+		if cfg.OAuthSuccessURL != "" {
+			oauthSettings.SuccessURL = cfg.OAuthSuccessURL
+		}
+		if oauthSettings.RedirectURL != "" {
+			managerOptions = append(managerOptions, manager.WithOAuth(oauth.New(cfg.DB, box, oauthSettings)))
+		}
+	}
+	mcpManager := manager.NewMCPManager(cfg.MCPServers, managerOptions...)
+
 	// Create agent
-	agent := agent.NewAgent(cfg.DB, mcpManager, llmClient, agentConfig)
+	agent, err := agent.NewAgent(cfg.DB, mcpManager, llmClient, agentConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Host{
 		agent:      agent,
 		mcpManager: mcpManager,
 		llmClient:  llmClient,
+		embedder:   embedder,
 		config:     cfg,
 	}, nil
 }
@@ -123,6 +247,10 @@ func (h *Host) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
 		UserMessage:    req.UserMessage,
 		Messages:       req.Messages,
 		Model:          req.Model,
+		PinnedTools:    req.PinnedTools,
+		ToolFilter:     req.ToolFilter,
+		Claims:         req.Claims,
+		Approvals:      req.Approvals,
 	}
 
 	agentResp, err := h.agent.Chat(ctx, agentReq)
@@ -132,9 +260,11 @@ func (h *Host) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
 
 	return &ChatResponse{
 		Message:     agentResp.Message,
+		Messages:    agentResp.Messages,
 		ToolsUsed:   convertToolExecutions(agentResp.ToolsUsed),
 		Iterations:  agentResp.Iterations,
 		TotalTokens: agentResp.TotalTokens,
+		Context:     agentResp.Context,
 		Error:       agentResp.Error,
 	}, nil
 }
@@ -164,6 +294,10 @@ func (h *Host) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamEv
 		UserMessage:    req.UserMessage,
 		Messages:       req.Messages,
 		Model:          req.Model,
+		PinnedTools:    req.PinnedTools,
+		ToolFilter:     req.ToolFilter,
+		Claims:         req.Claims,
+		Approvals:      req.Approvals,
 	}
 
 	agentStream, err := h.agent.ChatStream(ctx, agentReq)
@@ -177,12 +311,16 @@ func (h *Host) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamEv
 		defer close(hostStream)
 		for event := range agentStream {
 			hostStream <- StreamEvent{
-				Type:    StreamEventType(event.Type),
-				Content: event.Content,
-				Tool:    convertToolExecution(event.Tool),
-				Delta:   event.Delta,
-				Done:    event.Done,
-				Error:   event.Error,
+				Type:        StreamEventType(event.Type),
+				Content:     event.Content,
+				Tool:        convertToolExecution(event.Tool),
+				ToolCall:    event.ToolCall,
+				Delta:       event.Delta,
+				Messages:    event.Messages,
+				Elicitation: event.Elicitation,
+				Context:     event.Context,
+				Done:        event.Done,
+				Error:       event.Error,
 			}
 		}
 	}()
@@ -200,25 +338,57 @@ func (h *Host) GetAvailableResources(ctx context.Context, userID uuid.UUID, bear
 	return h.agent.GetAvailableResources(ctx, userID, bearerToken)
 }
 
+// RespondToElicitation answers an elicitation_request event of a streamed conversation.
+func (h *Host) RespondToElicitation(conversationID uuid.UUID, elicitationID string, response ElicitationResponse) error {
+	return h.agent.RespondToElicitation(conversationID, elicitationID, response)
+}
+
+// SetUserRoots replaces the runtime roots a user exposes to an MCP server, in addition to the
+// server's configured roots. Active sessions notify the server with roots/list_changed.
+func (h *Host) SetUserRoots(userID uuid.UUID, serverName string, roots []config.RootConfig) {
+	h.mcpManager.SetUserRoots(userID, serverName, roots)
+}
+
 // CloseConversation cleans up resources for a conversation.
 // Call this when a conversation is complete to free up MCP sessions.
 func (h *Host) CloseConversation(conversationID uuid.UUID) error {
 	return h.agent.CloseConversation(conversationID)
 }
 
-// ServeHTTP sets up HTTP routes on the provided chi router.
-// This is useful if you want to expose the MCP Host as a web service.
+// ServeHTTP mounts the REST/WebSocket API (conversations, messages incl. WebSocket and SSE
+// streaming, MCP servers/tools/resources and optionally local auth) under /api/v1 on the
+// provided chi router. The handlers use the Host's agent, MCP manager and DB, so
+// Config.DB is required (ErrNoDB). Authentication is configured with WithTokenValidator,
+// WithUserResolver, WithClaimsResolver or WithLocalAuth (ErrNoHTTPAuth without any). On
+// error, no routes are mounted.
 //
 // Example:
 //
 //	r := chi.NewRouter()
-//	host.ServeHTTP(r)
+//	err := host.ServeHTTP(r, mcphost.WithUserResolver(func(r *http.Request) (uuid.UUID, error) {
+//	    return myAuth.UserID(r)
+//	}))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
 //	http.ListenAndServe(":8080", r)
-func (h *Host) ServeHTTP(r chi.Router) {
-	// This would integrate with the existing handlers package
-	// For now, this is a placeholder - full implementation would wire up
-	// handlers with the host's agent and mcpManager
-	// TODO: Implement route setup
+func (h *Host) ServeHTTP(r chi.Router, opts ...HTTPOption) error {
+	if h.config.DB == nil {
+		return ErrNoDB
+	}
+
+	options := &httpOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	authMiddleware, localAuth, err := options.authMiddleware(h)
+	if err != nil {
+		return err
+	}
+
+	handlers.RegisterAPIRoutes(r, h.config.DB, h.agent, h.mcpManager, authMiddleware, localAuth)
+	return nil
 }
 
 // Agent returns the underlying agent for advanced usage
@@ -236,18 +406,34 @@ func (h *Host) LLMClient() llm.Client {
 	return h.llmClient
 }
 
+// Embedder returns the embeddings backend, or nil when the LLM client provides none
+func (h *Host) Embedder() llm.Embedder {
+	return h.embedder
+}
+
+// Embed computes one embedding vector per text. An empty model uses the default
+// embedding model (see Config.OpenAIEmbeddingModel).
+func (h *Host) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if h.embedder == nil {
+		return nil, llm.ErrEmbeddingsUnsupported
+	}
+	return h.embedder.Embed(ctx, model, texts)
+}
+
 // Helper conversion functions
 
 func convertToolExecutions(executions []agent.ToolExecution) []ToolExecution {
 	result := make([]ToolExecution, len(executions))
 	for i, e := range executions {
 		result[i] = ToolExecution{
+			ToolCallID: e.ToolCallID,
 			ServerName: e.ServerName,
 			ToolName:   e.ToolName,
 			Arguments:  e.Arguments,
 			Result:     e.Result,
 			Error:      e.Error,
 			Duration:   e.Duration,
+			Approval:   e.Approval,
 		}
 	}
 	return result
@@ -258,11 +444,26 @@ func convertToolExecution(execution *agent.ToolExecution) *ToolExecution {
 		return nil
 	}
 	return &ToolExecution{
+		ToolCallID: execution.ToolCallID,
 		ServerName: execution.ServerName,
 		ToolName:   execution.ToolName,
 		Arguments:  execution.Arguments,
 		Result:     execution.Result,
 		Error:      execution.Error,
 		Duration:   execution.Duration,
+		Approval:   execution.Approval,
+	}
+}
+
+// parseTimeout parses a configured request timeout; empty or invalid values use the client default
+func parseTimeout(raw string) time.Duration {
+	if raw == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		logging.LogWarningf(err, "Invalid request timeout %q, using client default", raw)
+		return 0
 	}
+	return timeout
 }
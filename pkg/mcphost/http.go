@@ -0,0 +1,93 @@
+package mcphost
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+	"github.com/d4l-data4life/go-mcp-host/pkg/handlers"
+)
+
+var (
+	// ErrNoDB indicates Host.ServeHTTP on a host without Config.DB
+	ErrNoDB = errors.New("mcphost: the HTTP API requires Config.DB")
+	// ErrNoHTTPAuth indicates Host.ServeHTTP without an authentication option
+	ErrNoHTTPAuth = errors.New("mcphost: the HTTP API requires WithTokenValidator, WithUserResolver, WithClaimsResolver or WithLocalAuth")
+)
+
+// HTTPOption configures the API mounted by Host.ServeHTTP
+type HTTPOption func(*httpOptions)
+
+type httpOptions struct {
+	tokenValidator auth.TokenValidator
+	userResolver   handlers.UserResolver
+	jwtSecret      []byte
+}
+
+// WithTokenValidator authenticates requests by validating their bearer JWT, e.g. with an
+// auth.OIDCValidator or auth.RemoteKeyStore. The user ID is taken from the oid, sub or
+// email claim unless the validator is wrapped with auth.WithClaimMapping.
+func WithTokenValidator(validator auth.TokenValidator) HTTPOption {
+	return func(o *httpOptions) {
+		o.tokenValidator = validator
+	}
+}
+
+// WithUserResolver authenticates requests with a custom function that returns the user ID
+// for a request, for hosts that already authenticate users themselves. It takes precedence
+// over WithTokenValidator. Use WithClaimsResolver for tool policies that match claims.
+func WithUserResolver(resolve func(r *http.Request) (uuid.UUID, error)) HTTPOption {
+	return func(o *httpOptions) {
+		o.userResolver = func(r *http.Request) (uuid.UUID, *auth.Claims, error) {
+			userID, err := resolve(r)
+			return userID, nil, err
+		}
+	}
+}
+
+// WithClaimsResolver is WithUserResolver for hosts that also know the user's claims. The
+// groups, roles and scopes of the claims are evaluated by the agent's tool policy, and
+// name and email are synced into the user.
+func WithClaimsResolver(resolve func(r *http.Request) (uuid.UUID, *Claims, error)) HTTPOption {
+	return func(o *httpOptions) {
+		o.userResolver = resolve
+	}
+}
+
+// WithLocalAuth mounts the /auth register, login, refresh and logout routes. Logins are
+// sessions stored in the DB whose access tokens are HS256 JWTs signed with jwtSecret (see
+// auth.LocalAuth). Unless another validator is configured, those tokens are used to
+// authenticate the API.
+func WithLocalAuth(jwtSecret []byte) HTTPOption {
+	return func(o *httpOptions) {
+		o.jwtSecret = jwtSecret
+	}
+}
+
+// authMiddleware picks the authentication for the mounted API and creates the local auth
+// when configured
+func (o *httpOptions) authMiddleware(h *Host) (func(http.Handler) http.Handler, *auth.LocalAuth, error) {
+	var localAuth *auth.LocalAuth
+	if len(o.jwtSecret) > 0 {
+		var err error
+		localAuth, err = auth.NewLocalAuth(h.config.DB, o.jwtSecret, config.GetLocalAuthSettings())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if o.userResolver != nil {
+		return handlers.ResolverAuthMiddleware(h.config.DB, o.userResolver), localAuth, nil
+	}
+	validator := o.tokenValidator
+	if validator == nil && localAuth != nil {
+		validator = localAuth
+	}
+	if validator == nil {
+		return nil, nil, ErrNoHTTPAuth
+	}
+	return handlers.AuthMiddleware(h.config.DB, validator), localAuth, nil
+}
@@ -0,0 +1,46 @@
+package mcphost
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestServeHTTP_MountsAPIWithUserResolver(t *testing.T) {
+	host := &Host{config: Config{DB: &gorm.DB{}}}
+	r := chi.NewRouter()
+	require.NoError(t, host.ServeHTTP(r, WithUserResolver(func(*http.Request) (uuid.UUID, error) {
+		return uuid.Nil, errors.New("no session")
+	})))
+
+	routes := map[string]bool{}
+	_ = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes[method+" "+strings.ReplaceAll(route, "/*/", "/")] = true
+		return nil
+	})
+	assert.True(t, routes["GET /api/v1/conversations/"])
+	assert.True(t, routes["POST /api/v1/conversations/{id}/messages/"])
+	assert.True(t, routes["GET /api/v1/conversations/{id}/messages/stream"])
+	assert.True(t, routes["POST /api/v1/conversations/{id}/messages/sse"])
+	assert.True(t, routes["GET /api/v1/mcp/tools"])
+	assert.False(t, routes["POST /api/v1/auth/login"], "auth routes require WithLocalAuth")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/conversations/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestServeHTTP_RejectsIncompleteConfiguration(t *testing.T) {
+	r := chi.NewRouter()
+	assert.ErrorIs(t, (&Host{}).ServeHTTP(r, WithLocalAuth([]byte("secret"))), ErrNoDB)
+	assert.ErrorIs(t, (&Host{config: Config{DB: &gorm.DB{}}}).ServeHTTP(r), ErrNoHTTPAuth)
+	assert.Empty(t, r.Routes())
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/d4l-data4life/go-mcp-host/pkg/agent"
+	"github.com/d4l-data4life/go-mcp-host/pkg/auth"
 	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
 )
 
@@ -28,6 +29,22 @@ type ChatRequest struct {
 
 	// Model is the LLM model to use (optional, defaults to agent config)
 	Model string
+
+	// PinnedTools are always offered to the LLM when AgentConfig.MaxTools limits the tools
+	// (optional; qualified tool names <server>__<tool> or server names)
+	PinnedTools []string
+
+	// ToolFilter restricts the MCP servers and tools the request can use
+	// (optional; nil allows all tools)
+	ToolFilter *ToolFilter
+
+	// Claims of the user's validated token, evaluated by AgentConfig.ToolPolicy
+	// (optional; nil carries no groups, roles or scopes)
+	Claims *Claims
+
+	// Approvals receives decisions for tool calls paused by a tool_approval_required event
+	// (optional; without it, tools that require approval are denied)
+	Approvals *ToolApprovals
 }
 
 // ChatResponse represents the agent's response
@@ -35,6 +52,10 @@ type ChatResponse struct {
 	// Message is the assistant's response message
 	Message llm.Message
 
+	// Messages is the transcript produced after the user message: assistant tool-call
+	// turns and tool results in order, ending with Message. Store it to replay tool context.
+	Messages []llm.Message
+
 	// ToolsUsed lists all tools that were executed during this chat
 	ToolsUsed []ToolExecution
 
@@ -44,6 +65,9 @@ type ChatResponse struct {
 	// TotalTokens is the total number of tokens used
 	TotalTokens int
 
+	// Context reports history pruned to the token budget and resources injected as context
+	Context *ContextReport
+
 	// Error contains any error that occurred
 	Error error
 }
@@ -59,9 +83,21 @@ type StreamEvent struct {
 	// Tool is the tool execution info (for tool events)
 	Tool *ToolExecution
 
+	// ToolCall is the partially generated tool call (for tool_call_delta events)
+	ToolCall *ToolCallDelta
+
 	// Delta is the streaming delta (for partial content)
 	Delta *llm.Delta
 
+	// Messages is the turn's transcript (for done events, see ChatResponse.Messages)
+	Messages []llm.Message
+
+	// Elicitation is the server's request for user input (for elicitation_request events)
+	Elicitation *ElicitationRequest
+
+	// Context is the context report (for done events, see ChatResponse.Context)
+	Context *ContextReport
+
 	// Done indicates if the stream is complete
 	Done bool
 
@@ -76,9 +112,16 @@ const (
 	// StreamEventTypeContent indicates text content
 	StreamEventTypeContent StreamEventType = "content"
 
+	// StreamEventTypeToolCallDelta indicates the LLM is generating a tool call
+	StreamEventTypeToolCallDelta StreamEventType = "tool_call_delta"
+
 	// StreamEventTypeToolStart indicates a tool execution is starting
 	StreamEventTypeToolStart StreamEventType = "tool_start"
 
+	// StreamEventTypeToolApprovalRequired indicates a tool call is paused until it is
+	// approved or denied via ChatRequest.Approvals
+	StreamEventTypeToolApprovalRequired StreamEventType = "tool_approval_required"
+
 	// StreamEventTypeToolComplete indicates a tool execution is complete
 	StreamEventTypeToolComplete StreamEventType = "tool_complete"
 
@@ -87,10 +130,17 @@ const (
 
 	// StreamEventTypeError indicates an error occurred
 	StreamEventTypeError StreamEventType = "error"
+
+	// StreamEventTypeElicitation indicates an MCP server asks the user for input;
+	// answer it with Host.RespondToElicitation
+	StreamEventTypeElicitation StreamEventType = "elicitation_request"
 )
 
 // ToolExecution represents a tool execution
 type ToolExecution struct {
+	// ToolCallID is the LLM-assigned ID of the call; it pairs tool_start and tool_complete events
+	ToolCallID string
+
 	// ServerName is the MCP server that provided this tool
 	ServerName string
 
@@ -108,8 +158,40 @@ type ToolExecution struct {
 
 	// Duration is how long the tool took to execute
 	Duration time.Duration
+
+	// Approval is the user's decision for tools that require approval (nil otherwise)
+	Approval *ToolApprovalDecision
 }
 
+// ToolApprovals routes approve/deny decisions to paused tool calls
+type ToolApprovals = agent.ToolApprovals
+
+// ToolApprovalDecision is the user's answer to a tool_approval_required event
+type ToolApprovalDecision = agent.ToolApprovalDecision
+
+// ContextReport describes how the conversation was fitted into the model's context
+type ContextReport = agent.ContextReport
+
+// ToolFilter restricts the servers and tools of a request to a selection
+type ToolFilter = agent.ToolFilter
+
+// Claims are the groups, roles and scopes of the user's token
+type Claims = auth.Claims
+
+// ElicitationRequest is an MCP server's request for structured user input
+type ElicitationRequest = agent.ElicitationRequest
+
+// ElicitationResponse is the user's answer to an elicitation_request event
+type ElicitationResponse = agent.ElicitationResponse
+
+// NewToolApprovals creates an approval registry to pass with ChatRequest.Approvals
+func NewToolApprovals() *ToolApprovals {
+	return agent.NewToolApprovals()
+}
+
+// ToolCallDelta describes a tool call while the LLM is still generating it
+type ToolCallDelta = agent.ToolCallDelta
+
 // ToolInfo represents information about an available tool
 type ToolInfo = agent.ToolInfo
 
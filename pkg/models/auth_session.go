@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuthSession is a login of a local user. Access tokens name the session and are only
+// accepted while it exists; deleting it revokes them together with the refresh token.
+type AuthSession struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"    json:"id"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;index;constraint:OnDelete:CASCADE" json:"userId"`
+	// RefreshTokenHash is the SHA-256 of the current refresh token, which is rotated on use
+	RefreshTokenHash string `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	// ExpiresAt is when the session ends unless it is refreshed, never after MaxExpiresAt
+	ExpiresAt time.Time `gorm:"not null;index" json:"expiresAt"`
+	// MaxExpiresAt is when the session ends regardless of refreshes
+	MaxExpiresAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"maxExpiresAt"`
+	CreatedAt    time.Time `                                          json:"createdAt"`
+	UpdatedAt    time.Time `                                          json:"updatedAt"`
+
+	// Associations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for AuthSession model
+func (AuthSession) TableName() string {
+	return "auth_sessions"
+}
+
+// AuthRotatedRefreshToken is a refresh token of a session that was already rotated. Its
+// reuse means that it leaked, so the session is revoked.
+type AuthRotatedRefreshToken struct {
+	// TokenHash is the SHA-256 of the rotated refresh token
+	TokenHash string    `gorm:"size:64;primaryKey"       json:"-"`
+	SessionID uuid.UUID `gorm:"type:uuid;not null;index" json:"sessionId"`
+	CreatedAt time.Time `                                json:"createdAt"`
+
+	// Associations
+	Session AuthSession `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for AuthRotatedRefreshToken model
+func (AuthRotatedRefreshToken) TableName() string {
+	return "auth_rotated_refresh_tokens"
+}
+
+// BeforeCreate hook to ensure ID is set
+func (s *AuthSession) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -6,6 +6,10 @@ import (
 
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/config"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
 )
 
 // define messages to indentify errors
@@ -18,11 +22,24 @@ func MigrationFunc(conn *gorm.DB) error {
 	// use conn.Debug().AutoMigrate(...) to enable debugging
 	// Need to create schema here of migration fails
 	conn.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS \"%s\"", viper.GetString("DB_SCHEMA")))
-	return conn.AutoMigrate(
+	if err := conn.AutoMigrate(
 		&User{},
 		&Conversation{},
 		&Message{},
-	)
+		&ResourceChunk{},
+		&UserMCPServer{},
+		&MCPOAuthToken{},
+		&MCPOAuthPending{},
+		&AuthSession{},
+		&AuthRotatedRefreshToken{},
+	); err != nil {
+		return err
+	}
+	// pgvector is optional: resource retrieval falls back to in-process similarity
+	if err := migrateResourceChunkVectors(conn, config.GetOpenAIConfig().EmbeddingDims()); err != nil {
+		logging.LogWarningf(err, "pgvector unavailable, resource chunks are ranked in-process")
+	}
+	return nil
 }
 
 // BaseModel defines the basic fields for each other model
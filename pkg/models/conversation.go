@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Conversation represents a chat conversation
@@ -16,8 +17,15 @@ type Conversation struct {
 	Model        string         `gorm:"size:100;not null;default:'llama3.2'"                 json:"model"`
 	SystemPrompt string         `gorm:"type:text"                                            json:"systemPrompt,omitempty"`
 	Metadata     datatypes.JSON `gorm:"type:jsonb;default:'{}'"                              json:"metadata,omitempty"`
-	CreatedAt    time.Time      `                                                            json:"createdAt"`
-	UpdatedAt    time.Time      `                                                            json:"updatedAt"`
+	// Servers are the MCP servers the conversation may use (empty = all enabled servers)
+	Servers datatypes.JSONSlice[string] `gorm:"type:jsonb;not null;default:'[]'" json:"servers,omitempty"`
+	// AllowedTools limits the conversation to these tools: qualified tool names
+	// (<server>__<tool>) or glob patterns such as "files__read*" (empty = all tools)
+	AllowedTools datatypes.JSONSlice[string] `gorm:"type:jsonb;not null;default:'[]'" json:"allowedTools,omitempty"`
+	// BlockedTools are never offered to the LLM, even when allowed, in the same format
+	BlockedTools datatypes.JSONSlice[string] `gorm:"type:jsonb;not null;default:'[]'" json:"blockedTools,omitempty"`
+	CreatedAt    time.Time                   `                                        json:"createdAt"`
+	UpdatedAt    time.Time                   `                                        json:"updatedAt"`
 
 	// Associations
 	User     User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"         json:"user,omitempty"`
@@ -38,6 +46,21 @@ func (c *Conversation) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// conversationMetadataObject is the metadata of a conversation as a JSON object, empty when
+// it is unset or not an object
+const conversationMetadataObject = "CASE WHEN jsonb_typeof(metadata) = 'object' THEN metadata ELSE '{}'::jsonb END"
+
+// SetConversationMetadataKey returns an update of the metadata column that sets one key to
+// the JSON value. Unlike writing the whole column, it keeps keys changed concurrently.
+func SetConversationMetadataKey(key string, value []byte) clause.Expr {
+	return gorm.Expr("jsonb_set("+conversationMetadataObject+", ?::text[], ?::jsonb)", "{"+key+"}", string(value))
+}
+
+// DeleteConversationMetadataKey returns an update of the metadata column that removes one key
+func DeleteConversationMetadataKey(key string) clause.Expr {
+	return gorm.Expr(conversationMetadataObject+" - ?::text", key)
+}
+
 // ConversationSummary represents a lightweight conversation for listing
 type ConversationSummary struct {
 	ID            uuid.UUID `json:"id"`
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MCPOAuthPending is an OAuth authorization a user started for an HTTP MCP server and has
+// not completed yet. It is stored so that the callback can reach any replica, and deleted
+// when the callback consumes it. Secrets are sealed with auth.SecretBox.
+type MCPOAuthPending struct {
+	// StateHash is the SHA-256 of the state sent to the authorization server
+	StateHash string `gorm:"size:64;primaryKey" json:"-"`
+	// NonceHash is the SHA-256 of the nonce in the cookie of the browser that started the flow
+	NonceHash  string    `gorm:"size:64;not null"                                json:"-"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;constraint:OnDelete:CASCADE"  json:"userId"`
+	ServerName string    `gorm:"size:255;not null"                               json:"serverName"`
+	ServerURL  string    `gorm:"type:text;not null"                              json:"serverUrl"`
+	Issuer     string    `gorm:"type:text"                                       json:"issuer"`
+	Resource   string    `gorm:"type:text"                                       json:"resource"`
+	AuthURL    string    `gorm:"type:text;not null"                              json:"-"`
+	TokenURL   string    `gorm:"type:text;not null"                              json:"-"`
+	ClientID   string    `gorm:"type:text;not null"                              json:"-"`
+	// Scopes are space-separated
+	Scopes                string    `gorm:"type:text"      json:"scopes,omitempty"`
+	EncryptedClientSecret []byte    `gorm:"type:bytea"     json:"-"`
+	EncryptedVerifier     []byte    `gorm:"type:bytea"     json:"-"`
+	ExpiresAt             time.Time `gorm:"not null;index" json:"expiresAt"`
+	CreatedAt             time.Time `                      json:"createdAt"`
+
+	// Associations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for MCPOAuthPending model
+func (MCPOAuthPending) TableName() string {
+	return "mcp_oauth_pending"
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MCPOAuthToken holds the OAuth client and tokens a user obtained for an HTTP MCP server.
+// Secrets are sealed with auth.SecretBox.
+type MCPOAuthToken struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"                                                    json:"id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_mcp_oauth_tokens_server,priority:1;constraint:OnDelete:CASCADE" json:"userId"`
+	ServerName string    `gorm:"size:255;not null;uniqueIndex:idx_mcp_oauth_tokens_server,priority:2"                              json:"serverName"`
+	// ServerURL is the endpoint the tokens were issued for; they are not sent anywhere else
+	ServerURL string `gorm:"type:text;not null" json:"serverUrl"`
+	Issuer    string `gorm:"type:text"          json:"issuer"`
+	Resource  string `gorm:"type:text"          json:"resource"`
+	AuthURL   string `gorm:"type:text;not null" json:"-"`
+	TokenURL  string `gorm:"type:text;not null" json:"-"`
+	ClientID  string `gorm:"type:text;not null" json:"-"`
+	// Scopes are space-separated
+	Scopes                string     `gorm:"type:text"  json:"scopes,omitempty"`
+	EncryptedClientSecret []byte     `gorm:"type:bytea" json:"-"`
+	EncryptedAccessToken  []byte     `gorm:"type:bytea" json:"-"`
+	EncryptedRefreshToken []byte     `gorm:"type:bytea" json:"-"`
+	TokenType             string     `gorm:"size:50"    json:"-"`
+	Expiry                *time.Time `                  json:"expiry,omitempty"`
+	CreatedAt             time.Time  `                  json:"createdAt"`
+	UpdatedAt             time.Time  `                  json:"updatedAt"`
+
+	// Associations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for MCPOAuthToken model
+func (MCPOAuthToken) TableName() string {
+	return "mcp_oauth_tokens"
+}
+
+// BeforeCreate hook to ensure ID is set
+func (t *MCPOAuthToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
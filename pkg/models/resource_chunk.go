@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/d4l-data4life/go-svc/pkg/logging"
+)
+
+// ResourceChunkVectorColumn is the pgvector column holding a chunk's embedding. It only
+// exists when the vector extension is available; Embedding is used otherwise.
+const ResourceChunkVectorColumn = "embedding_vector"
+
+const (
+	// resourceChunkVectorIndex is the HNSW index of the vector column
+	resourceChunkVectorIndex = "idx_resource_chunks_embedding_vector"
+	// maxHNSWDimensions is the largest vector pgvector indexes with HNSW
+	maxHNSWDimensions = 2000
+)
+
+// ResourceChunk is a piece of an MCP resource's text together with its embedding, indexed
+// per user for retrieval
+type ResourceChunk struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"                                                json:"id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index:idx_resource_chunks_resource,priority:1;constraint:OnDelete:CASCADE" json:"userId"`
+	ServerName string    `gorm:"size:255;not null;index:idx_resource_chunks_resource,priority:2"                              json:"serverName"`
+	URI        string    `gorm:"type:text;not null;index:idx_resource_chunks_resource,priority:3"                             json:"uri"`
+	ChunkIndex int       `gorm:"not null"                                                                                      json:"chunkIndex"`
+	Content    string    `gorm:"type:text"                                                                                     json:"content"`
+	// ContentHash identifies the resource content the chunk was cut from
+	ContentHash string `gorm:"size:64;not null" json:"contentHash"`
+	// Embedding is the chunk's vector as a JSON array (null for resources without text)
+	Embedding datatypes.JSON `gorm:"type:jsonb" json:"-"`
+	CreatedAt time.Time      `                  json:"createdAt"`
+	UpdatedAt time.Time      `                  json:"updatedAt"`
+
+	// Associations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for ResourceChunk model
+func (ResourceChunk) TableName() string {
+	return "resource_chunks"
+}
+
+// BeforeCreate hook to ensure ID is set
+func (c *ResourceChunk) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// migrateResourceChunkVectors adds the pgvector column of dims dimensions to
+// resource_chunks, with an HNSW index for cosine distance, when the vector extension can be
+// used. When the dimensions changed, the column is recreated and the chunks are dropped, as
+// they were embedded by another model. Without the extension or known dimensions, similarity
+// is computed in-process from Embedding.
+func migrateResourceChunkVectors(conn *gorm.DB, dims int) error {
+	if dims <= 0 {
+		return fmt.Errorf("unknown embedding dimensions, set OPENAI_EMBEDDING_DIMENSIONS")
+	}
+	var available int64
+	if err := conn.Raw("SELECT count(*) FROM pg_available_extensions WHERE name = 'vector'").Scan(&available).Error; err != nil {
+		return err
+	}
+	if available == 0 {
+		return nil
+	}
+	if err := conn.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+		return err
+	}
+
+	if conn.Migrator().HasColumn(&ResourceChunk{}, ResourceChunkVectorColumn) {
+		// The type modifier of a vector column is its dimensions (-1 = unconstrained)
+		var current int
+		if err := conn.Raw(
+			"SELECT atttypmod FROM pg_attribute WHERE attrelid = 'resource_chunks'::regclass AND attname = ?",
+			ResourceChunkVectorColumn,
+		).Scan(&current).Error; err != nil {
+			return err
+		}
+		if current != dims {
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec("ALTER TABLE resource_chunks DROP COLUMN " + ResourceChunkVectorColumn).Error; err != nil {
+					return err
+				}
+				return tx.Exec("DELETE FROM resource_chunks").Error
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if !conn.Migrator().HasColumn(&ResourceChunk{}, ResourceChunkVectorColumn) {
+		if err := conn.Exec(fmt.Sprintf("ALTER TABLE resource_chunks ADD COLUMN %s vector(%d)", ResourceChunkVectorColumn, dims)).Error; err != nil {
+			return err
+		}
+	}
+
+	if dims > maxHNSWDimensions {
+		logging.LogWarningf(nil, "Embeddings of %d dimensions cannot be indexed with HNSW, resource chunks are searched sequentially", dims)
+		return nil
+	}
+	return conn.Exec(fmt.Sprintf(
+		"CREATE INDEX IF NOT EXISTS %s ON resource_chunks USING hnsw (%s vector_cosine_ops)",
+		resourceChunkVectorIndex, ResourceChunkVectorColumn,
+	)).Error
+}
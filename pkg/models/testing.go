@@ -1,7 +1,10 @@
 package models
 
 import (
+	"net"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -12,10 +15,20 @@ import (
 	"github.com/d4l-data4life/go-svc/pkg/db"
 )
 
-// InitializeTestDB connects to an inmemory sqlite for testing
+// InitializeTestDB connects to the test Postgres (see make docker-database). Tests are
+// skipped when it is not reachable, unless TEST_DB_REQUIRED is set.
 func InitializeTestDB(t *testing.T) {
 	config.SetupEnv()
 	config.SetupLogger()
+	address := net.JoinHostPort(viper.GetString("DB_HOST"), viper.GetString("DB_PORT"))
+	conn, err := net.DialTimeout("tcp", address, time.Second)
+	if err != nil {
+		if os.Getenv("TEST_DB_REQUIRED") != "" {
+			require.NoError(t, err, "test database is not reachable")
+		}
+		t.Skipf("Skipping test, Postgres is not reachable at %s: %v", address, err)
+	}
+	_ = conn.Close()
 	// override schema for testing
 	viper.Set("DB_SCHEMA", "testing")
 	dbOpts := db.NewConnection(
@@ -33,6 +46,6 @@ func InitializeTestDB(t *testing.T) {
 	)
 	db.InitializeTestPostgres(dbOpts)
 	assert.NotNil(t, db.Get(), "DB handle is nil")
-	err := db.Ping()
+	err = db.Ping()
 	require.NoError(t, err)
 }
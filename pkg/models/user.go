@@ -1,13 +1,17 @@
 package models
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
 	"github.com/d4l-data4life/go-svc/pkg/db"
+	"github.com/d4l-data4life/go-svc/pkg/logging"
 )
 
 // User represents a user in the system
@@ -15,6 +19,7 @@ type User struct {
 	ID           uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	Username     *string        `gorm:"size:255;uniqueIndex"                           json:"username,omitempty"`
 	Email        *string        `gorm:"size:255;uniqueIndex"                           json:"email,omitempty"`
+	DisplayName  *string        `gorm:"size:255"                                       json:"displayName,omitempty"`
 	PasswordHash *string        `gorm:"size:255"                                       json:"-"` // Never expose password hash in JSON
 	CreatedAt    time.Time      `                                                      json:"createdAt"`
 	UpdatedAt    time.Time      `                                                      json:"updatedAt"`
@@ -39,27 +44,90 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 
 // PublicUser represents user data safe for public consumption
 type PublicUser struct {
-	ID        uuid.UUID `json:"id"`
-	Username  *string   `json:"username,omitempty"`
-	Email     *string   `json:"email,omitempty"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID          uuid.UUID `json:"id"`
+	Username    *string   `json:"username,omitempty"`
+	Email       *string   `json:"email,omitempty"`
+	DisplayName *string   `json:"displayName,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
 }
 
 // ToPublic converts User to PublicUser (removes sensitive data)
 func (u *User) ToPublic() PublicUser {
 	return PublicUser{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
+		ID:          u.ID,
+		Username:    u.Username,
+		Email:       u.Email,
+		DisplayName: u.DisplayName,
+		CreatedAt:   u.CreatedAt,
 	}
 }
 
-// EnsureUser makes sure the user with the given ID exists
-func EnsureUser(userID uuid.UUID) error {
+// UserProfile holds the user details an identity provider asserts. Empty fields are left
+// unchanged.
+type UserProfile struct {
+	DisplayName string
+	Email       string
+	// EmailVerified is whether the identity provider verified Email; unverified emails are
+	// not synced, as anyone could claim them
+	EmailVerified bool
+}
+
+// EnsureUser makes sure the user with the given ID exists and syncs the profile into it
+func EnsureUser(userID uuid.UUID, profile UserProfile) error {
+	return EnsureUserInDB(db.Get(), userID, profile)
+}
+
+// EnsureUserInDB makes sure the user with the given ID exists in the given database and
+// syncs the profile into it. Only verified emails are synced, and an email that already
+// belongs to another user is not.
+func EnsureUserInDB(database *gorm.DB, userID uuid.UUID, profile UserProfile) error {
+	syncEmail := profile.Email != "" && profile.EmailVerified
+	err := upsertUser(database, userID, profile.DisplayName, syncEmail, profile.Email)
+	if syncEmail && isUniqueViolation(err, "email") {
+		logging.LogWarningf(nil, "Not syncing email of user %s: it belongs to another user", userID)
+		err = upsertUser(database, userID, profile.DisplayName, false, "")
+	}
+	return err
+}
+
+// upsertUser creates the user or updates the given profile columns of an existing one
+func upsertUser(database *gorm.DB, userID uuid.UUID, displayName string, syncEmail bool, email string) error {
 	u := &User{ID: userID}
-	return db.Get().Clauses(clause.OnConflict{
+	var columns []string
+	if displayName != "" {
+		u.DisplayName = &displayName
+		columns = append(columns, "display_name")
+	}
+	if syncEmail {
+		u.Email = &email
+		columns = append(columns, "email")
+	}
+
+	conflict := clause.OnConflict{
 		Columns:   []clause.Column{{Name: "id"}},
-		DoNothing: true,
-	}).Create(u).Error
+		DoNothing: len(columns) == 0,
+	}
+	if len(columns) > 0 {
+		// Only write when the profile changed, not on every request
+		conflict.DoUpdates = clause.AssignmentColumns(append(columns, "updated_at"))
+		conflict.Where = clause.Where{Exprs: []clause.Expression{profileChanged(columns)}}
+	}
+	return database.Clauses(conflict).Create(u).Error
+}
+
+// isUniqueViolation reports whether err is a violation of a unique index on the column
+func isUniqueViolation(err error, column string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) &&
+		pgErr.Code == PGUniqueViolationErrorCode &&
+		strings.Contains(pgErr.ConstraintName, column)
+}
+
+// profileChanged matches existing rows whose synced columns differ from the new values
+func profileChanged(columns []string) clause.Expression {
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = "users." + column + " IS DISTINCT FROM excluded." + column
+	}
+	return clause.Expr{SQL: strings.Join(conditions, " OR ")}
 }
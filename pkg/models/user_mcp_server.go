@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserMCPServer is an HTTP MCP server registered by a user. It is only available to that
+// user, in addition to the globally configured servers.
+type UserMCPServer struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"                                                  json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_mcp_servers_name,priority:1;constraint:OnDelete:CASCADE" json:"userId"`
+	Name        string    `gorm:"size:100;not null;uniqueIndex:idx_user_mcp_servers_name,priority:2"                              json:"name"`
+	Description string    `gorm:"type:text"                                                                                       json:"description,omitempty"`
+	URL         string    `gorm:"type:text;not null"                                                                              json:"url"`
+	Mode        string    `gorm:"size:20"                                                                                         json:"mode,omitempty"`
+	Enabled     bool      `gorm:"not null"                                                                                        json:"enabled"`
+	// RequireApproval pauses every tool call to the server until the user approves it
+	RequireApproval bool `gorm:"not null;default:false" json:"requireApproval"`
+	// EncryptedSecrets holds the server's headers and token, sealed with auth.SecretBox
+	EncryptedSecrets []byte    `gorm:"type:bytea" json:"-"`
+	CreatedAt        time.Time `                  json:"createdAt"`
+	UpdatedAt        time.Time `                  json:"updatedAt"`
+
+	// Associations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for UserMCPServer model
+func (UserMCPServer) TableName() string {
+	return "user_mcp_servers"
+}
+
+// BeforeCreate hook to ensure ID is set
+func (s *UserMCPServer) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
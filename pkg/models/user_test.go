@@ -0,0 +1,38 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/d4l-data4life/go-mcp-host/pkg/models"
+	"github.com/d4l-data4life/go-svc/pkg/db"
+)
+
+func TestEnsureUserInDB_SyncsVerifiedUniqueEmails(t *testing.T) {
+	models.InitializeTestDB(t)
+	defer db.Close()
+	database := db.Get()
+
+	email := func(userID uuid.UUID) *string {
+		var user models.User
+		require.NoError(t, database.First(&user, "id = ?", userID).Error)
+		return user.Email
+	}
+
+	// Unverified emails are not synced
+	ada := uuid.New()
+	require.NoError(t, models.EnsureUserInDB(database, ada, models.UserProfile{Email: "ada@example.com"}))
+	assert.Nil(t, email(ada))
+
+	require.NoError(t, models.EnsureUserInDB(database, ada, models.UserProfile{Email: "ada@example.com", EmailVerified: true}))
+	require.NotNil(t, email(ada))
+	assert.Equal(t, "ada@example.com", *email(ada))
+
+	// An email of another user is skipped, the user is still created
+	other := uuid.New()
+	require.NoError(t, models.EnsureUserInDB(database, other, models.UserProfile{DisplayName: "Other", Email: "ada@example.com", EmailVerified: true}))
+	assert.Nil(t, email(other))
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -11,8 +12,11 @@ import (
 	"github.com/d4l-data4life/go-mcp-host/pkg/config"
 	"github.com/d4l-data4life/go-mcp-host/pkg/handlers"
 	"github.com/d4l-data4life/go-mcp-host/pkg/llm"
+	llmanthropic "github.com/d4l-data4life/go-mcp-host/pkg/llm/anthropic"
 	llmopenai "github.com/d4l-data4life/go-mcp-host/pkg/llm/openai"
 	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/manager"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/oauth"
+	"github.com/d4l-data4life/go-mcp-host/pkg/mcp/registry"
 
 	"github.com/d4l-data4life/go-svc/pkg/db"
 	"github.com/d4l-data4life/go-svc/pkg/logging"
@@ -21,8 +25,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// SetupRoutes adds all routes that the server should listen to
-func SetupRoutes(ctx context.Context, mux *chi.Mux, tokenValidator auth.TokenValidator, jwtSecret []byte) {
+// SetupRoutes adds all routes that the server should listen to. Without tokenValidator,
+// requests are authenticated with the sessions of local users, signed with jwtSecret.
+// It fails when local auth cannot be set up with jwtSecret.
+func SetupRoutes(ctx context.Context, mux *chi.Mux, tokenValidator auth.TokenValidator, jwtSecret []byte) error {
 	// Get database connection
 	database := db.Get()
 
@@ -32,33 +38,94 @@ func SetupRoutes(ctx context.Context, mux *chi.Mux, tokenValidator auth.TokenVal
 		logging.LogErrorf(err, "Failed to load MCP config, using defaults")
 		mcpConfig = &config.FullMCPConfig{
 			Servers:           []config.MCPServerConfig{},
+			LLMProvider:       config.GetLLMProvider(),
 			OpenAI:            config.GetOpenAIConfig(),
+			Anthropic:         config.GetAnthropicConfig(),
 			Agent:             config.GetAgentConfig(),
 			ReconnectAttempts: 3,
 			ReconnectDelay:    5 * time.Second,
 		}
 	}
 
-	mcpManager := manager.NewMCPManager(
-		mcpConfig.Servers,
+	// Initialize LLM client (OpenAI-compatible for OpenAI and Ollama endpoints, or native Anthropic)
+	llmClient := newLLMClient(mcpConfig)
+
+	managerOptions := []manager.Option{
 		manager.WithReconnectPolicy(mcpConfig.ReconnectAttempts, mcpConfig.ReconnectDelay),
-	)
+		manager.WithSampling(llmClient, nil),
+	}
+	// Users can register their own MCP servers when their secrets can be encrypted
+	if mcpConfig.SecretsKey != "" {
+		box, err := auth.NewSecretBox(mcpConfig.SecretsKey)
+		if err != nil {
+			logging.LogErrorf(err, "Invalid MCP_SECRETS_KEY, user MCP servers are disabled")
+		}
+		guard, guardErr := registry.NewAddressGuard(mcpConfig.UserServerAllowedNetworks)
+		if guardErr != nil {
+			logging.LogErrorf(guardErr, "Invalid MCP_USER_SERVER_ALLOWED_NETWORKS, user MCP servers are disabled")
+		}
+		if err == nil && guardErr == nil {
+			managerOptions = append(managerOptions, manager.WithUserServers(registry.New(database, box, guard)))
+			// Users can authorize HTTP MCP servers with OAuth; the tokens are sealed by the same box
+			if mcpConfig.OAuth.RedirectURL != "" {
+				managerOptions = append(managerOptions, manager.WithOAuth(oauth.New(database, box, mcpConfig.OAuth)))
+			}
+		}
+	} else if mcpConfig.OAuth.RedirectURL != "" {
+		logging.LogWarningf(auth.ErrNoSecretsKey, "MCP_OAUTH_REDIRECT_URL is set, but OAuth for MCP servers requires MCP_SECRETS_KEY")
+	}
+	mcpManager := manager.NewMCPManager(mcpConfig.Servers, managerOptions...)
 
-	// Initialize LLM client (OpenAI-compatible for both OpenAI and Ollama endpoints)
-	var llmClient llm.Client = llmopenai.NewClient(llmopenai.Config{
-		APIKey:  mcpConfig.OpenAI.APIKey,
-		BaseURL: mcpConfig.OpenAI.BaseURL,
-		Model:   mcpConfig.OpenAI.DefaultModel,
-	})
+	// Rank tools and retrieve resource chunks by embeddings when enabled and the provider serves them
+	var toolEmbedder, resourceEmbedder llm.Embedder
+	if mcpConfig.Agent.ToolEmbeddings {
+		if toolEmbedder = llm.EmbedderFor(llmClient); toolEmbedder == nil {
+			logging.LogWarningf(llm.ErrEmbeddingsUnsupported, "Tool embeddings disabled for LLM provider %s", mcpConfig.LLMProvider)
+		}
+	}
+	if mcpConfig.Agent.ResourceRetrieval {
+		if resourceEmbedder = llm.EmbedderFor(llmClient); resourceEmbedder == nil {
+			logging.LogWarningf(llm.ErrEmbeddingsUnsupported, "Resource retrieval disabled for LLM provider %s", mcpConfig.LLMProvider)
+		}
+	}
+
+	// Restrict tools by token claims; an invalid policy denies all tools
+	toolPolicy, err := agent.NewToolPolicy(mcpConfig.ToolPolicy)
+	if err != nil {
+		logging.LogErrorf(err, "Invalid tool policy, denying all tools")
+		toolPolicy, _ = agent.NewToolPolicy(config.ToolPolicyConfig{Default: config.PolicyEffectDeny})
+	}
 
 	// Initialize Agent
-	agentInstance := agent.NewAgent(database, mcpManager, llmClient, agent.Config{
-		MaxIterations: mcpConfig.Agent.MaxIterations,
-		DefaultModel:  mcpConfig.Agent.DefaultModel,
+	agentInstance, err := agent.NewAgent(database, mcpManager, llmClient, agent.Config{
+		MaxIterations:        mcpConfig.Agent.MaxIterations,
+		MaxContextTokens:     mcpConfig.Agent.MaxContextTokens,
+		MaxContextResources:  mcpConfig.Agent.MaxContextResources,
+		PruningStrategy:      mcpConfig.Agent.PruningStrategy,
+		MaxTools:             mcpConfig.Agent.MaxTools,
+		ToolEmbedder:         toolEmbedder,
+		ToolPolicy:           toolPolicy,
+		ResourceEmbedder:     resourceEmbedder,
+		ResourceIndexTTL:     parseTimeout(mcpConfig.Agent.ResourceIndexTTL),
+		MaxParallelToolCalls: mcpConfig.Agent.MaxParallelToolCalls,
+		ElicitationTimeout:   parseTimeout(mcpConfig.Agent.ElicitationTimeout),
+		ApprovalTimeout:      parseTimeout(mcpConfig.Agent.ApprovalTimeout),
+		DefaultModel:         mcpConfig.Agent.DefaultModel,
 	})
+	if err != nil {
+		return fmt.Errorf("invalid agent configuration: %w", err)
+	}
+
+	// Locally registered users log in with sessions when a JWT secret is set
+	var localAuth *auth.LocalAuth
+	if len(jwtSecret) > 0 {
+		if localAuth, err = auth.NewLocalAuth(database, jwtSecret, config.GetLocalAuthSettings()); err != nil {
+			return fmt.Errorf("invalid JWT secret: %w", err)
+		}
+	}
 
 	// Register new API routes
-	handlers.RegisterRoutes(mux, database, agentInstance, mcpManager, tokenValidator, jwtSecret)
+	handlers.RegisterRoutes(mux, database, agentInstance, mcpManager, tokenValidator, localAuth)
 
 	// Health checks and metrics
 	ch := handlers.NewChecksHandler()
@@ -74,4 +141,41 @@ func SetupRoutes(ctx context.Context, mux *chi.Mux, tokenValidator auth.TokenVal
 	if err := chi.Walk(mux, walkFunc); err != nil {
 		logging.LogErrorf(err, "logging error")
 	}
+	return nil
+}
+
+// newLLMClient builds the llm.Client for the configured provider
+func newLLMClient(mcpConfig *config.FullMCPConfig) llm.Client {
+	switch mcpConfig.LLMProvider {
+	case config.LLMProviderAnthropic:
+		return llmanthropic.NewClient(llmanthropic.Config{
+			APIKey:    mcpConfig.Anthropic.APIKey,
+			BaseURL:   mcpConfig.Anthropic.BaseURL,
+			Model:     mcpConfig.Anthropic.DefaultModel,
+			MaxTokens: mcpConfig.Anthropic.MaxTokens,
+			Timeout:   parseTimeout(mcpConfig.Anthropic.RequestTimeout),
+		})
+	case config.LLMProviderOpenAI, "":
+	default:
+		logging.LogWarningf(nil, "Unknown LLM_PROVIDER %q, falling back to %s", mcpConfig.LLMProvider, config.LLMProviderOpenAI)
+	}
+	return llmopenai.NewClient(llmopenai.Config{
+		APIKey:         mcpConfig.OpenAI.APIKey,
+		BaseURL:        mcpConfig.OpenAI.BaseURL,
+		Model:          mcpConfig.OpenAI.DefaultModel,
+		EmbeddingModel: mcpConfig.OpenAI.EmbeddingModel,
+		Timeout:        parseTimeout(mcpConfig.OpenAI.RequestTimeout),
+	})
+}
+
+func parseTimeout(raw string) time.Duration {
+	if raw == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		logging.LogWarningf(err, "Invalid request timeout %q, using client default", raw)
+		return 0
+	}
+	return timeout
 }